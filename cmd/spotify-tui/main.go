@@ -2,9 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"os"
 
 	"spotify-tui/internal/auth"
 	"spotify-tui/internal/config"
@@ -13,6 +15,7 @@ import (
 	"spotify-tui/internal/ui"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/term"
 )
 
 const defaultLogFile = "log/spotify-tui.log"
@@ -21,12 +24,13 @@ func main() {
 	// Parse command-line flags
 	debug := flag.Bool("debug", false, "Enable debug mode (log to file)")
 	logFile := flag.String("log-file", "", "Log file path (default: ./log/spotify-tui.log)")
+	demo := flag.Bool("demo", false, "Run offline against an in-memory demo library")
+	headless := flag.Bool("headless", false, "Log in by pasting the redirect URL instead of running a local callback server (for SSH sessions)")
+	profile := flag.String("profile", config.DefaultProfile, "Profile (account) to use; manage them with the profiles subcommand")
 	flag.Parse()
 
-	// Load config
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+	if term.IsTerminal(os.Stdin.Fd()) {
+		config.Passphrase = promptPassphrase
 	}
 
 	// Initialize logger
@@ -39,44 +43,160 @@ func main() {
 	}
 	defer logger.Close()
 
-	logger.Info("Application started", "debug", *debug)
+	logger.Info("Application started", "debug", *debug, "demo", *demo, "profile", *profile)
 
-	// Check if client credentials are set
-	if cfg.ClientID == "" || cfg.ClientSecret == "" {
-		fmt.Println("Spotify credentials not found.")
-		fmt.Println("Please set your Spotify Client ID and Client Secret:")
-		fmt.Println()
-		fmt.Println("1. Go to https://developer.spotify.com/dashboard")
-		fmt.Println("2. Create an application")
-		fmt.Println("3. Set redirect URI to: http://localhost:8080/callback")
-		fmt.Println("4. Copy your Client ID and Client Secret")
-		fmt.Println()
+	// ロガーの初期化後は log の出力が slog に流れるので、
+	// 利用者に見せるエラーは標準エラー出力に直接書く
+	if flag.Arg(0) == "profiles" {
+		if err := runProfiles(flag.Args()[1:], *headless); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-		fmt.Print("Enter Client ID: ")
-		fmt.Scanln(&cfg.ClientID)
-		fmt.Print("Enter Client Secret: ")
-		fmt.Scanln(&cfg.ClientSecret)
+	// Load config
+	cfg, err := config.LoadProfile(*profile)
+	if errors.Is(err, config.ErrProfileNotFound) {
+		fmt.Fprintf(os.Stderr, "Profile %q not found. Create it with: spotify-tui profiles add %s\n", *profile, *profile)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	if err := ui.ValidateKeys(cfg.KeyBindings()); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cfg.Path(), err)
+		os.Exit(1)
+	}
+	themes, err := config.LoadThemes()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load themes: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := cfg.Theme.Resolve(themes); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cfg.Path(), err)
+		os.Exit(1)
+	}
+
+	if *demo {
+		// デモの曲は実際のアカウントでは再生できないので、Up Next は保存しない
+		run(cfg, themes, spotify.NewDemoPlayer(), nil, nil, nil)
+		return
+	}
 
+	// Check if client credentials are set
+	// PKCE mode only needs the Client ID
+	if cfg.ClientID == "" || (!cfg.UsesPKCE() && cfg.ClientSecret == "") {
+		promptCredentials(cfg)
 		if err := cfg.Save(); err != nil {
 			log.Fatalf("Failed to save config: %v", err)
 		}
 	}
 
 	// Authenticate
-	spotifyClient, err := auth.Authenticate(cfg)
+	session, err := auth.Authenticate(cfg, *headless)
 	if err != nil {
 		log.Fatalf("Failed to authenticate: %v", err)
 	}
 
 	// Create client wrapper
-	client := spotify.NewClient(spotifyClient)
+	client := spotify.NewClient(session.HTTPClient())
+	upNext, err := newUpNext(client, cfg.Profile())
+	if err != nil {
+		log.Fatalf("Failed to locate Up Next file: %v", err)
+	}
+	run(cfg, themes, client, session, profileSwitcher{}, upNext)
+}
 
+// promptCredentials はSpotifyアプリのクライアント情報を標準入力から受け取る
+func promptCredentials(cfg *config.Config) {
+	fmt.Println("Spotify credentials not found.")
+	if cfg.UsesPKCE() {
+		fmt.Println("Please set your Spotify Client ID:")
+	} else {
+		fmt.Println("Please set your Spotify Client ID and Client Secret:")
+	}
+	fmt.Println()
+	fmt.Println("1. Go to https://developer.spotify.com/dashboard")
+	fmt.Println("2. Create an application")
+	fmt.Println("3. Set redirect URI to: " + cfg.RedirectURL())
+	if cfg.UsesPKCE() {
+		fmt.Println("4. Copy your Client ID")
+	} else {
+		fmt.Println("4. Copy your Client ID and Client Secret")
+	}
+	fmt.Println()
+
+	fmt.Print("Enter Client ID: ")
+	fmt.Scanln(&cfg.ClientID)
+	if !cfg.UsesPKCE() {
+		fmt.Print("Enter Client Secret: ")
+		fmt.Scanln(&cfg.ClientSecret)
+	}
+}
+
+// promptPassphrase は暗号化ファイルのパスフレーズを端末から読む
+// 環境変数 SPOTIFY_TUI_PASSPHRASE が設定されていればそれを使う
+func promptPassphrase(create bool) (string, error) {
+	if p, err := config.EnvPassphrase(create); err == nil {
+		return p, nil
+	}
+
+	if create {
+		fmt.Println("The OS keyring is not available, so secrets will be stored in an encrypted file.")
+		fmt.Print("Choose a passphrase: ")
+	} else {
+		fmt.Print("Passphrase for spotify-tui secrets: ")
+	}
+	passphrase, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+
+	if create {
+		fmt.Print("Confirm passphrase: ")
+		confirm, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Println()
+		if err != nil {
+			return "", err
+		}
+		if string(confirm) != string(passphrase) {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return string(passphrase), nil
+}
+
+// newUpNext はプロファイルの Up Next を保存する管理キューを作成する
+func newUpNext(client spotify.Player, profile string) (*spotify.ManagedQueue, error) {
+	path, err := config.QueuePath(profile)
+	if err != nil {
+		return nil, err
+	}
+	return spotify.NewManagedQueue(client, path, nil), nil
+}
+
+func run(cfg *config.Config, themes []config.ThemeDef, client spotify.Player, reauth ui.Reauthenticator, accounts ui.AccountSwitcher, upNext *spotify.ManagedQueue) {
 	// Create Bubbletea model
 	ctx := context.Background()
-	model := ui.NewModel(ctx, client)
+	model := ui.NewModel(ctx, client, cfg).WithThemes(themes)
+	if reauth != nil {
+		model = model.WithReauthenticator(reauth)
+	}
+	if accounts != nil {
+		model = model.WithAccountSwitcher(accounts)
+	}
+	if upNext != nil {
+		model = model.WithUpNext(upNext)
+	}
+
+	// TUI の実行中は端末からパスフレーズを読めないので、まだ開いていない暗号化ファイルは環境変数でしか開けない
+	config.Passphrase = config.EnvPassphrase
 
 	// Start TUI
-	p := tea.NewProgram(model, tea.WithAltScreen())
+	p := tea.NewProgram(model, tea.WithAltScreen(), tea.WithMouseCellMotion())
 	if _, err := p.Run(); err != nil {
 		log.Fatalf("Error running program: %v", err)
 	}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"spotify-tui/internal/auth"
+	"spotify-tui/internal/config"
+	"spotify-tui/internal/spotify"
+	"spotify-tui/internal/ui"
+)
+
+const profilesUsage = `usage:
+  spotify-tui profiles [list]         List profiles
+  spotify-tui profiles add <name>     Add a profile and log in to it
+  spotify-tui profiles remove <name>  Remove a profile`
+
+// runProfiles は profiles サブコマンドを実行する
+func runProfiles(args []string, headless bool) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "list":
+		names, err := config.Profiles()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			cfg, err := config.LoadProfile(name)
+			if err != nil {
+				fmt.Printf("%-16s (error: %v)\n", name, err)
+				continue
+			}
+			status := "not logged in"
+			if cfg.AccessToken != "" {
+				status = "logged in"
+			}
+			fmt.Printf("%-16s %s\n", name, status)
+		}
+		return nil
+
+	case "add":
+		if len(args) != 2 {
+			return errors.New(profilesUsage)
+		}
+		cfg, err := config.NewProfile(args[1])
+		if err != nil {
+			return err
+		}
+
+		// 同じSpotifyアプリを使うことが多いので、既定のプロファイルのアプリ設定を引き継ぐ
+		if def, err := config.Load(); err == nil && def.ClientID != "" {
+			cfg.AuthMode = def.AuthMode
+			cfg.ClientID = def.ClientID
+			cfg.ClientSecret = def.ClientSecret
+			cfg.RedirectURI = def.RedirectURI
+			cfg.SecretStorage = def.SecretStorage
+			fmt.Println("Using the Spotify app settings from the default profile.")
+		} else {
+			promptCredentials(cfg)
+		}
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("failed to save profile: %w", err)
+		}
+
+		if _, err := auth.Authenticate(cfg, headless); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+		fmt.Printf("Profile %q added. Start it with: spotify-tui --profile %s\n", cfg.Profile(), cfg.Profile())
+		return nil
+
+	case "remove":
+		if len(args) != 2 {
+			return errors.New(profilesUsage)
+		}
+		if err := config.RemoveProfile(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Profile %q removed.\n", args[1])
+		return nil
+	}
+	return errors.New(profilesUsage)
+}
+
+// profileSwitcher は TUI のアカウント切り替えで、プロファイルごとに Spotify クライアントを作り直す
+// トークンがないプロファイルは再ログインが必要な状態で開く
+// TUI の実行中はパスフレーズを入力できないので、まだ開いていない暗号化ファイルを使うプロファイルには切り替えない
+type profileSwitcher struct{}
+
+func (profileSwitcher) Profiles() ([]string, error) {
+	return config.Profiles()
+}
+
+func (profileSwitcher) Open(profile string) (ui.Account, error) {
+	cfg, err := config.LoadProfile(profile)
+	if errors.Is(err, config.ErrPassphraseRequired) {
+		return ui.Account{}, fmt.Errorf("profile %q keeps its secrets in a locked encrypted file; set SPOTIFY_TUI_PASSPHRASE or start it with --profile %s", profile, profile)
+	}
+	if err != nil {
+		return ui.Account{}, err
+	}
+	if cfg.ClientID == "" {
+		return ui.Account{}, fmt.Errorf("profile %q has no Client ID; start it once with --profile %s", profile, profile)
+	}
+	if err := ui.ValidateKeys(cfg.KeyBindings()); err != nil {
+		return ui.Account{}, fmt.Errorf("profile %q: %w", profile, err)
+	}
+	themes, err := config.LoadThemes()
+	if err != nil {
+		return ui.Account{}, err
+	}
+	if _, err := cfg.Theme.Resolve(themes); err != nil {
+		return ui.Account{}, fmt.Errorf("profile %q: %w", profile, err)
+	}
+	session := auth.Resume(cfg)
+	client := spotify.NewClient(session.HTTPClient())
+	upNext, err := newUpNext(client, profile)
+	if err != nil {
+		return ui.Account{}, fmt.Errorf("profile %q: %w", profile, err)
+	}
+	return ui.Account{
+		Config: cfg,
+		Client: client,
+		Reauth: session,
+		UpNext: upNext,
+	}, nil
+}
@@ -1,10 +1,12 @@
 package auth
 
 import (
+	"bufio"
 	"context"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
+	"os"
 	"time"
 
 	"spotify-tui/internal/config"
@@ -15,106 +17,157 @@ import (
 	"golang.org/x/oauth2"
 )
 
-const (
-	redirectURI = "http://127.0.0.1:8080/callback"
-	state       = "spotify-tui-state"
-)
+var scopes = []string{
+	spotifyauth.ScopeUserReadPrivate,
+	spotifyauth.ScopeUserReadPlaybackState,
+	spotifyauth.ScopeUserModifyPlaybackState,
+	spotifyauth.ScopeUserReadCurrentlyPlaying,
+	spotifyauth.ScopePlaylistReadPrivate,
+	spotifyauth.ScopePlaylistModifyPublic,
+	spotifyauth.ScopePlaylistModifyPrivate,
+	spotifyauth.ScopeUserLibraryRead,
+	spotifyauth.ScopeUserLibraryModify,
+}
+
+// Session はログイン中のトークンを保持する
+// HTTPClient が返すクライアントは常に最新のトークンを使うので、
+// 実行中に再ログインしてもSpotifyクライアントを作り直す必要がない
+type Session struct {
+	cfg    *config.Config
+	source *savingTokenSource
+	client *http.Client
+}
 
-var (
-	auth   *spotifyauth.Authenticator
-	ch     = make(chan *oauth2.Token)
-	scopes = []string{
-		spotifyauth.ScopeUserReadPrivate,
-		spotifyauth.ScopeUserReadPlaybackState,
-		spotifyauth.ScopeUserModifyPlaybackState,
-		spotifyauth.ScopeUserReadCurrentlyPlaying,
-		spotifyauth.ScopePlaylistReadPrivate,
-		spotifyauth.ScopeUserLibraryRead,
+func newSession(cfg *config.Config) *Session {
+	source := &savingTokenSource{cfg: cfg}
+	return &Session{
+		cfg:    cfg,
+		source: source,
+		client: oauth2.NewClient(context.Background(), source),
 	}
-)
+}
 
-func Authenticate(cfg *config.Config) (*spotify.Client, error) {
-	logger.Debug("Starting authentication")
+// HTTPClient はトークンを自動更新して保存するHTTPクライアントを返す
+func (s *Session) HTTPClient() *http.Client {
+	return s.client
+}
 
-	auth = spotifyauth.New(
-		spotifyauth.WithRedirectURL(redirectURI),
-		spotifyauth.WithScopes(scopes...),
-		spotifyauth.WithClientID(cfg.ClientID),
-		spotifyauth.WithClientSecret(cfg.ClientSecret),
-	)
+// StartLogin はコールバックサーバーを起動して新しいログインを開始する
+// ブラウザで開く認可ページのURLと、ログインの完了を待つ関数を返す
+// wait は ctx がキャンセルされるかタイムアウトするとエラーを返す
+func (s *Session) StartLogin(ctx context.Context) (string, func() error, error) {
+	f, err := newLoginFlow(oauthConfig(s.cfg), s.cfg.UsesPKCE())
+	if err != nil {
+		return "", nil, err
+	}
+	server, err := startCallbackServer(f, s.cfg.RedirectURL())
+	if err != nil {
+		return "", nil, err
+	}
+	wait := func() error {
+		token, err := server.Wait(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Info("OAuth token received")
+		return s.source.setToken(token)
+	}
+	return f.AuthURL(), wait, nil
+}
 
-	// 既存のトークンがあれば使用
+// Resume は保存済みのトークンから Session を作成する
+// トークンの検証や対話的なログインは行わないので、実行中のアカウント切り替えに使う
+// トークンがない場合、リクエストは ErrReloginRequired で失敗する
+func Resume(cfg *config.Config) *Session {
+	session := newSession(cfg)
 	if cfg.AccessToken != "" {
-		logger.Debug("Existing token found, attempting to reuse")
-		token := &oauth2.Token{
+		session.source.useToken(&oauth2.Token{
 			AccessToken:  cfg.AccessToken,
 			RefreshToken: cfg.RefreshToken,
 			Expiry:       time.Unix(cfg.TokenExpiry, 0),
-		}
+		})
+	}
+	return session
+}
 
-		client := spotify.New(auth.Client(context.Background(), token))
+// Authenticate はログイン済みの Session を返す
+// 有効なトークンが保存されていればそれを使い、なければOAuthフローを開始する
+// headless の場合はコールバックサーバーを起動せず、別の端末のブラウザで認可した後の
+// リダイレクトURL（またはコード）を標準入力から受け取る
+func Authenticate(cfg *config.Config, headless bool) (*Session, error) {
+	logger.Debug("Starting authentication", "profile", cfg.Profile(), "pkce", cfg.UsesPKCE())
+	session := Resume(cfg)
+
+	// 既存のトークンがあれば使用
+	if cfg.AccessToken != "" {
+		logger.Debug("Existing token found, attempting to reuse")
 
 		// トークンが有効かチェック
-		_, err := client.CurrentUser(context.Background())
+		_, err := spotify.New(session.HTTPClient()).CurrentUser(context.Background())
 		if err == nil {
 			logger.Info("Authentication successful with existing token")
-			return client, nil
+			return session, nil
 		}
 		logger.Debug("Existing token invalid, need new authentication", "error", err)
 	}
 
 	// 新規認証
 	logger.Info("Starting new OAuth flow")
-	http.HandleFunc("/callback", completeAuth)
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("HTTP request received", "url", r.URL.String())
-	})
-
-	go func() {
-		err := http.ListenAndServe(":8080", nil)
+	if headless {
+		f, err := newLoginFlow(oauthConfig(cfg), cfg.UsesPKCE())
 		if err != nil {
-			logger.Error("HTTP server error", "error", err)
-			log.Fatal(err)
+			return nil, err
+		}
+		token, err := pasteAuth(f, os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		if err := session.source.setToken(token); err != nil {
+			return nil, err
 		}
-	}()
+		return session, nil
+	}
 
-	url := auth.AuthURL(state)
+	url, wait, err := session.StartLogin(context.Background())
+	if err != nil {
+		return nil, err
+	}
 	fmt.Println("Please log in to Spotify by visiting the following page in your browser:")
 	fmt.Println(url)
-
-	// Wait for auth to complete
-	token := <-ch
-	logger.Info("OAuth token received")
-
-	// Save token to config
-	cfg.AccessToken = token.AccessToken
-	cfg.RefreshToken = token.RefreshToken
-	cfg.TokenExpiry = token.Expiry.Unix()
-	if err := cfg.Save(); err != nil {
-		logger.Error("Failed to save token to config", "error", err)
+	if err := wait(); err != nil {
 		return nil, err
 	}
-	logger.Debug("Token saved to config")
-
-	client := spotify.New(auth.Client(context.Background(), token))
-	return client, nil
+	return session, nil
 }
 
-func completeAuth(w http.ResponseWriter, r *http.Request) {
-	logger.Debug("OAuth callback received")
-	token, err := auth.Token(r.Context(), state, r)
-	if err != nil {
-		logger.Error("Failed to get token from callback", "error", err)
-		http.Error(w, "Couldn't get token", http.StatusForbidden)
-		log.Fatal(err)
-	}
-	if st := r.FormValue("state"); st != state {
-		logger.Error("OAuth state mismatch", "expected", state, "got", st)
-		http.NotFound(w, r)
-		log.Fatalf("State mismatch: %s != %s\n", st, state)
-	}
+// pasteAuth はブラウザのリダイレクト先URLを貼り付けてもらってトークンに交換する
+// SSH 先などブラウザからコールバックサーバーに届かない環境で使う
+func pasteAuth(f *loginFlow, in io.Reader) (*oauth2.Token, error) {
+	fmt.Println("Open the following page in a browser on any machine and log in to Spotify:")
+	fmt.Println(f.AuthURL())
+	fmt.Println()
+	fmt.Println("After approving, the browser is redirected to a page that may fail to load.")
+	fmt.Println("Copy the full URL from the address bar and paste it here.")
+
+	reader := bufio.NewReader(in)
+	for {
+		fmt.Print("Redirect URL: ")
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf("failed to read redirect URL: %w", err)
+		}
 
-	logger.Info("OAuth callback completed successfully")
-	fmt.Fprintf(w, "Login Completed! You can close this window and return to the terminal.")
-	ch <- token
+		query, err := f.ParseRedirect(line)
+		if err != nil {
+			fmt.Println("Invalid input:", err)
+			continue
+		}
+		token, err := f.Exchange(context.Background(), query)
+		if err != nil {
+			logger.Error("Failed to exchange pasted code", "error", err)
+			return nil, err
+		}
+		logger.Info("OAuth token received")
+		return token, nil
+	}
 }
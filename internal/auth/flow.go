@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// loginFlow は1回のログインに使う state と PKCE の code verifier を保持する
+// state はログインごとにランダムに生成し、コールバックの取り違えや CSRF を防ぐ
+type loginFlow struct {
+	config   *oauth2.Config
+	state    string
+	verifier string // PKCE モードでのみ使用
+}
+
+func newLoginFlow(oc *oauth2.Config, pkce bool) (*loginFlow, error) {
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+	f := &loginFlow{
+		config: oc,
+		state:  state,
+	}
+	if pkce {
+		f.verifier = oauth2.GenerateVerifier()
+	}
+	return f, nil
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate OAuth state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// AuthURL はブラウザで開く認可ページのURLを返す
+func (f *loginFlow) AuthURL() string {
+	if f.verifier != "" {
+		return f.config.AuthCodeURL(f.state, oauth2.S256ChallengeOption(f.verifier))
+	}
+	return f.config.AuthCodeURL(f.state)
+}
+
+// Exchange はリダイレクトのクエリパラメータを検証し、認可コードをトークンに交換する
+func (f *loginFlow) Exchange(ctx context.Context, query url.Values) (*oauth2.Token, error) {
+	if e := query.Get("error"); e != "" {
+		return nil, fmt.Errorf("authorization denied: %s", e)
+	}
+	if st := query.Get("state"); st != f.state {
+		return nil, errors.New("OAuth state mismatch")
+	}
+	code := query.Get("code")
+	if code == "" {
+		return nil, errors.New("authorization code not found")
+	}
+
+	var opts []oauth2.AuthCodeOption
+	if f.verifier != "" {
+		opts = append(opts, oauth2.VerifierOption(f.verifier))
+	}
+	return f.config.Exchange(ctx, code, opts...)
+}
+
+// ParseRedirect は貼り付けられたリダイレクトURL（またはそのクエリ部分）を
+// Exchange に渡すクエリパラメータに変換する
+// コードのみの入力は state を照合できず CSRF を防げないので受け付けない
+func (f *loginFlow) ParseRedirect(input string) (url.Values, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, errors.New("empty input")
+	}
+	if strings.Contains(input, "?") {
+		u, err := url.Parse(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redirect URL: %w", err)
+		}
+		return u.Query(), nil
+	}
+	if strings.ContainsAny(input, "=&") {
+		return url.ParseQuery(input)
+	}
+	return nil, errors.New("paste the full URL from the address bar; a code alone can't be checked against the login's state")
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"spotify-tui/internal/config"
+
+	"golang.org/x/oauth2"
+)
+
+func TestParseRedirect(t *testing.T) {
+	f := &loginFlow{config: &oauth2.Config{}, state: "s1"}
+	tests := []struct {
+		name    string
+		input   string
+		code    string
+		state   string
+		wantErr bool
+	}{
+		{"full URL", "http://127.0.0.1:8080/callback?code=abc&state=s1\n", "abc", "s1", false},
+		{"query only", "code=abc&state=s2", "abc", "s2", false},
+		{"bare code", "abc", "", "", true},
+		{"empty", "  \n", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := f.ParseRedirect(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseRedirect(%q) = %v, want an error", tt.input, query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := query.Get("code"); got != tt.code {
+				t.Errorf("code = %q, want %q", got, tt.code)
+			}
+			if got := query.Get("state"); got != tt.state {
+				t.Errorf("state = %q, want %q", got, tt.state)
+			}
+		})
+	}
+}
+
+// fakeAccounts はトークンエンドポイントだけを持つ、テスト用の Spotify Accounts Service
+// PKCE の code_verifier と code_challenge の対応、クライアントの認証方法を確かめる
+type fakeAccounts struct {
+	challenge string // AuthURL で送られた code_challenge
+	verifier  string // トークンの要求で送られた code_verifier
+	clientID  string
+	secret    string
+}
+
+func (a *fakeAccounts) start(t *testing.T) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.Form.Get("code") != "good-code" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		a.verifier = r.Form.Get("code_verifier")
+		a.clientID = r.Form.Get("client_id")
+		if id, secret, ok := r.BasicAuth(); ok {
+			a.clientID, a.secret = id, secret
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"access","refresh_token":"refresh","token_type":"Bearer","expires_in":3600}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	old := endpoint
+	endpoint = oauth2.Endpoint{AuthURL: srv.URL + "/authorize", TokenURL: srv.URL + "/api/token"}
+	t.Cleanup(func() { endpoint = old })
+}
+
+// authParams は認可ページのURLのクエリを返す
+func authParams(t *testing.T, f *loginFlow) url.Values {
+	t.Helper()
+	u, err := url.Parse(f.AuthURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query()
+}
+
+func TestLoginFlowPKCE(t *testing.T) {
+	var accounts fakeAccounts
+	accounts.start(t)
+	cfg := &config.Config{ClientID: "client", AuthMode: config.AuthModePKCE}
+	f, err := newLoginFlow(oauthConfig(cfg), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := authParams(t, f)
+	if got := params.Get("state"); got != f.state {
+		t.Errorf("state in AuthURL = %q, want %q", got, f.state)
+	}
+	if got := params.Get("code_challenge_method"); got != "S256" {
+		t.Errorf("code_challenge_method = %q, want S256", got)
+	}
+	accounts.challenge = params.Get("code_challenge")
+
+	token, err := f.Exchange(context.Background(), url.Values{"code": {"good-code"}, "state": {f.state}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "access" || token.RefreshToken != "refresh" {
+		t.Errorf("token = %+v", token)
+	}
+	if got := oauth2.S256ChallengeFromVerifier(accounts.verifier); got != accounts.challenge {
+		t.Errorf("code_verifier %q doesn't match code_challenge %q", accounts.verifier, accounts.challenge)
+	}
+	if accounts.clientID != "client" || accounts.secret != "" {
+		t.Errorf("client_id = %q, secret = %q; PKCE sends only the client ID", accounts.clientID, accounts.secret)
+	}
+}
+
+func TestLoginFlowClientSecret(t *testing.T) {
+	var accounts fakeAccounts
+	accounts.start(t)
+	cfg := &config.Config{ClientID: "client", ClientSecret: "secret"}
+	f, err := newLoginFlow(oauthConfig(cfg), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params := authParams(t, f); params.Has("code_challenge") {
+		t.Error("AuthURL has a code_challenge without PKCE")
+	}
+	if _, err := f.Exchange(context.Background(), url.Values{"code": {"good-code"}, "state": {f.state}}); err != nil {
+		t.Fatal(err)
+	}
+	if accounts.verifier != "" {
+		t.Errorf("code_verifier %q sent without PKCE", accounts.verifier)
+	}
+	if accounts.clientID != "client" || accounts.secret != "secret" {
+		t.Errorf("client credentials = %q / %q", accounts.clientID, accounts.secret)
+	}
+}
+
+func TestLoginFlowState(t *testing.T) {
+	var accounts fakeAccounts
+	accounts.start(t)
+	cfg := &config.Config{ClientID: "client", AuthMode: config.AuthModePKCE}
+	f1, err := newLoginFlow(oauthConfig(cfg), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2, err := newLoginFlow(oauthConfig(cfg), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f1.state == "" || f1.state == f2.state {
+		t.Errorf("states %q and %q should be random and different", f1.state, f2.state)
+	}
+	if f1.verifier == f2.verifier {
+		t.Error("code verifiers should differ between logins")
+	}
+
+	tests := []struct {
+		name  string
+		query url.Values
+	}{
+		{"state of another login", url.Values{"code": {"good-code"}, "state": {f2.state}}},
+		{"missing state", url.Values{"code": {"good-code"}}},
+		{"denied", url.Values{"error": {"access_denied"}, "state": {f1.state}}},
+		{"missing code", url.Values{"state": {f1.state}}},
+		{"rejected code", url.Values{"code": {"bad-code"}, "state": {f1.state}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if token, err := f1.Exchange(context.Background(), tt.query); err == nil {
+				t.Errorf("Exchange succeeded with %v: %+v", tt.query, token)
+			}
+		})
+	}
+}
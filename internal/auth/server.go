@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"spotify-tui/internal/logger"
+
+	"golang.org/x/oauth2"
+)
+
+// loginTimeout はブラウザでの認可を待つ最大時間
+const loginTimeout = 5 * time.Minute
+
+var resultPage = template.Must(template.New("result").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>spotify-tui</title>
+<style>
+body { background: #121212; color: #FFFFFF; font-family: sans-serif; display: flex; align-items: center; justify-content: center; height: 100vh; margin: 0; }
+.card { background: #282828; padding: 2em 3em; border-radius: 8px; text-align: center; }
+h1 { color: {{if .OK}}#1DB954{{else}}#FF5555{{end}}; }
+p { color: #B3B3B3; }
+</style>
+</head>
+<body>
+<div class="card">
+<h1>{{.Title}}</h1>
+<p>{{.Message}}</p>
+</div>
+</body>
+</html>
+`))
+
+type loginResult struct {
+	token *oauth2.Token
+	err   error
+}
+
+// callbackServer はOAuthのコールバックを1回だけ受け取る一時的なHTTPサーバー
+// グローバルな http.DefaultServeMux を使わず、ログインごとに起動・停止する
+type callbackServer struct {
+	srv     *http.Server
+	results chan loginResult
+}
+
+// startCallbackServer はリダイレクトURIのホストとポートで待ち受けを開始する
+// ポートが使用中の場合などはここでエラーを返す
+func startCallbackServer(f *loginFlow, redirectURL string) (*callbackServer, error) {
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect URI %q: %w", redirectURL, err)
+	}
+	ln, err := net.Listen("tcp", u.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start login server on %s: %w", u.Host, err)
+	}
+
+	c := &callbackServer{
+		results: make(chan loginResult, 1),
+	}
+	// リダイレクトURIのパスが空や "/" でもパターンが衝突しないよう、ハンドラは1つにしてパスを比べる
+	callbackPath := u.Path
+	if callbackPath == "" {
+		callbackPath = "/"
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != callbackPath {
+			logger.Debug("HTTP request received", "url", r.URL.String())
+			http.NotFound(w, r)
+			return
+		}
+		logger.Debug("OAuth callback received")
+		token, err := f.Exchange(r.Context(), r.URL.Query())
+		if err != nil {
+			logger.Error("Failed to get token from callback", "error", err)
+			renderResult(w, http.StatusForbidden, false, "Login failed", err.Error())
+		} else {
+			logger.Info("OAuth callback completed successfully")
+			renderResult(w, http.StatusOK, true, "Login completed!", "You can close this window and return to the terminal.")
+		}
+		// 最初のコールバックの結果だけを採用する
+		select {
+		case c.results <- loginResult{token: token, err: err}:
+		default:
+		}
+	})
+
+	c.srv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := c.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("HTTP server error", "error", err)
+			select {
+			case c.results <- loginResult{err: err}:
+			default:
+			}
+		}
+	}()
+	return c, nil
+}
+
+// Wait はコールバックを受け取るか、タイムアウトまたは ctx のキャンセルまで待つ
+// どの場合も戻る前にサーバーを停止する
+func (c *callbackServer) Wait(ctx context.Context) (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(ctx, loginTimeout)
+	defer cancel()
+	defer c.shutdown()
+
+	select {
+	case res := <-c.results:
+		return res.token, res.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("login timed out after %s", loginTimeout)
+		}
+		return nil, fmt.Errorf("login cancelled: %w", ctx.Err())
+	}
+}
+
+func (c *callbackServer) shutdown() {
+	// 結果ページの送信が終わるのを少しだけ待つ
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.srv.Shutdown(ctx); err != nil {
+		logger.Error("Failed to shut down login server", "error", err)
+	}
+}
+
+func renderResult(w http.ResponseWriter, status int, ok bool, title, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	err := resultPage.Execute(w, struct {
+		OK      bool
+		Title   string
+		Message string
+	}{ok, title, message})
+	if err != nil {
+		logger.Error("Failed to render login page", "error", err)
+	}
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"spotify-tui/internal/config"
+
+	"golang.org/x/oauth2"
+)
+
+// freeAddr は空いているローカルのポートを返す
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestCallbackServerPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string // リダイレクトURIのパス
+		callback string // コールバックとして扱われるパス
+		other    string // 404 になるパス
+	}{
+		{"callback path", "/callback", "/callback", "/"},
+		{"root path", "/", "/", "/callback"},
+		{"empty path", "", "/", "/callback"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := freeAddr(t)
+			redirect := "http://" + addr + tt.path
+			f := &loginFlow{config: &oauth2.Config{RedirectURL: redirect}, state: "expected"}
+			c, err := startCallbackServer(f, redirect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.shutdown()
+
+			resp, err := http.Get("http://" + addr + tt.other)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusNotFound {
+				t.Errorf("GET %s: status %d, want 404", tt.other, resp.StatusCode)
+			}
+
+			// state が一致しないので、コールバックのハンドラがログインを失敗させる
+			resp, err = http.Get("http://" + addr + tt.callback + "?state=wrong&code=x")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusForbidden {
+				t.Errorf("GET %s: status %d, want 403", tt.callback, resp.StatusCode)
+			}
+			if _, err := c.Wait(context.Background()); err == nil {
+				t.Error("Wait succeeded with a mismatched state")
+			}
+		})
+	}
+}
+
+// closed はサーバーが停止して addr に接続できないことを確かめる
+func closed(t *testing.T, addr string) {
+	t.Helper()
+	if resp, err := http.Get("http://" + addr + "/callback"); err == nil {
+		resp.Body.Close()
+		t.Errorf("login server on %s still running after the login finished", addr)
+	}
+}
+
+func TestStartLogin(t *testing.T) {
+	var accounts fakeAccounts
+	accounts.start(t)
+	cfg := newTestConfig(t, "", "")
+	addr := freeAddr(t)
+	cfg.RedirectURI = "http://" + addr + "/callback"
+
+	authURL, wait, err := Resume(cfg).StartLogin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := u.Query().Get("state")
+
+	resp, err := http.Get(cfg.RedirectURI + "?code=good-code&state=" + url.QueryEscape(state))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "Login completed!") {
+		t.Errorf("callback: status %d, page %q; want the success page", resp.StatusCode, body)
+	}
+
+	if err := wait(); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.AccessToken != "access" || saved.RefreshToken != "refresh" {
+		t.Errorf("saved token = %q, %q; want access, refresh", saved.AccessToken, saved.RefreshToken)
+	}
+	closed(t, addr)
+}
+
+func TestStartLoginFails(t *testing.T) {
+	var accounts fakeAccounts
+	accounts.start(t)
+	cfg := newTestConfig(t, "", "")
+	addr := freeAddr(t)
+	cfg.RedirectURI = "http://" + addr + "/callback"
+
+	_, wait, err := Resume(cfg).StartLogin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// state を付けずにコールバックされたら、失敗のページを表示して wait がエラーを返す
+	resp, err := http.Get(cfg.RedirectURI + "?code=good-code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || !strings.Contains(string(body), "Login failed") {
+		t.Errorf("callback: status %d, page %q; want the failure page", resp.StatusCode, body)
+	}
+	if err := wait(); err == nil {
+		t.Error("wait succeeded without a state")
+	}
+	if cfg.AccessToken != "" {
+		t.Errorf("token = %q after a failed login", cfg.AccessToken)
+	}
+	closed(t, addr)
+}
+
+func TestStartLoginCancel(t *testing.T) {
+	cfg := newTestConfig(t, "", "")
+	addr := freeAddr(t)
+	cfg.RedirectURI = "http://" + addr + "/callback"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, wait, err := Resume(cfg).StartLogin(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := wait(); err == nil || !strings.Contains(err.Error(), "login cancelled") {
+		t.Errorf("wait = %v, want login cancelled", err)
+	}
+	closed(t, addr)
+}
+
+func TestStartLoginPortInUse(t *testing.T) {
+	cfg := newTestConfig(t, "", "")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	cfg.RedirectURI = "http://" + ln.Addr().String() + "/callback"
+
+	// 待ち受けを開始できない場合は、認可ページを開く前にエラーを返す
+	if _, _, err := Resume(cfg).StartLogin(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to start login server") {
+		t.Errorf("StartLogin = %v, want the port in use", err)
+	}
+}
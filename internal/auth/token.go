@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"spotify-tui/internal/config"
+	"spotify-tui/internal/logger"
+
+	spotifyauth "github.com/zmb3/spotify/v2/auth"
+	"golang.org/x/oauth2"
+)
+
+// ErrReloginRequired はトークンを更新できず、再ログインが必要なことを表す
+var ErrReloginRequired = errors.New("re-login required")
+
+// savingTokenSource はトークンが更新されるたびに設定ファイルへ書き戻す TokenSource
+// oauth2 のトランスポートは期限切れのトークンを自動で更新するが、
+// そのままでは新しいリフレッシュトークンと有効期限が保存されない
+type savingTokenSource struct {
+	mu     sync.Mutex
+	source oauth2.TokenSource
+	cfg    *config.Config
+}
+
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.source == nil {
+		return nil, fmt.Errorf("%w: not logged in", ErrReloginRequired)
+	}
+	token, err := s.source.Token()
+	if err != nil {
+		// トークンエンドポイントに拒否された場合は再ログインするしかない
+		// ネットワークエラーなどは一時的なものとしてそのまま返す
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) || s.cfg.RefreshToken == "" {
+			logger.Error("Token refresh rejected", "error", err)
+			return nil, fmt.Errorf("%w: %v", ErrReloginRequired, err)
+		}
+		logger.Error("Token refresh failed", "error", err)
+		return nil, err
+	}
+
+	if token.AccessToken != s.cfg.AccessToken {
+		logger.Info("Token refreshed", "expiry", token.Expiry)
+		if err := saveToken(s.cfg, token); err != nil {
+			// 保存に失敗しても現在のセッションは継続できる
+			logger.Error("Failed to save refreshed token", "error", err)
+		}
+	}
+	return token, nil
+}
+
+// useToken は保存済みのトークンから更新を始める
+func (s *savingTokenSource) useToken(token *oauth2.Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.source = oauthConfig(s.cfg).TokenSource(context.Background(), token)
+}
+
+// setToken はログインで得た新しいトークンを保存し、以降のリクエストで使う
+func (s *savingTokenSource) setToken(token *oauth2.Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := saveToken(s.cfg, token); err != nil {
+		logger.Error("Failed to save token to config", "error", err)
+		return err
+	}
+	logger.Debug("Token saved to config")
+	s.source = oauthConfig(s.cfg).TokenSource(context.Background(), token)
+	return nil
+}
+
+// saveToken はトークンを設定ファイルに保存する
+func saveToken(cfg *config.Config, token *oauth2.Token) error {
+	cfg.AccessToken = token.AccessToken
+	// リフレッシュ時に新しいリフレッシュトークンが返らない場合は既存のものを使い続ける
+	if token.RefreshToken != "" {
+		cfg.RefreshToken = token.RefreshToken
+	}
+	cfg.TokenExpiry = token.Expiry.Unix()
+	return cfg.Save()
+}
+
+// endpoint はSpotify Accounts ServiceのOAuthエンドポイント
+// ローカルのテスト用OAuthサーバーに向けられるよう変数にしている
+var endpoint = oauth2.Endpoint{
+	AuthURL:  spotifyauth.AuthURL,
+	TokenURL: spotifyauth.TokenURL,
+}
+
+func oauthConfig(cfg *config.Config) *oauth2.Config {
+	ep := endpoint
+	oc := &oauth2.Config{
+		ClientID:    cfg.ClientID,
+		RedirectURL: cfg.RedirectURL(),
+		Scopes:      scopes,
+	}
+	if cfg.UsesPKCE() {
+		// PKCE ではクライアントシークレットを使わず、client_id をリクエストボディで送る
+		ep.AuthStyle = oauth2.AuthStyleInParams
+	} else {
+		oc.ClientSecret = cfg.ClientSecret
+	}
+	oc.Endpoint = ep
+	return oc
+}
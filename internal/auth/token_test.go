@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"spotify-tui/internal/config"
+
+	"golang.org/x/oauth2"
+)
+
+// stubTokenSource は決まったトークンかエラーを返す TokenSource
+type stubTokenSource struct {
+	token *oauth2.Token
+	err   error
+}
+
+func (s stubTokenSource) Token() (*oauth2.Token, error) {
+	return s.token, s.err
+}
+
+// newTestConfig は一時ディレクトリを設定ディレクトリにして、平文で保存する設定を作る
+func newTestConfig(t *testing.T, access, refresh string) *config.Config {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	return &config.Config{
+		ClientID:      "client",
+		AccessToken:   access,
+		RefreshToken:  refresh,
+		SecretStorage: config.SecretStoragePlaintext,
+	}
+}
+
+func TestSavingTokenSourceSavesRefreshedToken(t *testing.T) {
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name        string
+		token       *oauth2.Token
+		wantRefresh string
+	}{
+		{"new refresh token", &oauth2.Token{AccessToken: "new", RefreshToken: "refresh2", Expiry: expiry}, "refresh2"},
+		// 新しいリフレッシュトークンが返らない場合は既存のものを使い続ける
+		{"no refresh token", &oauth2.Token{AccessToken: "new", Expiry: expiry}, "refresh"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, "old", "refresh")
+			s := &savingTokenSource{source: stubTokenSource{token: tt.token}, cfg: cfg}
+
+			token, err := s.Token()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if token.AccessToken != "new" {
+				t.Errorf("token = %q, want new", token.AccessToken)
+			}
+
+			saved, err := config.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved.AccessToken != "new" || saved.RefreshToken != tt.wantRefresh || saved.TokenExpiry != expiry.Unix() {
+				t.Errorf("saved token = %q, %q, %d; want new, %s, %d",
+					saved.AccessToken, saved.RefreshToken, saved.TokenExpiry, tt.wantRefresh, expiry.Unix())
+			}
+			path, err := config.ConfigPath()
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if perm := info.Mode().Perm(); perm != 0600 {
+				t.Errorf("config.json permissions = %o, want 600", perm)
+			}
+		})
+	}
+}
+
+func TestSavingTokenSourceKeepsUnchangedToken(t *testing.T) {
+	cfg := newTestConfig(t, "access", "refresh")
+	s := &savingTokenSource{source: stubTokenSource{token: &oauth2.Token{AccessToken: "access"}}, cfg: cfg}
+
+	for range 3 {
+		if _, err := s.Token(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path, err := config.ConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("config.json written for an unchanged token (err %v)", err)
+	}
+}
+
+func TestSavingTokenSourceErrors(t *testing.T) {
+	rejected := &oauth2.RetrieveError{ErrorCode: "invalid_grant"}
+	network := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	tests := []struct {
+		name    string
+		source  oauth2.TokenSource
+		refresh string
+		relogin bool
+	}{
+		{"not logged in", nil, "", true},
+		{"refresh rejected", stubTokenSource{err: rejected}, "refresh", true},
+		{"no refresh token", stubTokenSource{err: network}, "", true},
+		// ネットワークエラーは一時的なものなので、再ログインを求めない
+		{"network error", stubTokenSource{err: network}, "refresh", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, "access", tt.refresh)
+			s := &savingTokenSource{source: tt.source, cfg: cfg}
+			_, err := s.Token()
+			if err == nil {
+				t.Fatal("Token succeeded")
+			}
+			if got := errors.Is(err, ErrReloginRequired); got != tt.relogin {
+				t.Errorf("Token = %v; re-login required = %v, want %v", err, got, tt.relogin)
+			}
+		})
+	}
+}
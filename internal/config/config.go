@@ -2,16 +2,69 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 )
 
+// 認証方式
+const (
+	// AuthModeClientSecret はクライアントシークレットを使う Authorization Code フロー（デフォルト）
+	AuthModeClientSecret = "client_secret"
+	// AuthModePKCE はクライアントシークレットなしの Authorization Code with PKCE フロー
+	AuthModePKCE = "pkce"
+)
+
+// DefaultRedirectURI はOAuthのリダイレクト先のデフォルト値
+const DefaultRedirectURI = "http://127.0.0.1:8080/callback"
+
+// DefaultVolumeStep は音量を1回で変更する量（%）のデフォルト値
+const DefaultVolumeStep = 5
+
+// DefaultProfile は config.json を使う既定のプロファイル名
+// それ以外のプロファイルは profiles/<name>.json に保存する
+const DefaultProfile = "default"
+
+// ErrProfileNotFound は指定したプロファイルが存在しないことを表す
+var ErrProfileNotFound = errors.New("profile not found")
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
 type Config struct {
+	// profile はこの設定を読み込んだプロファイル名（ファイルには保存しない）
+	profile string
+
+	// AuthMode は認証方式（"client_secret" または "pkce"）、空の場合は "client_secret"
+	AuthMode     string `json:"auth_mode,omitempty"`
 	ClientID     string `json:"client_id"`
-	ClientSecret string `json:"client_secret"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 	TokenExpiry  int64  `json:"token_expiry"`
+
+	// SecretStorage はクライアントシークレットとトークンの保存先
+	// （"auto"、"keyring"、"file"、"plaintext"）、空の場合は "auto"
+	// plaintext 以外では config.json にシークレットを書かない
+	SecretStorage string `json:"secret_storage,omitempty"`
+
+	// RedirectURI はSpotifyアプリに登録したリダイレクトURI、空の場合はデフォルト値
+	// ログイン時のコールバックサーバーはこのホストとポートで待ち受ける
+	RedirectURI string `json:"redirect_uri,omitempty"`
+
+	// VolumeStep は +/- キーで変更する音量（%）、0の場合はデフォルト値
+	VolumeStep int `json:"volume_step,omitempty"`
+
+	// 以下はUIの設定で、未設定の項目はデフォルト値を使う（settings.go）
+	Keys        map[string][]string `json:"keys,omitempty"`
+	Polling     Polling             `json:"polling,omitzero"`
+	Theme       ThemeSettings       `json:"theme,omitzero"`
+	Layout      PanelRatios         `json:"layout,omitzero"`
+	LayoutMode  string              `json:"layout_mode,omitempty"`
+	StartupView string              `json:"startup_view,omitempty"`
 }
 
 func ConfigDir() (string, error) {
@@ -34,8 +87,54 @@ func ConfigPath() (string, error) {
 	return filepath.Join(dir, "config.json"), nil
 }
 
+// ProfilePath はプロファイルの設定ファイルのパスを返す
+func ProfilePath(name string) (string, error) {
+	if name == "" || name == DefaultProfile {
+		return ConfigPath()
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return "", err
+	}
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "profiles", name+".json"), nil
+}
+
+// ValidateProfileName はファイル名として安全なプロファイル名かを検証する
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// Load は既定のプロファイルを読み込む
 func Load() (*Config, error) {
-	path, err := ConfigPath()
+	return LoadProfile(DefaultProfile)
+}
+
+// LoadProfile はプロファイルの設定を読み込む
+// 既定のプロファイルはファイルがなければ空の設定を返し、
+// それ以外のプロファイルは ErrProfileNotFound を返す
+func LoadProfile(name string) (*Config, error) {
+	cfg, err := readProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.loadSecrets(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readProfile はシークレットを読み込まずに設定ファイルだけを読む
+func readProfile(name string) (*Config, error) {
+	if name == "" {
+		name = DefaultProfile
+	}
+	path, err := ProfilePath(name)
 	if err != nil {
 		return nil, err
 	}
@@ -43,35 +142,172 @@ func Load() (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return &Config{}, nil
+			if name == DefaultProfile {
+				return &Config{profile: name}, nil
+			}
+			return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
 		}
 		return nil, err
 	}
 
 	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return nil, err
+	if err := decodeConfig(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
 	}
+	cfg.profile = name
 	return &cfg, nil
 }
 
+// NewProfile は新しいプロファイルの空の設定を作成する（保存は Save で行う）
+func NewProfile(name string) (*Config, error) {
+	if name == DefaultProfile {
+		return nil, fmt.Errorf("profile %q already exists", name)
+	}
+	path, err := ProfilePath(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("profile %q already exists", name)
+	}
+	return &Config{profile: name}, nil
+}
+
+// Profiles は既定のプロファイルを先頭に、全プロファイル名を返す
+func Profiles() ([]string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "profiles"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if e.IsDir() || !ok || ValidateProfileName(name) != nil || name == DefaultProfile {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...), nil
+}
+
+// RemoveProfile はプロファイルの設定ファイル、シークレット、検索履歴を削除する
+// 既定のプロファイルは削除できない
+func RemoveProfile(name string) error {
+	if name == "" || name == DefaultProfile {
+		return errors.New("the default profile cannot be removed")
+	}
+	path, err := ProfilePath(name)
+	if err != nil {
+		return err
+	}
+	if cfg, err := readProfile(name); err == nil {
+		if err := cfg.deleteSecrets(); err != nil {
+			return fmt.Errorf("failed to delete secrets of profile %q: %w", name, err)
+		}
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+		}
+		return err
+	}
+	if err := removeSearchHistory(name); err != nil {
+		return err
+	}
+	return removeQueue(name)
+}
+
 func (c *Config) Save() error {
-	path, err := ConfigPath()
+	path, err := ProfilePath(c.Profile())
 	if err != nil {
 		return err
 	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
 
-	data, err := json.MarshalIndent(c, "", "  ")
+	// シークレットを先に保存し、config.json から消すのはその後にする
+	saved := *c
+	stored, err := c.saveSecrets()
 	if err != nil {
 		return err
 	}
+	if stored {
+		for _, value := range saved.secretFields() {
+			*value = ""
+		}
+	}
 
-	return os.WriteFile(path, data, 0600)
+	data, err := json.MarshalIndent(&saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(path, data, 0600)
+}
+
+// WriteFileAtomic は一時ファイルに書き込んでからリネームする
+// トークン更新の途中で終了しても設定ファイルが壊れないようにする
+// 設定ディレクトリに保存するほかのファイル（Up Next など）にも使う
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // リネーム済みの場合は何もしない
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// UsesPKCE は PKCE フローで認証するかを返す
+func (c *Config) UsesPKCE() bool {
+	return c.AuthMode == AuthModePKCE
+}
+
+// RedirectURL はOAuthのリダイレクトURIを返す
+func (c *Config) RedirectURL() string {
+	if c.RedirectURI == "" {
+		return DefaultRedirectURI
+	}
+	return c.RedirectURI
+}
+
+// Profile はこの設定のプロファイル名を返す
+func (c *Config) Profile() string {
+	if c.profile == "" {
+		return DefaultProfile
+	}
+	return c.profile
 }
 
 // Path は設定ファイルのパスを返す
 func (c *Config) Path() string {
-	path, err := ConfigPath()
+	path, err := ProfilePath(c.Profile())
 	if err != nil {
 		return ""
 	}
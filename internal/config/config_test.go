@@ -0,0 +1,143 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+)
+
+// saveProfile は暗号化ファイルにシークレットを保存するプロファイルを作る
+func saveProfile(t *testing.T, name, token string) *Config {
+	t.Helper()
+	cfg := &Config{profile: name, ClientID: "client", AccessToken: token, SecretStorage: SecretStorageFile}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveSearchHistory(name, []string{"query of " + name}); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestProfiles(t *testing.T) {
+	useTestFileStore(t)
+
+	// 設定ファイルがなくても既定のプロファイルは使える
+	names, err := Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{DefaultProfile}) {
+		t.Errorf("Profiles() = %v, want only the default", names)
+	}
+
+	saveProfile(t, DefaultProfile, "default-token")
+	for _, name := range []string{"work", "home"} {
+		cfg, err := NewProfile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saveProfile(t, cfg.Profile(), name+"-token")
+	}
+	if _, err := NewProfile("work"); err == nil {
+		t.Error("NewProfile(work) succeeded for an existing profile")
+	}
+	if _, err := NewProfile(DefaultProfile); err == nil {
+		t.Error("NewProfile(default) succeeded")
+	}
+
+	names, err = Profiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{DefaultProfile, "home", "work"}; !slices.Equal(names, want) {
+		t.Errorf("Profiles() = %v, want %v", names, want)
+	}
+
+	// トークンはプロファイルごとに別々に保存する
+	for _, name := range names {
+		cfg, err := LoadProfile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Profile() != name || cfg.AccessToken != name+"-token" {
+			t.Errorf("profile %s loaded as %s with token %q", name, cfg.Profile(), cfg.AccessToken)
+		}
+	}
+
+	if _, err := LoadProfile("missing"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("LoadProfile(missing) = %v, want ErrProfileNotFound", err)
+	}
+}
+
+func TestRemoveProfile(t *testing.T) {
+	store := useTestFileStore(t)
+	saveProfile(t, DefaultProfile, "default-token")
+	work := saveProfile(t, "work", "work-token")
+	path, err := ProfilePath("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemoveProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 設定ファイル、シークレット、検索履歴を削除し、ほかのプロファイルには触れない
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("profile file still exists (err %v)", err)
+	}
+	if _, err := store.Get(work.secretKey("access_token")); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("access token of the removed profile = %v, want ErrSecretNotFound", err)
+	}
+	if history, err := LoadSearchHistory("work"); err != nil || len(history) != 0 {
+		t.Errorf("search history of the removed profile = %v, %v", history, err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AccessToken != "default-token" {
+		t.Errorf("default token = %q after removing another profile", cfg.AccessToken)
+	}
+	if history, err := LoadSearchHistory(DefaultProfile); err != nil || len(history) != 1 {
+		t.Errorf("default search history = %v, %v after removing another profile", history, err)
+	}
+
+	if err := RemoveProfile("work"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("removing again = %v, want ErrProfileNotFound", err)
+	}
+	if err := RemoveProfile(DefaultProfile); err == nil {
+		t.Error("removed the default profile")
+	}
+}
+
+func TestValidateProfileName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"work", false},
+		{"Home_2", false},
+		{"a-b", false},
+		{"", true},
+		{"-work", true},
+		{"../work", true},
+		{"work/home", true},
+		{"work.json", true},
+		{"my profile", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProfileName(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateProfileName(%q) = %v, want error %v", tt.name, err, tt.wantErr)
+			}
+			// 不正な名前からはファイルのパスも作らない
+			if _, err := ProfilePath(tt.name); tt.name != "" && (err != nil) != tt.wantErr {
+				t.Errorf("ProfilePath(%q) = %v, want error %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ErrWrongPassphrase はパスフレーズが違うか、ファイルが改ざんされていることを表す
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted secrets file")
+
+const (
+	secretsFileVersion = 1
+	secretsFileKDF     = "pbkdf2-sha256"
+	// pbkdf2Iterations は鍵導出の反復回数（OWASP の推奨値）
+	pbkdf2Iterations = 600000
+)
+
+// secretsAAD は暗号文を他の用途のファイルと取り違えないための追加認証データ
+var secretsAAD = []byte("spotify-tui secrets v1")
+
+// encryptedFile は暗号化ファイルの形式
+// 鍵導出のパラメータを一緒に保存し、将来変更しても古いファイルを読めるようにする
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// EncryptedFileStore はパスフレーズから導出した鍵（PBKDF2-SHA256）と AES-256-GCM で
+// 暗号化したファイルにシークレットを保存する
+// キーリングが使えない環境のためのフォールバックで、デスクトップセッションを必要としない
+type EncryptedFileStore struct {
+	path       string
+	passphrase func(create bool) (string, error)
+	iterations int
+
+	mu sync.Mutex
+	// 導出した鍵はプロセス内で使い回し、パスフレーズの入力を1回で済ませる
+	salt []byte
+	key  []byte
+}
+
+// NewEncryptedFileStore は path に保存する EncryptedFileStore を作成する
+// passphrase は鍵の導出が必要になったときに1回だけ呼ばれる
+func NewEncryptedFileStore(path string, passphrase func(create bool) (string, error)) *EncryptedFileStore {
+	return &EncryptedFileStore{
+		path:       path,
+		passphrase: passphrase,
+		iterations: pbkdf2Iterations,
+	}
+}
+
+func (s *EncryptedFileStore) Name() string {
+	return "encrypted file " + s.path
+}
+
+func (s *EncryptedFileStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+func (s *EncryptedFileStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+	if current, ok := values[key]; ok && current == value {
+		return nil
+	}
+	values[key] = value
+	return s.store(values)
+}
+
+func (s *EncryptedFileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := values[key]; !ok {
+		return nil
+	}
+	delete(values, key)
+	return s.store(values)
+}
+
+// load はファイルを復号して全てのシークレットを返す
+// ファイルがない場合はパスフレーズを求めずに空のマップを返す
+func (s *EncryptedFileStore) load() (map[string]string, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+
+	var f encryptedFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	if f.Version != secretsFileVersion || f.KDF != secretsFileKDF {
+		return nil, fmt.Errorf("%s: unsupported secrets file (version %d, kdf %q)", s.path, f.Version, f.KDF)
+	}
+
+	key, err := s.deriveKey(f.Salt, f.Iterations, false)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, f.Nonce, f.Ciphertext, secretsAAD)
+	if err != nil {
+		// 次の操作でパスフレーズを入力し直せるようにする
+		s.salt, s.key = nil, nil
+		return nil, ErrWrongPassphrase
+	}
+
+	values := map[string]string{}
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	s.iterations = f.Iterations
+	return values, nil
+}
+
+// store は全てのシークレットを暗号化してファイルに書き込む
+// 初めて書き込むときはソルトを生成し、新しいパスフレーズを求める
+func (s *EncryptedFileStore) store(values map[string]string) error {
+	if s.key == nil {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		if _, err := s.deriveKey(salt, s.iterations, true); err != nil {
+			return err
+		}
+	}
+
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(s.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(encryptedFile{
+		Version:    secretsFileVersion,
+		KDF:        secretsFileKDF,
+		Iterations: s.iterations,
+		Salt:       s.salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, secretsAAD),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(s.path, data, 0600)
+}
+
+func (s *EncryptedFileStore) deriveKey(salt []byte, iterations int, create bool) ([]byte, error) {
+	if s.key != nil && bytes.Equal(s.salt, salt) {
+		return s.key, nil
+	}
+
+	passphrase, err := s.passphrase(create)
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == "" {
+		return nil, errors.New("empty passphrase")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	s.salt, s.key = salt, key
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
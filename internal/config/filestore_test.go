@@ -0,0 +1,178 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testIterations はテストで使う鍵導出の反復回数（実際の回数では遅すぎる）
+const testIterations = 1000
+
+// passphrases はパスフレーズを求められた回数と create を記録する
+type passphrases struct {
+	value string
+	calls []bool
+}
+
+func (p *passphrases) get(create bool) (string, error) {
+	p.calls = append(p.calls, create)
+	return p.value, nil
+}
+
+func newTestFileStore(path string, p *passphrases) *EncryptedFileStore {
+	s := NewEncryptedFileStore(path, p.get)
+	s.iterations = testIterations
+	return s
+}
+
+func TestEncryptedFileStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	p := &passphrases{value: "correct horse"}
+	s := newTestFileStore(path, p)
+
+	if _, err := s.Get("default/access_token"); !errors.Is(err, ErrSecretNotFound) {
+		t.Fatalf("Get from a missing file: err = %v, want ErrSecretNotFound", err)
+	}
+	if len(p.calls) != 0 {
+		t.Errorf("asked for a passphrase %d times before the file exists", len(p.calls))
+	}
+	if err := s.Set("default/access_token", "token-value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("work/client_secret", "secret-value"); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.calls) != 1 || !p.calls[0] {
+		t.Errorf("passphrase calls = %v, want one call creating the file", p.calls)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "token-value") || strings.Contains(string(data), "secret-value") {
+		t.Error("secrets file contains a plaintext secret")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("secrets file mode = %v, want 0600", perm)
+	}
+
+	// 別のインスタンス（次の起動）で同じパスフレーズから復号する
+	p2 := &passphrases{value: "correct horse"}
+	s2 := newTestFileStore(path, p2)
+	for key, want := range map[string]string{"default/access_token": "token-value", "work/client_secret": "secret-value"} {
+		got, err := s2.Get(key)
+		if err != nil || got != want {
+			t.Errorf("Get(%q) = %q, %v; want %q", key, got, err, want)
+		}
+	}
+	if len(p2.calls) != 1 || p2.calls[0] {
+		t.Errorf("passphrase calls = %v, want one call unlocking the file", p2.calls)
+	}
+
+	if err := s2.Delete("default/access_token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s2.Delete("default/access_token"); err != nil {
+		t.Errorf("deleting a missing secret: %v", err)
+	}
+	if _, err := s2.Get("default/access_token"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Get after Delete: err = %v, want ErrSecretNotFound", err)
+	}
+	if got, err := s2.Get("work/client_secret"); err != nil || got != "secret-value" {
+		t.Errorf("Get of the other secret after Delete = %q, %v", got, err)
+	}
+}
+
+func TestEncryptedFileStoreWrongPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	if err := newTestFileStore(path, &passphrases{value: "right"}).Set("default/access_token", "token"); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &passphrases{value: "wrong"}
+	s := newTestFileStore(path, p)
+	if _, err := s.Get("default/access_token"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("Get with a wrong passphrase: err = %v, want ErrWrongPassphrase", err)
+	}
+	if err := s.Set("default/access_token", "other"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Set with a wrong passphrase: err = %v, want ErrWrongPassphrase", err)
+	}
+
+	// 間違えた後は入力し直したパスフレーズで開ける
+	p.value = "right"
+	got, err := s.Get("default/access_token")
+	if err != nil || got != "token" {
+		t.Errorf("Get after retrying = %q, %v; want token", got, err)
+	}
+	if len(p.calls) != 3 {
+		t.Errorf("asked for a passphrase %d times, want once per attempt (3)", len(p.calls))
+	}
+}
+
+func TestEncryptedFileStoreCorrupted(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(f *encryptedFile)
+		want    error // nil の場合は ErrWrongPassphrase 以外のエラー
+	}{
+		{"ciphertext", func(f *encryptedFile) { f.Ciphertext[0] ^= 0xff }, ErrWrongPassphrase},
+		{"nonce", func(f *encryptedFile) { f.Nonce[0] ^= 0xff }, ErrWrongPassphrase},
+		{"salt", func(f *encryptedFile) { f.Salt[0] ^= 0xff }, ErrWrongPassphrase},
+		{"version", func(f *encryptedFile) { f.Version = 2 }, nil},
+		{"kdf", func(f *encryptedFile) { f.KDF = "scrypt" }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secrets.enc")
+			if err := newTestFileStore(path, &passphrases{value: "pass"}).Set("default/access_token", "token"); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var f encryptedFile
+			if err := json.Unmarshal(data, &f); err != nil {
+				t.Fatal(err)
+			}
+			tt.corrupt(&f)
+			if data, err = json.Marshal(f); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = newTestFileStore(path, &passphrases{value: "pass"}).Get("default/access_token")
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && (err == nil || errors.Is(err, ErrWrongPassphrase)) {
+				t.Errorf("err = %v, want an unsupported file error", err)
+			}
+		})
+	}
+
+	t.Run("not json", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "secrets.enc")
+		if err := os.WriteFile(path, []byte("garbage"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		p := &passphrases{value: "pass"}
+		if _, err := newTestFileStore(path, p).Get("default/access_token"); err == nil {
+			t.Error("Get from a garbage file succeeded")
+		}
+		if len(p.calls) != 0 {
+			t.Error("asked for a passphrase for a file that can't be read")
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// MaxSearchHistory は保存する検索履歴の最大件数
+const MaxSearchHistory = 100
+
+// SearchHistoryPath はプロファイルの検索履歴のファイルのパスを返す
+// プロファイルの一覧と混ざらないよう、history/<profile>.json に置く
+func SearchHistoryPath(profile string) (string, error) {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	if err := ValidateProfileName(profile); err != nil {
+		return "", err
+	}
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history", profile+".json"), nil
+}
+
+// LoadSearchHistory は検索履歴を新しい順に返す（ファイルがなければ空）
+func LoadSearchHistory(profile string) ([]string, error) {
+	path, err := SearchHistoryPath(profile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []string
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+	if len(history) > MaxSearchHistory {
+		history = history[:MaxSearchHistory]
+	}
+	return history, nil
+}
+
+// SaveSearchHistory は検索履歴を保存する
+// 検索語は他の人に見られたくないこともあるので、シークレットと同じく 0600 で書き込む
+func SaveSearchHistory(profile string, history []string) error {
+	path, err := SearchHistoryPath(profile)
+	if err != nil {
+		return err
+	}
+	if len(history) > MaxSearchHistory {
+		history = history[:MaxSearchHistory]
+	}
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, data, 0600)
+}
+
+// removeSearchHistory はプロファイルの検索履歴を削除する
+func removeSearchHistory(profile string) error {
+	path, err := SearchHistoryPath(profile)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringService はOSのキーリングに登録するサービス名
+const keyringService = "spotify-tui"
+
+// keyringStore はOSのキーリングにシークレットを保存する
+// Linux では D-Bus の Secret Service（GNOME Keyring、KWallet など）を使う
+type keyringStore struct{}
+
+// keyringAvailable はキーリングが使えるかを確認する
+// デスクトップセッションのない Linux（SSH 先など）では使えない
+func keyringAvailable() bool {
+	_, err := keyring.Get(keyringService, "availability-check")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+func (keyringStore) Name() string {
+	return "OS keyring"
+}
+
+func (keyringStore) Get(key string) (string, error) {
+	value, err := keyring.Get(keyringService, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrSecretNotFound
+	}
+	return value, err
+}
+
+func (keyringStore) Set(key, value string) error {
+	return keyring.Set(keyringService, key, value)
+}
+
+func (keyringStore) Delete(key string) error {
+	err := keyring.Delete(keyringService, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// QueuePath はプロファイルの管理キューを保存するファイルのパスを返す
+// 検索履歴と同じく、queue/<profile>.json に置く
+func QueuePath(profile string) (string, error) {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	if err := ValidateProfileName(profile); err != nil {
+		return "", err
+	}
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "queue", profile+".json"), nil
+}
+
+// removeQueue はプロファイルの管理キューを削除する
+func removeQueue(profile string) error {
+	path, err := QueuePath(profile)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
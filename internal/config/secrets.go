@@ -0,0 +1,212 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"spotify-tui/internal/logger"
+)
+
+// シークレット（クライアントシークレットとトークン）の保存先
+const (
+	// SecretStorageAuto はOSのキーリングを使い、使えない場合は暗号化ファイルに保存する（デフォルト）
+	SecretStorageAuto = "auto"
+	// SecretStorageKeyring はOSのキーリング（Secret Service / Keychain / Credential Manager）に保存する
+	SecretStorageKeyring = "keyring"
+	// SecretStorageFile はパスフレーズで暗号化したファイルに保存する
+	SecretStorageFile = "file"
+	// SecretStoragePlaintext は従来どおり config.json に平文で保存する
+	SecretStoragePlaintext = "plaintext"
+)
+
+// ErrSecretNotFound はシークレットが保存されていないことを表す
+var ErrSecretNotFound = errors.New("secret not found")
+
+// ErrPassphraseRequired は暗号化ファイルを開くパスフレーズを入力できないことを表す
+var ErrPassphraseRequired = errors.New("a passphrase is required to unlock the secrets file (set SPOTIFY_TUI_PASSPHRASE)")
+
+// SecretStore はシークレットを config.json の外に保存する
+// キーは "<プロファイル名>/<項目名>" の形式
+type SecretStore interface {
+	// Name はエラーメッセージやログに使う保存先の名前を返す
+	Name() string
+	// Get は保存されていない場合 ErrSecretNotFound を返す
+	Get(key string) (string, error)
+	Set(key, value string) error
+	// Delete は保存されていない場合も成功する
+	Delete(key string) error
+}
+
+// Passphrase は暗号化ファイルのパスフレーズを返す
+// create は新しいファイルを作成するときに true になる（確認入力に使う）
+// デフォルトでは EnvPassphrase を使い、main で端末からの入力に差し替える
+var Passphrase = EnvPassphrase
+
+// EnvPassphrase は環境変数 SPOTIFY_TUI_PASSPHRASE のパスフレーズを返す
+// 設定されていなければ ErrPassphraseRequired を返す
+func EnvPassphrase(create bool) (string, error) {
+	if p := os.Getenv("SPOTIFY_TUI_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	return "", ErrPassphraseRequired
+}
+
+var (
+	storesMu sync.Mutex
+	stores   = map[string]SecretStore{}
+)
+
+// secretStore は保存方式に対応する SecretStore を返す
+// 平文の場合は nil を返す。プロセス内では方式ごとに同じ SecretStore を使い回す
+func secretStore(mode string) (SecretStore, error) {
+	if mode == "" {
+		mode = SecretStorageAuto
+	}
+	if mode == SecretStoragePlaintext {
+		return nil, nil
+	}
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	if s, ok := stores[mode]; ok {
+		return s, nil
+	}
+
+	var s SecretStore
+	switch mode {
+	case SecretStorageAuto:
+		if keyringAvailable() {
+			s = keyringStore{}
+		} else {
+			logger.Info("OS keyring not available, using encrypted file for secrets")
+			fs, err := fileStoreLocked()
+			if err != nil {
+				return nil, err
+			}
+			s = fs
+		}
+	case SecretStorageKeyring:
+		if !keyringAvailable() {
+			return nil, errors.New("OS keyring is not available; set secret_storage to \"file\" or \"auto\"")
+		}
+		s = keyringStore{}
+	case SecretStorageFile:
+		fs, err := fileStoreLocked()
+		if err != nil {
+			return nil, err
+		}
+		s = fs
+	default:
+		return nil, fmt.Errorf("unknown secret_storage %q (use auto, keyring, file or plaintext)", mode)
+	}
+	stores[mode] = s
+	return s, nil
+}
+
+// fileStoreLocked は暗号化ファイルの SecretStore を返す
+// auto と file で同じインスタンスを使い、パスフレーズの入力を1回で済ませる
+// storesMu を保持した状態で呼ぶ
+func fileStoreLocked() (SecretStore, error) {
+	if s, ok := stores[SecretStorageFile]; ok {
+		return s, nil
+	}
+	dir, err := ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	s := NewEncryptedFileStore(filepath.Join(dir, "secrets.enc"), func(create bool) (string, error) {
+		return Passphrase(create)
+	})
+	stores[SecretStorageFile] = s
+	return s, nil
+}
+
+// secretFields は SecretStore に保存する項目を返す
+func (c *Config) secretFields() map[string]*string {
+	return map[string]*string{
+		"client_secret": &c.ClientSecret,
+		"access_token":  &c.AccessToken,
+		"refresh_token": &c.RefreshToken,
+	}
+}
+
+func (c *Config) secretKey(field string) string {
+	return c.Profile() + "/" + field
+}
+
+// loadSecrets は SecretStore からシークレットを読み込む
+// config.json に平文のシークレットが残っている場合は SecretStore に移して書き直す
+// 一部の項目だけが平文で残っている場合（client_secret だけを書き戻した場合など）も、
+// 保存済みのほかの項目を消さないよう、先に SecretStore から読み込んでから書き直す
+func (c *Config) loadSecrets() error {
+	store, err := secretStore(c.SecretStorage)
+	if err != nil || store == nil {
+		return err
+	}
+
+	migrate := false
+	for field, value := range c.secretFields() {
+		if *value != "" {
+			migrate = true
+			continue
+		}
+		v, err := store.Get(c.secretKey(field))
+		if errors.Is(err, ErrSecretNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s from %s: %w", field, store.Name(), err)
+		}
+		*value = v
+	}
+
+	if migrate {
+		if err := c.Save(); err != nil {
+			return fmt.Errorf("failed to move secrets to %s: %w", store.Name(), err)
+		}
+		logger.Info("Migrated plaintext secrets out of config", "profile", c.Profile(), "store", store.Name())
+	}
+	return nil
+}
+
+// saveSecrets はシークレットを SecretStore に書き込む
+// 平文で保存する場合は false を返す
+func (c *Config) saveSecrets() (bool, error) {
+	store, err := secretStore(c.SecretStorage)
+	if err != nil {
+		return false, err
+	}
+	if store == nil {
+		return false, nil
+	}
+
+	for field, value := range c.secretFields() {
+		key := c.secretKey(field)
+		if *value == "" {
+			err = store.Delete(key)
+		} else {
+			err = store.Set(key, *value)
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to save %s to %s: %w", field, store.Name(), err)
+		}
+	}
+	return true, nil
+}
+
+// deleteSecrets はプロファイルのシークレットを SecretStore から削除する
+func (c *Config) deleteSecrets() error {
+	store, err := secretStore(c.SecretStorage)
+	if err != nil || store == nil {
+		return err
+	}
+	for field := range c.secretFields() {
+		if err := store.Delete(c.secretKey(field)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useTestFileStore は設定ディレクトリを一時ディレクトリにして、
+// シークレットの保存先 "file" をテスト用の暗号化ファイルにする
+func useTestFileStore(t *testing.T) *EncryptedFileStore {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	storesMu.Lock()
+	saved := stores
+	s := newTestFileStore(filepath.Join(home, "secrets.enc"), &passphrases{value: "pass"})
+	stores = map[string]SecretStore{SecretStorageFile: s}
+	storesMu.Unlock()
+	t.Cleanup(func() {
+		storesMu.Lock()
+		stores = saved
+		storesMu.Unlock()
+	})
+	return s
+}
+
+func TestLoadSecretsMigratesPlaintext(t *testing.T) {
+	store := useTestFileStore(t)
+	path, err := ConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := `{
+  "client_id": "id",
+  "client_secret": "secret",
+  "access_token": "access",
+  "refresh_token": "refresh",
+  "token_expiry": 123,
+  "secret_storage": "file"
+}`
+	if err := os.WriteFile(path, []byte(plaintext), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ClientSecret != "secret" || cfg.AccessToken != "access" || cfg.RefreshToken != "refresh" {
+		t.Errorf("loaded secrets = %q, %q, %q", cfg.ClientSecret, cfg.AccessToken, cfg.RefreshToken)
+	}
+
+	// config.json からシークレットが消え、それ以外の設定は残る
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret", "access", "refresh"} {
+		if strings.Contains(string(data), `"`+secret+`"`) {
+			t.Errorf("config.json still contains %q:\n%s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), `"client_id": "id"`) || !strings.Contains(string(data), `"token_expiry": 123`) {
+		t.Errorf("config.json lost its settings:\n%s", data)
+	}
+	for field, want := range map[string]string{"client_secret": "secret", "access_token": "access", "refresh_token": "refresh"} {
+		if got, err := store.Get("default/" + field); err != nil || got != want {
+			t.Errorf("stored %s = %q, %v; want %q", field, got, err, want)
+		}
+	}
+
+	// 次に読み込むときは SecretStore から読む
+	cfg, err = Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ClientSecret != "secret" || cfg.AccessToken != "access" || cfg.RefreshToken != "refresh" {
+		t.Errorf("reloaded secrets = %q, %q, %q", cfg.ClientSecret, cfg.AccessToken, cfg.RefreshToken)
+	}
+}
+
+func TestLoadSecretsPlaintext(t *testing.T) {
+	store := useTestFileStore(t)
+	path, err := ConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := `{"client_id": "id", "access_token": "access", "token_expiry": 0, "secret_storage": "plaintext"}`
+	if err := os.WriteFile(path, []byte(plaintext), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AccessToken != "access" {
+		t.Errorf("access token = %q, want access", cfg.AccessToken)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != plaintext {
+		t.Errorf("config.json was rewritten:\n%s", data)
+	}
+	if _, err := os.Stat(store.path); !os.IsNotExist(err) {
+		t.Errorf("secrets file created for plaintext storage (err %v)", err)
+	}
+}
+
+func TestLoadSecretsMigratesPartially(t *testing.T) {
+	store := useTestFileStore(t)
+	for field, value := range map[string]string{"client_secret": "old", "access_token": "access", "refresh_token": "refresh"} {
+		if err := store.Set("default/"+field, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path, err := ConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// client_secret だけを config.json に書き戻した
+	plaintext := `{"client_id": "id", "client_secret": "new", "token_expiry": 123, "secret_storage": "file"}`
+	if err := os.WriteFile(path, []byte(plaintext), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ClientSecret != "new" || cfg.AccessToken != "access" || cfg.RefreshToken != "refresh" {
+		t.Errorf("loaded secrets = %q, %q, %q; want new, access, refresh", cfg.ClientSecret, cfg.AccessToken, cfg.RefreshToken)
+	}
+	// 書き戻した項目だけが移り、保存済みのトークンは消えない
+	for field, want := range map[string]string{"client_secret": "new", "access_token": "access", "refresh_token": "refresh"} {
+		if got, err := store.Get("default/" + field); err != nil || got != want {
+			t.Errorf("stored %s = %q, %v; want %q", field, got, err, want)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"new"`) {
+		t.Errorf("config.json still contains the client secret:\n%s", data)
+	}
+}
+
+func TestLoadSecretsLocked(t *testing.T) {
+	store := useTestFileStore(t)
+	if err := store.Set("default/access_token", "access"); err != nil {
+		t.Fatal(err)
+	}
+	path, err := ConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`{"client_id": "id", "secret_storage": "file"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// パスフレーズを入力できない状態（TUI の実行中）で、まだ開いていないファイルを読む
+	t.Setenv("SPOTIFY_TUI_PASSPHRASE", "")
+	locked := NewEncryptedFileStore(store.path, EnvPassphrase)
+	locked.iterations = testIterations
+	storesMu.Lock()
+	stores[SecretStorageFile] = locked
+	storesMu.Unlock()
+
+	if _, err := Load(); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("Load = %v, want ErrPassphraseRequired", err)
+	}
+
+	// 環境変数があれば開ける
+	t.Setenv("SPOTIFY_TUI_PASSPHRASE", "pass")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AccessToken != "access" {
+		t.Errorf("access token = %q, want access", cfg.AccessToken)
+	}
+}
@@ -0,0 +1,467 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration は設定ファイルに "5s" のような文字列で書く時間
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("durations must be strings like \"5s\" or \"500ms\", got %s", data)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: use a number with a unit like \"5s\" or \"500ms\"", s)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Polling はAPIをポーリングする間隔
+type Polling struct {
+	Playback Duration `json:"playback,omitzero"`
+	Queue    Duration `json:"queue,omitzero"`
+	Devices  Duration `json:"devices,omitzero"`
+}
+
+// UnmarshalJSON はエラーメッセージにフィールド名を含めるために項目ごとに読み込む
+func (p *Polling) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("polling must be an object like {\"playback\": \"1s\"}")
+	}
+	fields := map[string]*Duration{
+		"playback": &p.Playback,
+		"queue":    &p.Queue,
+		"devices":  &p.Devices,
+	}
+	for name, value := range raw {
+		field, ok := fields[name]
+		if !ok {
+			return fmt.Errorf("polling: unknown field %q (use playback, queue or devices)", name)
+		}
+		if err := field.UnmarshalJSON(value); err != nil {
+			return fmt.Errorf("polling.%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// DefaultPolling はポーリング間隔のデフォルト値
+var DefaultPolling = Polling{
+	Playback: Duration(time.Second),
+	Queue:    Duration(5 * time.Second),
+	Devices:  Duration(10 * time.Second),
+}
+
+// ポーリング間隔の下限（レート制限を避けるため）
+const (
+	minPlaybackPolling = 500 * time.Millisecond
+	minPolling         = time.Second
+)
+
+// Colors はUIの配色
+// 値は "#RRGGBB"、"#RGB"、またはANSIカラー番号（"0"〜"255"）
+// テーマでは空の項目は色を付けず、設定ファイルの theme ではテーマの色をそのまま使う
+type Colors struct {
+	Primary   string `json:"primary,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Muted     string `json:"muted,omitempty"`
+	Highlight string `json:"highlight,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DefaultColors はSpotify風のデフォルトの配色
+var DefaultColors = Colors{
+	Primary:   "#1DB954",
+	Text:      "#FFFFFF",
+	Muted:     "#B3B3B3",
+	Highlight: "#282828",
+	Error:     "#FF0000",
+}
+
+// ThemeSettings は設定ファイルの theme で、使うテーマと配色の上書きを指定する
+// 色はテーマの同じ項目を置き換える（テーマについては themes.go）
+type ThemeSettings struct {
+	Name string `json:"name,omitempty"`
+	Colors
+}
+
+// PanelRatios は上段の3パネル（サイドバー、メイン、キュー）の幅の比率
+type PanelRatios struct {
+	Sidebar int `json:"sidebar"`
+	Main    int `json:"main"`
+	Queue   int `json:"queue"`
+}
+
+// DefaultPanelRatios はパネル幅の比率のデフォルト値（3:4:3）
+var DefaultPanelRatios = PanelRatios{Sidebar: 3, Main: 4, Queue: 3}
+
+// 画面の構成（layout_mode）
+const (
+	// LayoutAuto は端末の大きさに合わせて構成を選ぶ（デフォルト）
+	LayoutAuto = "auto"
+	// LayoutColumns はサイドバー、メイン、キューを横に並べる
+	LayoutColumns = "columns"
+	// LayoutStacked は1つのパネルを全幅で表示し、Tab で切り替える
+	LayoutStacked = "stacked"
+	// LayoutMini はプレイヤーバーだけを表示する
+	LayoutMini = "mini"
+)
+
+var layoutModes = []string{LayoutAuto, LayoutColumns, LayoutStacked, LayoutMini}
+
+// 起動時の表示
+const (
+	// StartupPlaylists はサイドバーのプレイリスト一覧にフォーカスして起動する（デフォルト）
+	StartupPlaylists = "playlists"
+	// StartupLikedSongs は Liked Songs を開いて起動する
+	StartupLikedSongs = "liked_songs"
+	// StartupSearch は検索モードで起動する
+	StartupSearch = "search"
+	// StartupQueue はキューにフォーカスして起動する
+	StartupQueue = "queue"
+)
+
+var startupViews = []string{StartupPlaylists, StartupLikedSongs, StartupSearch, StartupQueue}
+
+// DefaultKeys はアクションごとのデフォルトのキー
+// keys で上書きしたアクションはそのキーだけが有効になり、空の配列で無効にできる
+// キー名は bubbletea の表記（"ctrl+a"、"shift+left"、"enter" など）で、スペースは "space"
+// "g l" のように空白で区切ると、続けて押すキーの組み合わせ（chord）になる
+var DefaultKeys = map[string][]string{
+	// プレイヤー（メイン画面のどこでも有効）
+	"quit":               {"q"},
+	"help":               {"?"},
+	"play_pause":         {"space"},
+	"next":               {"n"},
+	"previous":           {"p"},
+	"shuffle":            {"s"},
+	"repeat":             {"r"},
+	"seek_backward":      {"left"},
+	"seek_forward":       {"right"},
+	"seek_backward_long": {"shift+left"},
+	"seek_forward_long":  {"shift+right"},
+	"seek_percent":       {"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}, // n 番目（0 から数える）のキーで n×10% の位置へ
+	"volume_up":          {"+", "="},
+	"volume_down":        {"-"},
+	"mute":               {"m"},
+	"devices":            {"d"},
+	"accounts":           {"A"},
+	"relogin":            {"L"},
+	"theme":              {"T"},
+	"layout_mode":        {"v"},
+	"toggle_sidebar":     {"["},
+	"toggle_queue":       {"]"},
+	"grow_panel":         {">"},
+	"shrink_panel":       {"<"},
+	"search":             {"/"},
+	"like":               {"l"},
+	"like_playing":       {"g h"},
+	"like_album":         {"g L"},
+	"add_to_queue":       {"z"},
+	"add_to_up_next":     {"Z"},
+	"play_next":          {"g z"},
+	"clear_up_next":      {"X"},
+
+	// パネルの移動とリスト操作
+	"focus_next":   {"tab"},
+	"focus_prev":   {"shift+tab"},
+	"select":       {"enter"},
+	"up":           {"up", "k"},
+	"down":         {"down", "j"},
+	"go_top":       {"g g", "home"},
+	"go_bottom":    {"G", "end"},
+	"go_liked":     {"g l"},
+	"go_playlists": {"g p"},
+	"go_queue":     {"g q"},
+
+	// アーティストとアルバムのページ
+	"artist":         {"a"},
+	"playing_artist": {"g a"},
+	"album":          {"b"},
+	"playing_album":  {"g b"},
+	"back":           {"backspace"},
+
+	// プレイリストの編集（remove はサイドバーではプレイリスト、メインパネルでは曲が対象）
+	// キューでは remove と move_up、move_down で Up Next の曲を編集する
+	"create_playlist":         {"N"},
+	"rename_playlist":         {"R"},
+	"remove":                  {"x", "delete"},
+	"add_to_playlist":         {"P"},
+	"add_playing_to_playlist": {"g P"},
+	"move_up":                 {"K", "shift+up"},
+	"move_down":               {"J", "shift+down"},
+	"undo":                    {"u"},
+
+	// 検索モード（文字入力と衝突しないキーだけを使う）
+	"search_submit": {"enter"},
+	"search_cancel": {"esc"},
+	"search_delete": {"backspace", "ctrl+h"},
+	"search_up":     {"up"},
+	"search_down":   {"down"},
+
+	"search_next_section":    {"tab"},
+	"search_prev_section":    {"shift+tab"},
+	"search_artist":          {"alt+a"},
+	"search_album":           {"alt+b"},
+	"search_like":            {"alt+l"},
+	"search_add_to_playlist": {"alt+p"},
+	"search_add_to_queue":    {"alt+z"},
+	"search_add_to_up_next":  {"alt+Z"},
+
+	// デバイス選択・アカウント切り替え・プレイリスト選択
+	"picker_up":     {"up", "k"},
+	"picker_down":   {"down", "j"},
+	"picker_select": {"enter"},
+	"picker_close":  {"esc"},
+	"transfer_only": {"t"},
+}
+
+// PollingIntervals は未設定の項目をデフォルト値で補ったポーリング間隔を返す
+func (c *Config) PollingIntervals() Polling {
+	p := c.Polling
+	if p.Playback == 0 {
+		p.Playback = DefaultPolling.Playback
+	}
+	if p.Queue == 0 {
+		p.Queue = DefaultPolling.Queue
+	}
+	if p.Devices == 0 {
+		p.Devices = DefaultPolling.Devices
+	}
+	return p
+}
+
+// Panels はパネル幅の比率を返す
+func (c *Config) Panels() PanelRatios {
+	if c.Layout == (PanelRatios{}) {
+		return DefaultPanelRatios
+	}
+	return c.Layout
+}
+
+// Startup は起動時の表示を返す
+func (c *Config) Startup() string {
+	if c.StartupView == "" {
+		return StartupPlaylists
+	}
+	return c.StartupView
+}
+
+// StartupLayout は起動時の画面の構成を返す
+func (c *Config) StartupLayout() string {
+	if c.LayoutMode == "" {
+		return LayoutAuto
+	}
+	return c.LayoutMode
+}
+
+// KeyBindings はデフォルトのキーにユーザーの設定を上書きしたキー割り当てを返す
+func (c *Config) KeyBindings() map[string][]string {
+	bindings := make(map[string][]string, len(DefaultKeys))
+	for action, keys := range DefaultKeys {
+		bindings[action] = keys
+	}
+	for action, keys := range c.Keys {
+		bindings[action] = keys
+	}
+	return bindings
+}
+
+var colorPattern = regexp.MustCompile(`^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`)
+
+type colorProblem struct {
+	field   string
+	message string
+}
+
+// problems は色として解釈できない項目を返す（空の項目は問題にしない）
+func (c Colors) problems() []colorProblem {
+	var problems []colorProblem
+	check := func(field, v string) {
+		if v == "" || colorPattern.MatchString(v) {
+			return
+		}
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 && n <= 255 {
+			return
+		}
+		problems = append(problems, colorProblem{field,
+			fmt.Sprintf("must be a hex colour like \"#1DB954\" or an ANSI colour number 0-255, got %q", v)})
+	}
+	check("primary", c.Primary)
+	check("text", c.Text)
+	check("muted", c.Muted)
+	check("highlight", c.Highlight)
+	check("error", c.Error)
+	return problems
+}
+
+// Validate は設定値を検証し、問題をまとめて1つのエラーとして返す
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(field, format string, args ...any) {
+		problems = append(problems, field+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch c.AuthMode {
+	case "", AuthModeClientSecret, AuthModePKCE:
+	default:
+		add("auth_mode", "must be %q or %q, got %q", AuthModeClientSecret, AuthModePKCE, c.AuthMode)
+	}
+	switch c.SecretStorage {
+	case "", SecretStorageAuto, SecretStorageKeyring, SecretStorageFile, SecretStoragePlaintext:
+	default:
+		add("secret_storage", "must be one of auto, keyring, file, plaintext, got %q", c.SecretStorage)
+	}
+	if c.RedirectURI != "" {
+		u, err := url.Parse(c.RedirectURI)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			add("redirect_uri", "must be an absolute URL like %q, got %q", DefaultRedirectURI, c.RedirectURI)
+		}
+	}
+	if c.VolumeStep < 0 || c.VolumeStep > 100 {
+		add("volume_step", "must be between 1 and 100, got %d", c.VolumeStep)
+	}
+
+	checkPolling := func(field string, d Duration, min time.Duration) {
+		if d != 0 && time.Duration(d) < min {
+			add(field, "must be at least %s, got %s", min, time.Duration(d))
+		}
+	}
+	checkPolling("polling.playback", c.Polling.Playback, minPlaybackPolling)
+	checkPolling("polling.queue", c.Polling.Queue, minPolling)
+	checkPolling("polling.devices", c.Polling.Devices, minPolling)
+
+	if c.Theme.Name != "" && !themeNamePattern.MatchString(c.Theme.Name) {
+		add("theme.name", "must be a theme name like %q, got %q", ThemeLight, c.Theme.Name)
+	}
+	for _, problem := range c.Theme.Colors.problems() {
+		add("theme."+problem.field, "%s", problem.message)
+	}
+
+	if c.Layout != (PanelRatios{}) {
+		if c.Layout.Sidebar < 1 || c.Layout.Main < 1 || c.Layout.Queue < 1 {
+			add("layout", "sidebar, main and queue must all be at least 1, got %d:%d:%d",
+				c.Layout.Sidebar, c.Layout.Main, c.Layout.Queue)
+		}
+	}
+
+	if c.LayoutMode != "" && !contains(layoutModes, c.LayoutMode) {
+		add("layout_mode", "must be one of %s, got %q", strings.Join(layoutModes, ", "), c.LayoutMode)
+	}
+
+	if c.StartupView != "" && !contains(startupViews, c.StartupView) {
+		add("startup_view", "must be one of %s, got %q", strings.Join(startupViews, ", "), c.StartupView)
+	}
+
+	actions := make([]string, 0, len(c.Keys))
+	for action := range c.Keys {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		if _, ok := DefaultKeys[action]; !ok {
+			add("keys."+action, "unknown action (known actions: %s)", strings.Join(knownActions(), ", "))
+			continue
+		}
+		for _, k := range c.Keys[action] {
+			if strings.TrimSpace(k) == "" {
+				add("keys."+action, "key names must not be empty (use \"space\" for the space bar)")
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid settings:\n  %s", strings.Join(problems, "\n  "))
+}
+
+func knownActions() []string {
+	actions := make([]string, 0, len(DefaultKeys))
+	for action := range DefaultKeys {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeConfig は設定ファイルを読み込み、位置の分かるエラーメッセージを返す
+// 綴りの誤りに気づけるよう、未知のフィールドはエラーにする
+func decodeConfig(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		line, col := position(data, syntaxErr.Offset)
+		return fmt.Errorf("line %d, column %d: %v", line, col, syntaxErr)
+	case errors.As(err, &typeErr):
+		line, col := position(data, typeErr.Offset)
+		return fmt.Errorf("line %d, column %d: %s must be %s, got %s", line, col, typeErr.Field, jsonTypeName(typeErr.Type.Kind().String()), typeErr.Value)
+	}
+	return err
+}
+
+func jsonTypeName(kind string) string {
+	switch kind {
+	case "string":
+		return "a string"
+	case "int", "int64":
+		return "a number"
+	case "slice":
+		return "an array"
+	case "map", "struct":
+		return "an object"
+	case "bool":
+		return "true or false"
+	}
+	return kind
+}
+
+// position はエラーのオフセット（エラーまでに読んだバイト数）を、最後に読んだ文字の行と列（1始まり）に変換する
+// 構文の誤りでは誤った文字、型の誤りでは値の最後の文字の位置になる
+func position(data []byte, offset int64) (line, col int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset > 0 {
+		offset--
+	}
+	before := string(data[:offset])
+	line = strings.Count(before, "\n") + 1
+	col = int(offset) - strings.LastIndex(before, "\n")
+	return line, col
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string // エラーメッセージに含まれる文字列（空ならエラーなし）
+	}{
+		{"valid", `{"client_id": "id", "polling": {"playback": "2s"}, "layout": {"sidebar": 1, "main": 2, "queue": 1}}`, ""},
+		// 構文の誤りは行と列を示す
+		{"missing comma", "{\n  \"client_id\": \"id\"\n  \"volume_step\": 5\n}", "line 3, column 3: invalid character '\"' after object key:value pair"},
+		{"trailing comma", "{\n  \"client_id\": \"id\",\n}", "line 3, column 1: invalid character '}' looking for beginning of object key string"},
+		// 型の誤りは行と列、フィールド名と期待する型を示す
+		{"wrong type", "{\n  \"volume_step\": \"5\"\n}", `line 2, column 20: volume_step must be a number, got string`},
+		{"wrong nested type", `{"layout": {"sidebar": true}}`, `line 1, column 27: layout.sidebar must be a number, got bool`},
+		// 綴りの誤りに気づけるよう、未知のフィールドはエラーにする
+		{"unknown key", `{"client_id": "id", "volume": 5}`, `unknown field "volume"`},
+		{"unknown polling key", `{"polling": {"playbak": "1s"}}`, `polling: unknown field "playbak" (use playback, queue or devices)`},
+		{"bad duration", `{"polling": {"queue": "5"}}`, `polling.queue: invalid duration "5": use a number with a unit like "5s" or "500ms"`},
+		{"numeric duration", `{"polling": {"queue": 5}}`, `polling.queue: durations must be strings like "5s" or "500ms", got 5`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			err := decodeConfig([]byte(tt.data), &cfg)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("decodeConfig = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("decodeConfig = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string // エラーメッセージに含まれる行（空ならエラーなし）
+	}{
+		{"empty", Config{}, nil},
+		{"valid", Config{
+			AuthMode:    AuthModePKCE,
+			VolumeStep:  10,
+			Polling:     Polling{Playback: Duration(500 * time.Millisecond), Queue: Duration(time.Second)},
+			Theme:       ThemeSettings{Name: ThemeLight, Colors: Colors{Primary: "#fff", Muted: "240"}},
+			Layout:      PanelRatios{Sidebar: 1, Main: 1, Queue: 1},
+			LayoutMode:  LayoutStacked,
+			StartupView: StartupQueue,
+			Keys:        map[string][]string{"quit": {"ctrl+q"}, "like": {}},
+		}, nil},
+		{"volume step too large", Config{VolumeStep: 101}, []string{"volume_step: must be between 1 and 100, got 101"}},
+		{"volume step negative", Config{VolumeStep: -1}, []string{"volume_step: must be between 1 and 100, got -1"}},
+		{"polling too fast", Config{Polling: Polling{Playback: Duration(100 * time.Millisecond), Devices: Duration(500 * time.Millisecond)}}, []string{
+			"polling.playback: must be at least 500ms, got 100ms",
+			"polling.devices: must be at least 1s, got 500ms",
+		}},
+		{"zero panel", Config{Layout: PanelRatios{Sidebar: 0, Main: 4, Queue: 3}}, []string{"layout: sidebar, main and queue must all be at least 1, got 0:4:3"}},
+		{"bad colours", Config{Theme: ThemeSettings{Colors: Colors{Primary: "green", Error: "256"}}}, []string{
+			`theme.primary: must be a hex colour like "#1DB954" or an ANSI colour number 0-255, got "green"`,
+			`theme.error: must be a hex colour like "#1DB954" or an ANSI colour number 0-255, got "256"`,
+		}},
+		{"unknown choices", Config{AuthMode: "password", SecretStorage: "vault", LayoutMode: "grid", StartupView: "albums"}, []string{
+			`auth_mode: must be "client_secret" or "pkce", got "password"`,
+			`secret_storage: must be one of auto, keyring, file, plaintext, got "vault"`,
+			`layout_mode: must be one of auto, columns, stacked, mini, got "grid"`,
+			`startup_view: must be one of playlists, liked_songs, search, queue, got "albums"`,
+		}},
+		{"relative redirect", Config{RedirectURI: "/callback"}, []string{`redirect_uri: must be an absolute URL like "http://127.0.0.1:8080/callback", got "/callback"`}},
+		{"unknown action", Config{Keys: map[string][]string{"jump": {"j"}}}, []string{"keys.jump: unknown action (known actions: "}},
+		{"empty key", Config{Keys: map[string][]string{"quit": {" "}}}, []string{`keys.quit: key names must not be empty (use "space" for the space bar)`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate = nil, want %q", tt.want)
+			}
+			// 問題は1行ずつまとめて報告する
+			msg := err.Error()
+			if !strings.HasPrefix(msg, "invalid settings:\n") {
+				t.Errorf("Validate = %q, want it to start with \"invalid settings:\"", msg)
+			}
+			if got := strings.Count(msg, "\n  "); got != len(tt.want) {
+				t.Errorf("Validate reported %d problems, want %d:\n%s", got, len(tt.want), msg)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(msg, "\n  "+want) {
+					t.Errorf("Validate = %q, want a line %q", msg, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 組み込みテーマの名前
+const (
+	// ThemeSpotify はSpotify風の暗い配色（デフォルト）
+	ThemeSpotify = "spotify"
+	// ThemeLight は明るい背景の端末向けの配色
+	ThemeLight = "light"
+	// ThemeHighContrast はコントラストを強めた配色
+	ThemeHighContrast = "high-contrast"
+	// ThemeMonochrome は色を使わず、太字と反転だけで表示する（NO_COLOR のときに使う）
+	ThemeMonochrome = "monochrome"
+)
+
+var themeNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// ThemeDef は名前の付いた配色
+// 空の項目は色を付けない（ThemeMonochrome は全ての項目が空）
+type ThemeDef struct {
+	Name   string
+	Colors Colors
+}
+
+// BuiltinThemes は組み込みのテーマで、実行中のテーマ切り替えはこの順に進む
+var BuiltinThemes = []ThemeDef{
+	{ThemeSpotify, DefaultColors},
+	{ThemeLight, Colors{
+		Primary:   "#1A7F37",
+		Text:      "#191414",
+		Muted:     "#6A6A6A",
+		Highlight: "#E3E3E3",
+		Error:     "#C62828",
+	}},
+	{ThemeHighContrast, Colors{
+		Primary:   "#FFFF00",
+		Text:      "#FFFFFF",
+		Muted:     "#00FFFF",
+		Highlight: "#0000AA",
+		Error:     "#FF5555",
+	}},
+	{ThemeMonochrome, Colors{}},
+}
+
+// themeFile はユーザー定義のテーマファイル（themes/<name>.json）
+// base のテーマから始めて、指定した色だけを置き換える
+type themeFile struct {
+	Base string `json:"base,omitempty"`
+	Colors
+}
+
+// ThemesDir はユーザー定義のテーマを置くディレクトリを返す
+func ThemesDir() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "themes"), nil
+}
+
+// LoadThemes は組み込みのテーマと、ThemesDir にあるユーザー定義のテーマを返す
+// ユーザー定義のテーマは名前順に組み込みのテーマの後に並ぶ
+func LoadThemes() ([]ThemeDef, error) {
+	themes := append([]ThemeDef(nil), BuiltinThemes...)
+
+	dir, err := ThemesDir()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		theme, err := readTheme(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		themes = append(themes, theme)
+	}
+	return themes, nil
+}
+
+func readTheme(path string) (ThemeDef, error) {
+	name := strings.TrimSuffix(filepath.Base(path), ".json")
+	if !themeNamePattern.MatchString(name) {
+		return ThemeDef{}, fmt.Errorf("invalid theme name %q: use letters, digits, '-' and '_'", name)
+	}
+	if _, ok := FindTheme(BuiltinThemes, name); ok {
+		return ThemeDef{}, fmt.Errorf("%q is a built-in theme; choose another file name", name)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ThemeDef{}, err
+	}
+	var f themeFile
+	if err := decodeConfig(data, &f); err != nil {
+		return ThemeDef{}, err
+	}
+
+	var problems []string
+	for _, problem := range f.Colors.problems() {
+		problems = append(problems, problem.field+": "+problem.message)
+	}
+	base, ok := FindTheme(BuiltinThemes, f.Base)
+	switch {
+	case f.Base == "":
+		base, _ = FindTheme(BuiltinThemes, ThemeSpotify)
+	case !ok:
+		problems = append(problems, fmt.Sprintf("base: must be a built-in theme (%s), got %q", strings.Join(themeNames(BuiltinThemes), ", "), f.Base))
+	}
+	if len(problems) > 0 {
+		return ThemeDef{}, fmt.Errorf("invalid theme:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return ThemeDef{Name: name, Colors: base.Colors.With(f.Colors)}, nil
+}
+
+// FindTheme は名前の一致するテーマを返す
+func FindTheme(themes []ThemeDef, name string) (ThemeDef, bool) {
+	for _, t := range themes {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return ThemeDef{}, false
+}
+
+func themeNames(themes []ThemeDef) []string {
+	names := make([]string, len(themes))
+	for i, t := range themes {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// With は override で指定された項目だけを置き換えた配色を返す
+func (c Colors) With(override Colors) Colors {
+	replace := func(v *string, o string) {
+		if o != "" {
+			*v = o
+		}
+	}
+	replace(&c.Primary, override.Primary)
+	replace(&c.Text, override.Text)
+	replace(&c.Muted, override.Muted)
+	replace(&c.Highlight, override.Highlight)
+	replace(&c.Error, override.Error)
+	return c
+}
+
+// Resolve は起動時に使うテーマを themes から選ぶ
+// theme.name のテーマ（未設定なら ThemeSpotify）に theme の色を上書きする
+// 環境変数 NO_COLOR が設定されていれば、設定に関係なく ThemeMonochrome を使う
+func (s ThemeSettings) Resolve(themes []ThemeDef) (ThemeDef, error) {
+	if os.Getenv("NO_COLOR") != "" {
+		if t, ok := FindTheme(themes, ThemeMonochrome); ok {
+			return t, nil
+		}
+	}
+
+	name := s.Name
+	if name == "" {
+		name = ThemeSpotify
+	}
+	t, ok := FindTheme(themes, name)
+	if !ok {
+		return ThemeDef{}, fmt.Errorf("theme.name: unknown theme %q (available: %s)", name, strings.Join(themeNames(themes), ", "))
+	}
+	t.Colors = t.Colors.With(s.Colors)
+	return t, nil
+}
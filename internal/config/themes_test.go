@@ -0,0 +1,129 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeThemes は設定ディレクトリを一時ディレクトリにして、themes/<name>.json を書き込む
+func writeThemes(t *testing.T, files map[string]string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	dir, err := ThemesDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResolveTheme(t *testing.T) {
+	light, _ := FindTheme(BuiltinThemes, ThemeLight)
+	tests := []struct {
+		name     string
+		settings ThemeSettings
+		noColor  string
+		want     ThemeDef
+		wantErr  string
+	}{
+		{"default", ThemeSettings{}, "", ThemeDef{ThemeSpotify, DefaultColors}, ""},
+		{"built-in", ThemeSettings{Name: ThemeLight}, "", light, ""},
+		{"override colours", ThemeSettings{Name: ThemeLight, Colors: Colors{Primary: "#FF0000"}}, "",
+			ThemeDef{ThemeLight, light.Colors.With(Colors{Primary: "#FF0000"})}, ""},
+		{"NO_COLOR", ThemeSettings{Name: ThemeLight, Colors: Colors{Primary: "#FF0000"}}, "1", ThemeDef{ThemeMonochrome, Colors{}}, ""},
+		{"unknown", ThemeSettings{Name: "neon"}, "", ThemeDef{},
+			`theme.name: unknown theme "neon" (available: spotify, light, high-contrast, monochrome)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+			got, err := tt.settings.Resolve(BuiltinThemes)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Resolve() = %v, %v; want error %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadThemes(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	writeThemes(t, map[string]string{
+		"ocean":  `{"base": "light", "primary": "#0077BE"}`,
+		"forest": `{"primary": "22"}`,
+	})
+	themes, err := LoadThemes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ユーザー定義のテーマは名前順に組み込みのテーマの後に並ぶ
+	want := append(themeNames(BuiltinThemes), "forest", "ocean")
+	if got := themeNames(themes); !slices.Equal(got, want) {
+		t.Errorf("themes = %v, want %v", got, want)
+	}
+
+	// base のテーマ（未指定なら spotify）の、指定した色だけを置き換える
+	light, _ := FindTheme(BuiltinThemes, ThemeLight)
+	ocean, _ := FindTheme(themes, "ocean")
+	if want := light.Colors.With(Colors{Primary: "#0077BE"}); ocean.Colors != want {
+		t.Errorf("ocean = %+v, want %+v", ocean.Colors, want)
+	}
+	forest, _ := FindTheme(themes, "forest")
+	if want := DefaultColors.With(Colors{Primary: "22"}); forest.Colors != want {
+		t.Errorf("forest = %+v, want %+v", forest.Colors, want)
+	}
+
+	// ユーザー定義のテーマも theme.name に使える
+	got, err := ThemeSettings{Name: "ocean"}.Resolve(themes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != ocean {
+		t.Errorf("Resolve(ocean) = %+v, want %+v", got, ocean)
+	}
+}
+
+func TestLoadThemesInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		wantErr string
+	}{
+		{"built-in name", "light", `{}`, `"light" is a built-in theme; choose another file name`},
+		{"unknown base", "ocean", `{"base": "neon"}`, `base: must be a built-in theme (spotify, light, high-contrast, monochrome), got "neon"`},
+		{"bad colour", "ocean", `{"primary": "blue", "error": "256"}`, "primary: must be a hex colour"},
+		{"unknown key", "ocean", `{"primry": "#0077BE"}`, `unknown field "primry"`},
+		{"bad file name", "my theme", `{}`, `invalid theme name "my theme"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeThemes(t, map[string]string{tt.file: tt.data})
+			_, err := LoadThemes()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadThemes() = %v, want an error containing %q", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.file+".json") {
+				t.Errorf("error %q doesn't name the file", err)
+			}
+		})
+	}
+}
@@ -1,19 +1,110 @@
 package spotify
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
 	"spotify-tui/internal/logger"
+	"strings"
 	"time"
 
 	"github.com/zmb3/spotify/v2"
 )
 
+const apiBaseURL = "https://api.spotify.com/v1/"
+
 type Client struct {
 	client *spotify.Client
+	http   *http.Client
+}
+
+// NewClient は認証済みのHTTPクライアントからClientを作成する
+func NewClient(httpClient *http.Client) *Client {
+	return &Client{
+		client: spotify.New(httpClient),
+		http:   httpClient,
+	}
+}
+
+// Device はプレイヤーデバイスの情報
+// zmb3/spotify の PlayerDevice には supports_volume と is_private_session が
+// 含まれないため、APIのレスポンスを直接デコードする
+type Device struct {
+	spotify.PlayerDevice
+	IsPrivateSession bool `json:"is_private_session"`
+	SupportsVolume   bool `json:"supports_volume"`
+}
+
+// IsNoActiveDevice は再生操作がアクティブなデバイスがないために失敗したかを返す
+func IsNoActiveDevice(err error) bool {
+	var e spotify.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.Status == http.StatusNotFound && strings.Contains(e.Message, "No active device")
+}
+
+// IsInsufficientScope はトークンに必要な権限（スコープ）がないために失敗したかを返す
+// スコープを追加する前にログインしたトークンでは、ログインし直すまでこのエラーになる
+func IsInsufficientScope(err error) bool {
+	var e spotify.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.Status == http.StatusForbidden && strings.Contains(e.Message, "Insufficient client scope")
+}
+
+// getJSON は zmb3/spotify が対応していないフィールドを読むためにAPIを直接呼ぶ
+func (c *Client) getJSON(ctx context.Context, path string, result any) error {
+	return c.sendJSON(ctx, http.MethodGet, path, nil, result)
 }
 
-func NewClient(c *spotify.Client) *Client {
-	return &Client{client: c}
+// sendJSON は body を JSON にしてAPIを直接呼び、レスポンスを result にデコードする
+// body が nil の場合はリクエストの本文を送らず、result が nil の場合はレスポンスを読まない
+func (c *Client) sendJSON(ctx context.Context, method, path string, body, result any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, apiBaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusAccepted {
+		var e struct {
+			E spotify.Error `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.E.Message == "" {
+			return fmt.Errorf("spotify: unexpected HTTP %d: %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+		}
+		return e.E
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
 }
 
 func (c *Client) CurrentlyPlaying(ctx context.Context) (*spotify.CurrentlyPlaying, error) {
@@ -61,15 +152,17 @@ func (c *Client) Next(ctx context.Context) error {
 	return err
 }
 
-func (c *Client) SkipToNth(ctx context.Context, n int) error {
-	logger.Debug("API call", "method", "SkipToNth", "n", n)
-	for i := 0; i < n; i++ {
-		if err := c.client.Next(ctx); err != nil {
-			logger.Error("API error", "method", "SkipToNth", "error", err)
-			return err
-		}
+// SkipToQueueItem はキューの index 番目（0 が次の曲）の曲 uri から再生する
+// 再生中のコンテキストから外れた場合は lostContext が true になる
+func (c *Client) SkipToQueueItem(ctx context.Context, index int, uri spotify.URI) (lostContext bool, err error) {
+	logger.Debug("API call", "method", "SkipToQueueItem", "index", index, "uri", uri)
+	lostContext, err = skipToQueueItem(ctx, c, index, uri)
+	if err != nil {
+		logger.Error("API error", "method", "SkipToQueueItem", "error", err)
+	} else if lostContext {
+		logger.Info("Skipped to a queued track outside the playing context", "uri", uri)
 	}
-	return nil
+	return lostContext, err
 }
 
 func (c *Client) Previous(ctx context.Context) error {
@@ -90,24 +183,167 @@ func (c *Client) Seek(ctx context.Context, position time.Duration) error {
 	return err
 }
 
+// ページングの1回あたりの取得件数（APIの上限値）
+const (
+	playlistsPageSize      = 50
+	playlistTracksPageSize = 100
+	artistAlbumsPageSize   = 50
+	savedAlbumsPageSize    = 50
+	libraryIDsLimit        = 50 // 保存済みの確認と追加・削除で1回に指定できるIDの数
+)
+
 func (c *Client) UserPlaylists(ctx context.Context) ([]spotify.SimplePlaylist, error) {
 	logger.Debug("API call", "method", "UserPlaylists")
-	playlists, err := c.client.CurrentUsersPlaylists(ctx)
+	var allPlaylists []spotify.SimplePlaylist
+	for {
+		playlists, total, err := c.UserPlaylistsPage(ctx, len(allPlaylists))
+		if err != nil {
+			return nil, err
+		}
+		allPlaylists = append(allPlaylists, playlists...)
+		if len(playlists) == 0 || len(allPlaylists) >= total {
+			break
+		}
+	}
+	logger.Debug("API call completed", "method", "UserPlaylists", "totalPlaylists", len(allPlaylists))
+	return allPlaylists, nil
+}
+
+// UserPlaylistsPage は offset から1ページ分のプレイリストと総件数を返す
+func (c *Client) UserPlaylistsPage(ctx context.Context, offset int) ([]spotify.SimplePlaylist, int, error) {
+	logger.Debug("API call", "method", "UserPlaylistsPage", "offset", offset)
+	playlists, err := c.client.CurrentUsersPlaylists(ctx, spotify.Limit(playlistsPageSize), spotify.Offset(offset))
 	if err != nil {
-		logger.Error("API error", "method", "UserPlaylists", "error", err)
-		return nil, err
+		logger.Error("API error", "method", "UserPlaylistsPage", "error", err)
+		return nil, 0, err
 	}
-	return playlists.Playlists, nil
+	return playlists.Playlists, int(playlists.Total), nil
 }
 
 func (c *Client) PlaylistTracks(ctx context.Context, playlistID spotify.ID) ([]spotify.PlaylistTrack, error) {
 	logger.Debug("API call", "method", "PlaylistTracks", "playlistID", playlistID)
-	tracks, err := c.client.GetPlaylistTracks(ctx, playlistID)
+	var allTracks []spotify.PlaylistTrack
+	for {
+		tracks, total, err := c.PlaylistTracksPage(ctx, playlistID, len(allTracks))
+		if err != nil {
+			return nil, err
+		}
+		allTracks = append(allTracks, tracks...)
+		if len(tracks) == 0 || len(allTracks) >= total {
+			break
+		}
+	}
+	logger.Debug("API call completed", "method", "PlaylistTracks", "totalTracks", len(allTracks))
+	return allTracks, nil
+}
+
+// PlaylistTracksPage は offset から1ページ分のトラックと総件数を返す
+func (c *Client) PlaylistTracksPage(ctx context.Context, playlistID spotify.ID, offset int) ([]spotify.PlaylistTrack, int, error) {
+	logger.Debug("API call", "method", "PlaylistTracksPage", "playlistID", playlistID, "offset", offset)
+	tracks, err := c.client.GetPlaylistTracks(ctx, playlistID, spotify.Limit(playlistTracksPageSize), spotify.Offset(offset))
 	if err != nil {
-		logger.Error("API error", "method", "PlaylistTracks", "error", err)
+		logger.Error("API error", "method", "PlaylistTracksPage", "error", err)
+		return nil, 0, err
+	}
+	return tracks.Tracks, int(tracks.Total), nil
+}
+
+// CreatePlaylist はユーザーの非公開プレイリストを作成する
+func (c *Client) CreatePlaylist(ctx context.Context, userID, name string) (*spotify.FullPlaylist, error) {
+	logger.Debug("API call", "method", "CreatePlaylist", "name", name)
+	playlist, err := c.client.CreatePlaylistForUser(ctx, userID, name, "", false, false)
+	if err != nil {
+		logger.Error("API error", "method", "CreatePlaylist", "error", err)
 		return nil, err
 	}
-	return tracks.Tracks, nil
+	return playlist, nil
+}
+
+// RenamePlaylist はプレイリストの名前を変える
+func (c *Client) RenamePlaylist(ctx context.Context, playlistID spotify.ID, name string) error {
+	logger.Debug("API call", "method", "RenamePlaylist", "playlistID", playlistID, "name", name)
+	if err := c.client.ChangePlaylistName(ctx, playlistID, name); err != nil {
+		logger.Error("API error", "method", "RenamePlaylist", "error", err)
+		return err
+	}
+	return nil
+}
+
+// UnfollowPlaylist はプレイリストをライブラリから削除する
+// Web API にはプレイリストを削除する方法がなく、自分のプレイリストもフォローをやめると削除したことになる
+func (c *Client) UnfollowPlaylist(ctx context.Context, playlistID spotify.ID) error {
+	logger.Debug("API call", "method", "UnfollowPlaylist", "playlistID", playlistID)
+	if err := c.client.UnfollowPlaylist(ctx, playlistID); err != nil {
+		logger.Error("API error", "method", "UnfollowPlaylist", "error", err)
+		return err
+	}
+	return nil
+}
+
+// FollowPlaylist はプレイリストをライブラリに加える（削除したプレイリストを元に戻すのに使う）
+func (c *Client) FollowPlaylist(ctx context.Context, playlistID spotify.ID, public bool) error {
+	logger.Debug("API call", "method", "FollowPlaylist", "playlistID", playlistID)
+	if err := c.client.FollowPlaylist(ctx, playlistID, public); err != nil {
+		logger.Error("API error", "method", "FollowPlaylist", "error", err)
+		return err
+	}
+	return nil
+}
+
+// AddPlaylistItems は曲をプレイリストの position の位置に加え、新しいスナップショットIDを返す
+// position が負の場合は最後に加える（zmb3/spotify は位置を指定できないため、APIを直接呼ぶ）
+func (c *Client) AddPlaylistItems(ctx context.Context, playlistID spotify.ID, uris []spotify.URI, position int) (string, error) {
+	logger.Debug("API call", "method", "AddPlaylistItems", "playlistID", playlistID, "count", len(uris), "position", position)
+	body := struct {
+		URIs     []spotify.URI `json:"uris"`
+		Position *int          `json:"position,omitempty"`
+	}{URIs: uris}
+	if position >= 0 {
+		body.Position = &position
+	}
+	var result struct {
+		SnapshotID string `json:"snapshot_id"`
+	}
+	if err := c.sendJSON(ctx, http.MethodPost, "playlists/"+string(playlistID)+"/tracks", body, &result); err != nil {
+		logger.Error("API error", "method", "AddPlaylistItems", "error", err)
+		return "", err
+	}
+	return result.SnapshotID, nil
+}
+
+// RemovePlaylistItem はプレイリストの position の位置の曲を削除し、新しいスナップショットIDを返す
+// 位置は snapshotID の時点のプレイリストでの位置として扱われる
+func (c *Client) RemovePlaylistItem(ctx context.Context, playlistID spotify.ID, uri spotify.URI, position int, snapshotID string) (string, error) {
+	logger.Debug("API call", "method", "RemovePlaylistItem", "playlistID", playlistID, "uri", uri, "position", position)
+	tracks := []spotify.TrackToRemove{{URI: string(uri), Positions: []int{position}}}
+	snapshot, err := c.client.RemoveTracksFromPlaylistOpt(ctx, playlistID, tracks, snapshotID)
+	if err != nil {
+		logger.Error("API error", "method", "RemovePlaylistItem", "error", err)
+		return "", err
+	}
+	return snapshot, nil
+}
+
+// MovePlaylistItem はプレイリストの from の位置の曲を to の位置に移し、新しいスナップショットIDを返す
+// 位置は snapshotID の時点のプレイリストでの位置として扱われる
+func (c *Client) MovePlaylistItem(ctx context.Context, playlistID spotify.ID, from, to int, snapshotID string) (string, error) {
+	logger.Debug("API call", "method", "MovePlaylistItem", "playlistID", playlistID, "from", from, "to", to)
+	// API は移動先を「この位置の曲の前」で指定するので、後ろに移す場合は1つ先を指定する
+	insertBefore := to
+	if to > from {
+		insertBefore = to + 1
+	}
+	snapshot, err := c.client.ReorderPlaylistTracks(ctx, playlistID, spotify.PlaylistReorderOptions{
+		RangeStart:   spotify.Numeric(from),
+		RangeLength:  1,
+		InsertBefore: spotify.Numeric(insertBefore),
+		SnapshotID:   snapshotID,
+	})
+	if err != nil {
+		logger.Error("API error", "method", "MovePlaylistItem", "error", err)
+		return "", err
+	}
+	return snapshot, nil
 }
 
 func (c *Client) SavedTracks(ctx context.Context) ([]spotify.SavedTrack, error) {
@@ -136,6 +372,86 @@ func (c *Client) SavedTracks(ctx context.Context) ([]spotify.SavedTrack, error)
 	return allTracks, nil
 }
 
+// TracksSaved は曲が Liked Songs に保存されているかを ids の順に返す
+// API の上限を超える数のIDは分けて問い合わせる
+func (c *Client) TracksSaved(ctx context.Context, ids []spotify.ID) ([]bool, error) {
+	logger.Debug("API call", "method", "TracksSaved", "count", len(ids))
+	saved := make([]bool, 0, len(ids))
+	for chunk := range slices.Chunk(ids, libraryIDsLimit) {
+		result, err := c.client.UserHasTracks(ctx, chunk...)
+		if err != nil {
+			logger.Error("API error", "method", "TracksSaved", "error", err)
+			return nil, err
+		}
+		saved = append(saved, result...)
+	}
+	return saved, nil
+}
+
+// SaveTracks は曲を Liked Songs に保存する
+func (c *Client) SaveTracks(ctx context.Context, ids ...spotify.ID) error {
+	logger.Debug("API call", "method", "SaveTracks", "count", len(ids))
+	for chunk := range slices.Chunk(ids, libraryIDsLimit) {
+		if err := c.client.AddTracksToLibrary(ctx, chunk...); err != nil {
+			logger.Error("API error", "method", "SaveTracks", "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveSavedTracks は曲を Liked Songs から削除する
+func (c *Client) RemoveSavedTracks(ctx context.Context, ids ...spotify.ID) error {
+	logger.Debug("API call", "method", "RemoveSavedTracks", "count", len(ids))
+	for chunk := range slices.Chunk(ids, libraryIDsLimit) {
+		if err := c.client.RemoveTracksFromLibrary(ctx, chunk...); err != nil {
+			logger.Error("API error", "method", "RemoveSavedTracks", "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// AlbumsSaved はアルバムがライブラリに保存されているかを ids の順に返す
+// API の上限を超える数のIDは分けて問い合わせる
+func (c *Client) AlbumsSaved(ctx context.Context, ids []spotify.ID) ([]bool, error) {
+	logger.Debug("API call", "method", "AlbumsSaved", "count", len(ids))
+	saved := make([]bool, 0, len(ids))
+	for chunk := range slices.Chunk(ids, libraryIDsLimit) {
+		result, err := c.client.UserHasAlbums(ctx, chunk...)
+		if err != nil {
+			logger.Error("API error", "method", "AlbumsSaved", "error", err)
+			return nil, err
+		}
+		saved = append(saved, result...)
+	}
+	return saved, nil
+}
+
+// SaveAlbums はアルバムをライブラリに保存する
+func (c *Client) SaveAlbums(ctx context.Context, ids ...spotify.ID) error {
+	logger.Debug("API call", "method", "SaveAlbums", "count", len(ids))
+	for chunk := range slices.Chunk(ids, libraryIDsLimit) {
+		if err := c.client.AddAlbumsToLibrary(ctx, chunk...); err != nil {
+			logger.Error("API error", "method", "SaveAlbums", "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveSavedAlbums はアルバムをライブラリから削除する
+func (c *Client) RemoveSavedAlbums(ctx context.Context, ids ...spotify.ID) error {
+	logger.Debug("API call", "method", "RemoveSavedAlbums", "count", len(ids))
+	for chunk := range slices.Chunk(ids, libraryIDsLimit) {
+		if err := c.client.RemoveAlbumsFromLibrary(ctx, chunk...); err != nil {
+			logger.Error("API error", "method", "RemoveSavedAlbums", "error", err)
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *Client) PlayTrackInContext(ctx context.Context, contextURI spotify.URI, offset int) error {
 	logger.Debug("API call", "method", "PlayTrackInContext", "contextURI", contextURI, "offset", offset)
 	opts := &spotify.PlayOptions{
@@ -149,6 +465,35 @@ func (c *Client) PlayTrackInContext(ctx context.Context, contextURI spotify.URI,
 	return err
 }
 
+// PlayContext はコンテキストを先頭から再生する
+// アーティストや番組のコンテキストは位置を指定できないので、PlayTrackInContext ではなくこちらを使う
+func (c *Client) PlayContext(ctx context.Context, contextURI spotify.URI) error {
+	logger.Debug("API call", "method", "PlayContext", "contextURI", contextURI)
+	opts := &spotify.PlayOptions{
+		PlaybackContext: &contextURI,
+	}
+	err := c.client.PlayOpt(ctx, opts)
+	if err != nil {
+		logger.Error("API error", "method", "PlayContext", "error", err)
+	}
+	return err
+}
+
+// PlayTrackURIInContext はコンテキストの中の trackURI の曲から再生する
+// コンテキストの中での位置がわからない場合（キューの曲を再生し終えて戻る場合など）に使う
+func (c *Client) PlayTrackURIInContext(ctx context.Context, contextURI, trackURI spotify.URI) error {
+	logger.Debug("API call", "method", "PlayTrackURIInContext", "contextURI", contextURI, "trackURI", trackURI)
+	opts := &spotify.PlayOptions{
+		PlaybackContext: &contextURI,
+		PlaybackOffset:  &spotify.PlaybackOffset{URI: trackURI},
+	}
+	err := c.client.PlayOpt(ctx, opts)
+	if err != nil {
+		logger.Error("API error", "method", "PlayTrackURIInContext", "error", err)
+	}
+	return err
+}
+
 func (c *Client) PlayTrackFromURIList(ctx context.Context, uris []spotify.URI, offset int) error {
 	logger.Debug("API call", "method", "PlayTrackFromURIList", "uriCount", len(uris), "offset", offset)
 	if len(uris) == 0 {
@@ -215,20 +560,198 @@ func (c *Client) SetRepeat(ctx context.Context, state string) error {
 	return err
 }
 
-func (c *Client) Search(ctx context.Context, query string) ([]spotify.FullTrack, error) {
-	logger.Debug("API call", "method", "Search", "query", query)
-	results, err := c.client.Search(ctx, query, spotify.SearchTypeTrack)
+// SearchResults は検索結果を種類ごとにまとめたもの
+type SearchResults struct {
+	Tracks    []spotify.FullTrack
+	Artists   []spotify.FullArtist
+	Albums    []spotify.SimpleAlbum
+	Playlists []spotify.SimplePlaylist
+	Shows     []spotify.FullShow
+	Episodes  []spotify.EpisodePage
+
+	// Paging は検索した種類ごとのページの位置
+	Paging map[spotify.SearchType]SearchPaging
+}
+
+// SearchPaging は検索結果の1つの種類のページの位置
+type SearchPaging struct {
+	Total int // API が返した結果の総数
+	Next  int // 次のページの offset（次のページがなければ 0）
+}
+
+// searchTypes は検索する種類（トラック、アーティスト、アルバム、プレイリスト、番組、エピソード）
+const searchTypes = spotify.SearchTypeTrack | spotify.SearchTypeArtist | spotify.SearchTypeAlbum |
+	spotify.SearchTypePlaylist | spotify.SearchTypeShow | spotify.SearchTypeEpisode
+
+// MaxSearchOffset は検索で指定できる offset の上限（API の制限）
+// total がこれより多くても、この位置より先のページは取得できない
+const MaxSearchOffset = 1000
+
+// Search は全ての種類の最初のページを検索する
+func (c *Client) Search(ctx context.Context, query string) (*SearchResults, error) {
+	return c.search(ctx, query, searchTypes, 0)
+}
+
+// SearchMore は searchType の1種類だけを offset から検索する（検索結果の続きのページ）
+func (c *Client) SearchMore(ctx context.Context, query string, searchType spotify.SearchType, offset int) (*SearchResults, error) {
+	return c.search(ctx, query, searchType, offset)
+}
+
+func (c *Client) search(ctx context.Context, query string, types spotify.SearchType, offset int) (*SearchResults, error) {
+	logger.Debug("API call", "method", "Search", "query", query, "offset", offset)
+	results, err := c.client.Search(ctx, query, types, spotify.Offset(offset))
 	if err != nil {
 		logger.Error("API error", "method", "Search", "error", err)
 		return nil, err
 	}
 
-	if results.Tracks == nil {
-		return []spotify.FullTrack{}, nil
+	// APIは削除済みの項目を null で返すことがあるので、URIのない項目を除く
+	// 次のページの位置は除く前の件数で数える
+	r := &SearchResults{Paging: make(map[spotify.SearchType]SearchPaging)}
+	if results.Tracks != nil {
+		r.Tracks = results.Tracks.Tracks
+		r.Paging[spotify.SearchTypeTrack] = newSearchPaging(int(results.Tracks.Total), int(results.Tracks.Offset), len(results.Tracks.Tracks))
+	}
+	if results.Artists != nil {
+		r.Artists = results.Artists.Artists
+		r.Paging[spotify.SearchTypeArtist] = newSearchPaging(int(results.Artists.Total), int(results.Artists.Offset), len(results.Artists.Artists))
+	}
+	if results.Albums != nil {
+		r.Albums = results.Albums.Albums
+		r.Paging[spotify.SearchTypeAlbum] = newSearchPaging(int(results.Albums.Total), int(results.Albums.Offset), len(results.Albums.Albums))
+	}
+	if results.Playlists != nil {
+		for _, pl := range results.Playlists.Playlists {
+			if pl.URI != "" {
+				r.Playlists = append(r.Playlists, pl)
+			}
+		}
+		r.Paging[spotify.SearchTypePlaylist] = newSearchPaging(int(results.Playlists.Total), int(results.Playlists.Offset), len(results.Playlists.Playlists))
+	}
+	if results.Shows != nil {
+		for _, show := range results.Shows.Shows {
+			if show.URI != "" {
+				r.Shows = append(r.Shows, show)
+			}
+		}
+		r.Paging[spotify.SearchTypeShow] = newSearchPaging(int(results.Shows.Total), int(results.Shows.Offset), len(results.Shows.Shows))
+	}
+	if results.Episodes != nil {
+		for _, ep := range results.Episodes.Episodes {
+			if ep.URI != "" {
+				r.Episodes = append(r.Episodes, ep)
+			}
+		}
+		r.Paging[spotify.SearchTypeEpisode] = newSearchPaging(int(results.Episodes.Total), int(results.Episodes.Offset), len(results.Episodes.Episodes))
+	}
+
+	logger.Debug("API call completed", "method", "Search",
+		"tracks", len(r.Tracks), "artists", len(r.Artists), "albums", len(r.Albums),
+		"playlists", len(r.Playlists), "shows", len(r.Shows), "episodes", len(r.Episodes))
+	return r, nil
+}
+
+// newSearchPaging はページの offset と件数から次のページの位置を求める
+func newSearchPaging(total, offset, count int) SearchPaging {
+	p := SearchPaging{Total: total}
+	if next := offset + count; count > 0 && next < min(total, MaxSearchOffset) {
+		p.Next = next
+	}
+	return p
+}
+
+// Album はアルバムの情報と全トラック
+// zmb3/spotify の FullAlbum には label が含まれないため、APIのレスポンスを直接デコードする
+type Album struct {
+	spotify.FullAlbum
+	Label string `json:"label"`
+}
+
+// Album はアルバムの情報（発売日、レーベル）と全トラックを返す
+// 2ページ目以降のトラックは Tracks に加える
+func (c *Client) Album(ctx context.Context, albumID spotify.ID) (*Album, error) {
+	logger.Debug("API call", "method", "Album", "albumID", albumID)
+	var album Album
+	if err := c.getJSON(ctx, "albums/"+string(albumID), &album); err != nil {
+		logger.Error("API error", "method", "Album", "error", err)
+		return nil, err
+	}
+
+	// NextPage は page.Tracks の配列に上書きでデコードするので、読み込んだトラックは別の配列に集める
+	tracks := append([]spotify.SimpleTrack(nil), album.Tracks.Tracks...)
+	page := album.Tracks
+	for {
+		err := c.client.NextPage(ctx, &page)
+		if errors.Is(err, spotify.ErrNoMorePages) {
+			break
+		}
+		if err != nil {
+			logger.Error("API error", "method", "Album", "error", err)
+			return nil, err
+		}
+		tracks = append(tracks, page.Tracks...)
 	}
+	album.Tracks.Tracks = tracks
 
-	logger.Debug("API call completed", "method", "Search", "resultCount", len(results.Tracks.Tracks))
-	return results.Tracks.Tracks, nil
+	logger.Debug("API call completed", "method", "Album", "trackCount", len(tracks))
+	return &album, nil
+}
+
+// SavedAlbumsPage は保存したアルバムを offset から1ページ分と総件数を返す
+func (c *Client) SavedAlbumsPage(ctx context.Context, offset int) ([]spotify.SavedAlbum, int, error) {
+	logger.Debug("API call", "method", "SavedAlbumsPage", "offset", offset)
+	albums, err := c.client.CurrentUsersAlbums(ctx, spotify.Limit(savedAlbumsPageSize), spotify.Offset(offset))
+	if err != nil {
+		logger.Error("API error", "method", "SavedAlbumsPage", "error", err)
+		return nil, 0, err
+	}
+	return albums.Albums, int(albums.Total), nil
+}
+
+// ArtistTopTracks はアーティストの人気曲（最大10曲）を返す
+// country は ISO 3166-1 alpha-2 の国コードで、空ならトークンのユーザーの国を使う
+func (c *Client) ArtistTopTracks(ctx context.Context, artistID spotify.ID, country string) ([]spotify.FullTrack, error) {
+	logger.Debug("API call", "method", "ArtistTopTracks", "artistID", artistID, "country", country)
+	if country == "" {
+		country = spotify.MarketFromToken
+	}
+	tracks, err := c.client.GetArtistsTopTracks(ctx, artistID, country)
+	if err != nil {
+		logger.Error("API error", "method", "ArtistTopTracks", "error", err)
+	}
+	return tracks, err
+}
+
+// Artist はアーティストの情報（フォロワー数、ジャンル）を返す
+func (c *Client) Artist(ctx context.Context, artistID spotify.ID) (*spotify.FullArtist, error) {
+	logger.Debug("API call", "method", "Artist", "artistID", artistID)
+	artist, err := c.client.GetArtist(ctx, artistID)
+	if err != nil {
+		logger.Error("API error", "method", "Artist", "error", err)
+	}
+	return artist, err
+}
+
+// ArtistAlbumsPage はアーティストの albumType（アルバム、シングル、コンピレーション）を offset から1ページ分返す
+func (c *Client) ArtistAlbumsPage(ctx context.Context, artistID spotify.ID, albumType spotify.AlbumType, offset int) ([]spotify.SimpleAlbum, int, error) {
+	logger.Debug("API call", "method", "ArtistAlbumsPage", "artistID", artistID, "offset", offset)
+	albums, err := c.client.GetArtistAlbums(ctx, artistID, []spotify.AlbumType{albumType},
+		spotify.Limit(artistAlbumsPageSize), spotify.Offset(offset))
+	if err != nil {
+		logger.Error("API error", "method", "ArtistAlbumsPage", "error", err)
+		return nil, 0, err
+	}
+	return albums.Albums, int(albums.Total), nil
+}
+
+// RelatedArtists はアーティストに似たアーティストを返す
+func (c *Client) RelatedArtists(ctx context.Context, artistID spotify.ID) ([]spotify.FullArtist, error) {
+	logger.Debug("API call", "method", "RelatedArtists", "artistID", artistID)
+	artists, err := c.client.GetRelatedArtists(ctx, artistID)
+	if err != nil {
+		logger.Error("API error", "method", "RelatedArtists", "error", err)
+	}
+	return artists, err
 }
 
 func (c *Client) CurrentUser(ctx context.Context) (*spotify.PrivateUser, error) {
@@ -249,13 +772,29 @@ func (c *Client) GetQueue(ctx context.Context) (*spotify.Queue, error) {
 	return result, err
 }
 
-func (c *Client) PlayerDevices(ctx context.Context) ([]spotify.PlayerDevice, error) {
+// AddToQueue は曲（またはエピソード）を再生キューの最後に加える
+// zmb3/spotify の QueueSong はトラックのIDしか指定できないため、APIを直接呼ぶ
+func (c *Client) AddToQueue(ctx context.Context, uri spotify.URI) error {
+	logger.Debug("API call", "method", "AddToQueue", "uri", uri)
+	path := "me/player/queue?" + url.Values{"uri": {string(uri)}}.Encode()
+	if err := c.sendJSON(ctx, http.MethodPost, path, nil, nil); err != nil {
+		logger.Error("API error", "method", "AddToQueue", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (c *Client) PlayerDevices(ctx context.Context) ([]Device, error) {
 	logger.Debug("API call", "method", "PlayerDevices")
-	result, err := c.client.PlayerDevices(ctx)
+	var result struct {
+		Devices []Device `json:"devices"`
+	}
+	err := c.getJSON(ctx, "me/player/devices", &result)
 	if err != nil {
 		logger.Error("API error", "method", "PlayerDevices", "error", err)
+		return nil, err
 	}
-	return result, err
+	return result.Devices, nil
 }
 
 func (c *Client) SetVolume(ctx context.Context, volume int) error {
@@ -267,9 +806,11 @@ func (c *Client) SetVolume(ctx context.Context, volume int) error {
 	return err
 }
 
-func (c *Client) TransferPlayback(ctx context.Context, deviceID spotify.ID) error {
-	logger.Debug("API call", "method", "TransferPlayback", "deviceID", deviceID)
-	err := c.client.TransferPlayback(ctx, deviceID, true)
+// TransferPlayback は再生を deviceID のデバイスに移す
+// play が false の場合は現在の再生状態を維持する
+func (c *Client) TransferPlayback(ctx context.Context, deviceID spotify.ID, play bool) error {
+	logger.Debug("API call", "method", "TransferPlayback", "deviceID", deviceID, "play", play)
+	err := c.client.TransferPlayback(ctx, deviceID, play)
 	if err != nil {
 		logger.Error("API error", "method", "TransferPlayback", "error", err)
 	}
@@ -0,0 +1,63 @@
+package spotify
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// NewDemoPlayer はデモ用のライブラリを持つ FakePlayer を作成する
+// Spotifyアカウントなしでオフラインに TUI を動かすために使う
+func NewDemoPlayer() *FakePlayer {
+	f := NewFakePlayer(nil)
+
+	f.SetUser(spotify.PrivateUser{
+		User: spotify.User{
+			ID:          "demo",
+			DisplayName: "Demo User",
+			URI:         "spotify:user:demo",
+			Followers:   spotify.Followers{Count: 42},
+		},
+		Product: "premium",
+	})
+
+	f.SetDevices([]Device{
+		{PlayerDevice: spotify.PlayerDevice{ID: "demo-computer", Name: "Demo Laptop", Type: "Computer", Active: true, Volume: 60}, SupportsVolume: true},
+		{PlayerDevice: spotify.PlayerDevice{ID: "demo-speaker", Name: "Living Room", Type: "Speaker", Volume: 35}, SupportsVolume: true},
+		// スマートフォンは音量をAPIから変更できない
+		{PlayerDevice: spotify.PlayerDevice{ID: "demo-phone", Name: "Pocket Phone", Type: "Smartphone", Volume: 80}},
+		{PlayerDevice: spotify.PlayerDevice{ID: "demo-tv", Name: "Bedroom TV", Type: "TV", Restricted: true, Volume: 20}, IsPrivateSession: true},
+	})
+
+	artists := []string{"The Midnight Owls", "Neon Harbor", "Paper Satellites", "Quiet Riot Club", "Luna Park"}
+	albums := []string{"Night Drive", "Coastal Lights", "Orbit", "Small Hours", "Daydream"}
+	words := []string{"Echo", "Static", "Glow", "Drift", "Signal", "Ember", "Tide", "Hollow", "Bloom", "Vapor", "Pulse", "Haze"}
+
+	var all []spotify.FullTrack
+	for i := 0; i < 60; i++ {
+		a := i % len(artists)
+		name := fmt.Sprintf("%s %s", words[i%len(words)], words[(i/len(words)+i)%len(words)])
+		duration := time.Duration(150+(i*37)%120) * time.Second
+		all = append(all, FakeTrack(fmt.Sprintf("demo%03d", i), name, artists[a], albums[a], duration))
+	}
+
+	f.SetSavedTracks(all[:25])
+	f.SetSavedAlbums([]spotify.ID{"night-drive", "orbit", "daydream"})
+	f.AddPlaylist(spotify.SimplePlaylist{ID: "demo-focus", Name: "Deep Focus"}, all[10:30])
+	f.AddPlaylist(spotify.SimplePlaylist{ID: "demo-commute", Name: "Commute Mix"}, all[25:45])
+	f.AddPlaylist(spotify.SimplePlaylist{ID: "demo-weekend", Name: "Weekend Vibes"}, all[40:60])
+
+	// 検索の Shows と Episodes のセクションに出る番組
+	f.AddShow(spotify.SimpleShow{ID: "demo-liner-notes", Name: "Liner Notes", Publisher: "Neon Harbor"}, []spotify.EpisodePage{
+		FakeEpisode("demo-ep-001", "The Making of Night Drive", "2024-03-01", 42*time.Minute),
+		FakeEpisode("demo-ep-002", "Synths on a Budget", "2024-03-15", 38*time.Minute),
+		FakeEpisode("demo-ep-003", "Touring Coastal Lights", "2024-04-01", 51*time.Minute),
+	})
+	f.AddShow(spotify.SimpleShow{ID: "demo-signal-hour", Name: "Signal Hour", Publisher: "Demo Radio"}, []spotify.EpisodePage{
+		FakeEpisode("demo-ep-101", "Echoes from the Small Hours", "2024-05-10", 58*time.Minute),
+		FakeEpisode("demo-ep-102", "Drift and Static", "2024-05-17", 61*time.Minute),
+	})
+
+	return f
+}
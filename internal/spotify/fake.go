@@ -0,0 +1,1207 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// 実APIと同じ形式のエラーを返す
+var (
+	errNoActiveDevice = spotify.Error{Message: "Player command failed: No active device found", Status: http.StatusNotFound}
+	errRestricted     = spotify.Error{Message: "Player command failed: Restriction violated", Status: http.StatusForbidden}
+	errNotFound       = spotify.Error{Message: "Non existing id", Status: http.StatusNotFound}
+)
+
+// FakePlayer はSpotify Web APIを模したインメモリの Player 実装
+// 再生位置は注入された時計から計算するので、テストでは時計を進めるだけで
+// 曲の終了やキューの進行を決定的に再現できる
+type FakePlayer struct {
+	mu  sync.Mutex
+	now func() time.Time
+	rng *rand.Rand
+
+	// ライブラリ
+	user           spotify.PrivateUser
+	playlists      []spotify.SimplePlaylist
+	playlistTracks map[spotify.ID][]spotify.FullTrack
+	unfollowed     map[spotify.ID]spotify.SimplePlaylist // フォローをやめたプレイリスト（フォローし直すと戻る）
+	snapshots      int                                   // スナップショットIDの連番
+	removed        []spotify.FullTrack                   // プレイリストから削除した曲（実APIと同じく、加え直せるように残す）
+	saved          []spotify.FullTrack
+	savedAlbums    []spotify.ID
+	shows          []spotify.SimpleShow
+	showEpisodes   map[spotify.ID][]spotify.EpisodePage
+	devices        []Device
+
+	// 再生状態
+	contextURI  spotify.URI
+	contextType string
+	list        []spotify.FullTrack // 再生中コンテキストのトラック
+	order       []int               // 再生順（シャッフル時は並び替える）
+	pos         int                 // order 内の現在位置
+	queue       []spotify.FullTrack // ユーザーが追加したキュー
+	current     *spotify.FullTrack
+	playing     bool
+	progress    time.Duration
+	updatedAt   time.Time
+	shuffle     bool
+	repeat      string
+}
+
+// NewFakePlayer は空の FakePlayer を作成する
+// now が nil の場合は time.Now を使う
+func NewFakePlayer(now func() time.Time) *FakePlayer {
+	if now == nil {
+		now = time.Now
+	}
+	return &FakePlayer{
+		now:            now,
+		rng:            rand.New(rand.NewSource(1)),
+		playlistTracks: make(map[spotify.ID][]spotify.FullTrack),
+		showEpisodes:   make(map[spotify.ID][]spotify.EpisodePage),
+		unfollowed:     make(map[spotify.ID]spotify.SimplePlaylist),
+		repeat:         "off",
+		updatedAt:      now(),
+	}
+}
+
+// FakeTrack はテストやデモ用のトラックを作成する
+func FakeTrack(id, name, artist, album string, duration time.Duration) spotify.FullTrack {
+	t := spotify.FullTrack{
+		SimpleTrack: spotify.SimpleTrack{
+			ID:       spotify.ID(id),
+			URI:      spotify.URI("spotify:track:" + id),
+			Name:     name,
+			Duration: spotify.Numeric(duration.Milliseconds()),
+			Artists: []spotify.SimpleArtist{{
+				ID:   spotify.ID(slug(artist)),
+				URI:  spotify.URI("spotify:artist:" + slug(artist)),
+				Name: artist,
+			}},
+			Type: "track",
+		},
+		Album: spotify.SimpleAlbum{
+			ID:   spotify.ID(slug(album)),
+			URI:  spotify.URI("spotify:album:" + slug(album)),
+			Name: album,
+		},
+	}
+	return t
+}
+
+// FakeEpisode はテストやデモ用のエピソードを作成する（番組は AddShow で設定する）
+func FakeEpisode(id, name, releaseDate string, duration time.Duration) spotify.EpisodePage {
+	return spotify.EpisodePage{
+		ID:          spotify.ID(id),
+		URI:         spotify.URI("spotify:episode:" + id),
+		Name:        name,
+		ReleaseDate: releaseDate,
+		Duration_ms: spotify.Numeric(duration.Milliseconds()),
+		IsPlayable:  true,
+		Type:        "episode",
+	}
+}
+
+func slug(s string) string {
+	return strings.ReplaceAll(strings.ToLower(s), " ", "-")
+}
+
+// SetUser はログインユーザーを設定する
+func (f *FakePlayer) SetUser(user spotify.PrivateUser) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.user = user
+}
+
+// AddPlaylist はプレイリストとそのトラックを追加する
+func (f *FakePlayer) AddPlaylist(pl spotify.SimplePlaylist, tracks []spotify.FullTrack) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if pl.URI == "" {
+		pl.URI = spotify.URI("spotify:playlist:" + string(pl.ID))
+	}
+	if pl.Owner.ID == "" {
+		pl.Owner = f.user.User
+	}
+	if pl.SnapshotID == "" {
+		pl.SnapshotID = f.nextSnapshot()
+	}
+	pl.Tracks.Total = spotify.Numeric(len(tracks))
+	f.playlists = append(f.playlists, pl)
+	f.playlistTracks[pl.ID] = append([]spotify.FullTrack(nil), tracks...)
+}
+
+// AddShow は番組とそのエピソードを追加する
+func (f *FakePlayer) AddShow(show spotify.SimpleShow, episodes []spotify.EpisodePage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if show.URI == "" {
+		show.URI = spotify.URI("spotify:show:" + string(show.ID))
+	}
+	show.Type = "show"
+	f.shows = append(f.shows, show)
+	eps := make([]spotify.EpisodePage, len(episodes))
+	for i, ep := range episodes {
+		ep.Show = show
+		eps[i] = ep
+	}
+	f.showEpisodes[show.ID] = eps
+}
+
+// episodeTrack は再生するエピソードを、再生状態やキューで扱うトラックの形にする
+// 番組名をアーティストとして表示できるよう、番組をアーティストに入れる
+func episodeTrack(ep spotify.EpisodePage) spotify.FullTrack {
+	return spotify.FullTrack{
+		SimpleTrack: spotify.SimpleTrack{
+			ID:       ep.ID,
+			URI:      ep.URI,
+			Name:     ep.Name,
+			Duration: ep.Duration_ms,
+			Artists:  []spotify.SimpleArtist{{Name: ep.Show.Name, URI: ep.Show.URI}},
+			Type:     "episode",
+		},
+	}
+}
+
+// showTracks は番組のエピソードを再生する順（追加した順）のトラックで返す
+func (f *FakePlayer) showTracks(showID spotify.ID) []spotify.FullTrack {
+	var tracks []spotify.FullTrack
+	for _, ep := range f.showEpisodes[showID] {
+		tracks = append(tracks, episodeTrack(ep))
+	}
+	return tracks
+}
+
+// SetSavedTracks は Liked Songs を設定する
+func (f *FakePlayer) SetSavedTracks(tracks []spotify.FullTrack) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.saved = append([]spotify.FullTrack(nil), tracks...)
+}
+
+// SetSavedAlbums は保存したアルバムを設定する（カタログにある曲のアルバムのID）
+func (f *FakePlayer) SetSavedAlbums(albumIDs []spotify.ID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.savedAlbums = append([]spotify.ID(nil), albumIDs...)
+}
+
+// SetDevices は利用可能なデバイスを設定する
+func (f *FakePlayer) SetDevices(devices []Device) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.devices = append([]Device(nil), devices...)
+}
+
+// sync は前回の呼び出しからの経過時間だけ再生を進める
+// 曲が終わった場合はリピート設定に従って次の曲へ移る
+func (f *FakePlayer) sync() {
+	now := f.now()
+	if f.playing && f.current != nil {
+		f.progress += now.Sub(f.updatedAt)
+		for f.playing && f.current != nil {
+			dur := f.current.TimeDuration()
+			if dur <= 0 || f.progress < dur {
+				break
+			}
+			f.progress -= dur
+			if f.repeat == "track" {
+				continue
+			}
+			f.advance()
+		}
+	}
+	f.updatedAt = now
+}
+
+// advance は次の曲へ進む（キューが優先）
+func (f *FakePlayer) advance() {
+	if len(f.queue) > 0 {
+		t := f.queue[0]
+		f.queue = f.queue[1:]
+		f.current = &t
+		return
+	}
+	if f.pos+1 < len(f.order) {
+		f.pos++
+	} else if f.repeat == "context" && len(f.order) > 0 {
+		f.pos = 0
+	} else {
+		// コンテキストの最後まで再生したら停止する
+		f.playing = false
+		f.progress = 0
+		return
+	}
+	t := f.list[f.order[f.pos]]
+	f.current = &t
+}
+
+func (f *FakePlayer) activeDevice() *Device {
+	for i := range f.devices {
+		if f.devices[i].Active {
+			return &f.devices[i]
+		}
+	}
+	return nil
+}
+
+// checkDevice は操作対象のアクティブデバイスがあるか確認する
+func (f *FakePlayer) checkDevice() error {
+	d := f.activeDevice()
+	if d == nil {
+		return errNoActiveDevice
+	}
+	if d.Restricted {
+		return errRestricted
+	}
+	return nil
+}
+
+// start は tracks を offset の位置から再生する
+func (f *FakePlayer) start(tracks []spotify.FullTrack, offset int, contextURI spotify.URI, contextType string) error {
+	if err := f.checkDevice(); err != nil {
+		return err
+	}
+	if len(tracks) == 0 {
+		return errRestricted
+	}
+	if offset < 0 || offset >= len(tracks) {
+		offset = 0
+	}
+	f.list = tracks
+	f.contextURI = contextURI
+	f.contextType = contextType
+	f.order = make([]int, len(tracks))
+	for i := range f.order {
+		f.order[i] = i
+	}
+	f.pos = offset
+	if f.shuffle {
+		f.shuffleFrom(offset)
+	}
+	t := f.list[f.order[f.pos]]
+	f.current = &t
+	f.playing = true
+	f.progress = 0
+	return nil
+}
+
+// shuffleFrom は再生中の曲を先頭に置き、残りをランダムに並べる
+func (f *FakePlayer) shuffleFrom(current int) {
+	rest := make([]int, 0, len(f.list))
+	for i := range f.list {
+		if i != current {
+			rest = append(rest, i)
+		}
+	}
+	f.rng.Shuffle(len(rest), func(i, j int) { rest[i], rest[j] = rest[j], rest[i] })
+	f.order = append([]int{current}, rest...)
+	f.pos = 0
+}
+
+func (f *FakePlayer) currentlyPlaying() spotify.CurrentlyPlaying {
+	cp := spotify.CurrentlyPlaying{
+		Timestamp: f.now().UnixMilli(),
+	}
+	if f.current == nil {
+		return cp
+	}
+	t := *f.current
+	cp.Item = &t
+	cp.Playing = f.playing
+	cp.Progress = spotify.Numeric(f.progress.Milliseconds())
+	cp.PlaybackContext = spotify.PlaybackContext{
+		Type: f.contextType,
+		URI:  f.contextURI,
+	}
+	return cp
+}
+
+// catalog はライブラリ内の全トラックを重複なしで返す
+func (f *FakePlayer) catalog() []spotify.FullTrack {
+	seen := make(map[spotify.URI]bool)
+	var all []spotify.FullTrack
+	add := func(tracks []spotify.FullTrack) {
+		for _, t := range tracks {
+			if !seen[t.URI] {
+				seen[t.URI] = true
+				all = append(all, t)
+			}
+		}
+	}
+	add(f.saved)
+	for _, pl := range f.playlists {
+		add(f.playlistTracks[pl.ID])
+	}
+	return all
+}
+
+// trackByURI はカタログかプレイリストから削除したトラック、番組のエピソードを返す
+func (f *FakePlayer) trackByURI(uri spotify.URI) (spotify.FullTrack, bool) {
+	for _, t := range slices.Concat(f.catalog(), f.removed) {
+		if t.URI == uri {
+			return t, true
+		}
+	}
+	for _, show := range f.shows {
+		for _, ep := range f.showEpisodes[show.ID] {
+			if ep.URI == uri {
+				return episodeTrack(ep), true
+			}
+		}
+	}
+	return spotify.FullTrack{}, false
+}
+
+func (f *FakePlayer) CurrentlyPlaying(ctx context.Context) (*spotify.CurrentlyPlaying, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	cp := f.currentlyPlaying()
+	return &cp, nil
+}
+
+func (f *FakePlayer) PlayerState(ctx context.Context) (*spotify.PlayerState, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	// デバイスも再生中の曲もない場合、実APIは空のレスポンスを返す
+	d := f.activeDevice()
+	if d == nil || f.current == nil {
+		return &spotify.PlayerState{}, nil
+	}
+	return &spotify.PlayerState{
+		CurrentlyPlaying: f.currentlyPlaying(),
+		Device:           d.PlayerDevice,
+		ShuffleState:     f.shuffle,
+		RepeatState:      f.repeat,
+	}, nil
+}
+
+func (f *FakePlayer) Play(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	if err := f.checkDevice(); err != nil {
+		return err
+	}
+	if f.current == nil {
+		return errRestricted
+	}
+	f.playing = true
+	return nil
+}
+
+func (f *FakePlayer) Pause(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	if err := f.checkDevice(); err != nil {
+		return err
+	}
+	f.playing = false
+	return nil
+}
+
+func (f *FakePlayer) Next(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	if err := f.checkDevice(); err != nil {
+		return err
+	}
+	if f.current == nil {
+		return errRestricted
+	}
+	f.progress = 0
+	f.advance()
+	return nil
+}
+
+func (f *FakePlayer) SkipToQueueItem(ctx context.Context, index int, uri spotify.URI) (bool, error) {
+	return skipToQueueItem(ctx, f, index, uri)
+}
+
+func (f *FakePlayer) Previous(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	if err := f.checkDevice(); err != nil {
+		return err
+	}
+	if f.current == nil {
+		return errRestricted
+	}
+	// 実クライアントと同様に、3秒以上再生していれば曲の先頭に戻る
+	if f.progress < 3*time.Second && f.pos > 0 {
+		f.pos--
+		t := f.list[f.order[f.pos]]
+		f.current = &t
+	}
+	f.progress = 0
+	return nil
+}
+
+func (f *FakePlayer) Seek(ctx context.Context, position time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	if err := f.checkDevice(); err != nil {
+		return err
+	}
+	if f.current == nil {
+		return errRestricted
+	}
+	if position < 0 {
+		position = 0
+	}
+	f.progress = position
+	// 曲の長さを超えた場合は次の曲へ進む
+	if position >= f.current.TimeDuration() {
+		f.progress = 0
+		f.advance()
+	}
+	return nil
+}
+
+func (f *FakePlayer) UserPlaylists(ctx context.Context) ([]spotify.SimplePlaylist, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]spotify.SimplePlaylist(nil), f.playlists...), nil
+}
+
+func (f *FakePlayer) UserPlaylistsPage(ctx context.Context, offset int) ([]spotify.SimplePlaylist, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	start, end := pageBounds(len(f.playlists), offset, playlistsPageSize)
+	return append([]spotify.SimplePlaylist(nil), f.playlists[start:end]...), len(f.playlists), nil
+}
+
+func (f *FakePlayer) PlaylistTracks(ctx context.Context, playlistID spotify.ID) ([]spotify.PlaylistTrack, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tracks, ok := f.playlistTracks[playlistID]
+	if !ok {
+		return nil, errNotFound
+	}
+	return toPlaylistTracks(tracks), nil
+}
+
+func (f *FakePlayer) PlaylistTracksPage(ctx context.Context, playlistID spotify.ID, offset int) ([]spotify.PlaylistTrack, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, 0, err
+	}
+	tracks, ok := f.playlistTracks[playlistID]
+	if !ok {
+		return nil, 0, errNotFound
+	}
+	start, end := pageBounds(len(tracks), offset, playlistTracksPageSize)
+	return toPlaylistTracks(tracks[start:end]), len(tracks), nil
+}
+
+// nextSnapshot は新しいスナップショットIDを返す
+func (f *FakePlayer) nextSnapshot() string {
+	f.snapshots++
+	return fmt.Sprintf("snapshot-%d", f.snapshots)
+}
+
+// playlistIndex は f.playlists でのプレイリストの位置を返す（なければ -1）
+func (f *FakePlayer) playlistIndex(playlistID spotify.ID) int {
+	for i, pl := range f.playlists {
+		if pl.ID == playlistID {
+			return i
+		}
+	}
+	return -1
+}
+
+// editPlaylist は自分のプレイリストの曲を edit で変更し、スナップショットIDを更新する
+// 実APIと同じく、ほかのユーザーのプレイリストは共同編集の場合だけ変更できる
+func (f *FakePlayer) editPlaylist(playlistID spotify.ID, edit func([]spotify.FullTrack) ([]spotify.FullTrack, error)) (string, error) {
+	i := f.playlistIndex(playlistID)
+	if i < 0 {
+		return "", errNotFound
+	}
+	pl := &f.playlists[i]
+	if pl.Owner.ID != f.user.ID && !pl.Collaborative {
+		return "", spotify.Error{Message: "You cannot edit this playlist", Status: http.StatusForbidden}
+	}
+	tracks, err := edit(f.playlistTracks[playlistID])
+	if err != nil {
+		return "", err
+	}
+	f.playlistTracks[playlistID] = tracks
+	pl.Tracks.Total = spotify.Numeric(len(tracks))
+	pl.SnapshotID = f.nextSnapshot()
+	return pl.SnapshotID, nil
+}
+
+// CreatePlaylist は実APIと同じく、新しいプレイリストを一覧の先頭に加える
+func (f *FakePlayer) CreatePlaylist(ctx context.Context, userID, name string) (*spotify.FullPlaylist, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// フォローをやめたプレイリストも曲は残しているので、IDは重ならない
+	pl := spotify.SimplePlaylist{
+		ID:         spotify.ID(fmt.Sprintf("fake-playlist-%d", len(f.playlistTracks)+1)),
+		Name:       name,
+		Owner:      f.user.User,
+		SnapshotID: f.nextSnapshot(),
+	}
+	pl.URI = spotify.URI("spotify:playlist:" + string(pl.ID))
+	f.playlists = append([]spotify.SimplePlaylist{pl}, f.playlists...)
+	f.playlistTracks[pl.ID] = nil
+	return &spotify.FullPlaylist{SimplePlaylist: pl}, nil
+}
+
+func (f *FakePlayer) RenamePlaylist(ctx context.Context, playlistID spotify.ID, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, err := f.editPlaylist(playlistID, func(tracks []spotify.FullTrack) ([]spotify.FullTrack, error) {
+		return tracks, nil
+	})
+	if err != nil {
+		return err
+	}
+	f.playlists[f.playlistIndex(playlistID)].Name = name
+	return nil
+}
+
+func (f *FakePlayer) UnfollowPlaylist(ctx context.Context, playlistID spotify.ID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := f.playlistIndex(playlistID)
+	if i < 0 {
+		return errNotFound
+	}
+	f.unfollowed[playlistID] = f.playlists[i]
+	f.playlists = append(f.playlists[:i:i], f.playlists[i+1:]...)
+	return nil
+}
+
+// FollowPlaylist はフォローをやめたプレイリストを一覧の先頭に戻す
+func (f *FakePlayer) FollowPlaylist(ctx context.Context, playlistID spotify.ID, public bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.playlistIndex(playlistID) >= 0 {
+		return nil
+	}
+	pl, ok := f.unfollowed[playlistID]
+	if !ok {
+		return errNotFound
+	}
+	delete(f.unfollowed, playlistID)
+	f.playlists = append([]spotify.SimplePlaylist{pl}, f.playlists...)
+	return nil
+}
+
+func (f *FakePlayer) AddPlaylistItems(ctx context.Context, playlistID spotify.ID, uris []spotify.URI, position int) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	added := make([]spotify.FullTrack, 0, len(uris))
+	for _, uri := range uris {
+		t, ok := f.trackByURI(uri)
+		if !ok {
+			return "", spotify.Error{Message: "Invalid track uri: " + string(uri), Status: http.StatusBadRequest}
+		}
+		added = append(added, t)
+	}
+	return f.editPlaylist(playlistID, func(tracks []spotify.FullTrack) ([]spotify.FullTrack, error) {
+		if position < 0 {
+			position = len(tracks)
+		}
+		if position > len(tracks) {
+			return nil, spotify.Error{Message: "Index out of bounds", Status: http.StatusBadRequest}
+		}
+		return slices.Insert(slices.Clone(tracks), position, added...), nil
+	})
+}
+
+func (f *FakePlayer) RemovePlaylistItem(ctx context.Context, playlistID spotify.ID, uri spotify.URI, position int, snapshotID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.editPlaylist(playlistID, func(tracks []spotify.FullTrack) ([]spotify.FullTrack, error) {
+		if position < 0 || position >= len(tracks) || tracks[position].URI != uri {
+			return nil, spotify.Error{Message: "Could not remove tracks, please check parameters.", Status: http.StatusBadRequest}
+		}
+		f.removed = append(f.removed, tracks[position])
+		return slices.Delete(slices.Clone(tracks), position, position+1), nil
+	})
+}
+
+func (f *FakePlayer) MovePlaylistItem(ctx context.Context, playlistID spotify.ID, from, to int, snapshotID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.editPlaylist(playlistID, func(tracks []spotify.FullTrack) ([]spotify.FullTrack, error) {
+		if from < 0 || from >= len(tracks) || to < 0 || to >= len(tracks) {
+			return nil, spotify.Error{Message: "Index out of bounds", Status: http.StatusBadRequest}
+		}
+		t := tracks[from]
+		moved := slices.Delete(slices.Clone(tracks), from, from+1)
+		return slices.Insert(moved, to, t), nil
+	})
+}
+
+// pageBounds は offset と limit を total の範囲内に収める
+func pageBounds(total, offset, limit int) (int, int) {
+	if offset > total {
+		offset = total
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return offset, end
+}
+
+func toPlaylistTracks(tracks []spotify.FullTrack) []spotify.PlaylistTrack {
+	result := make([]spotify.PlaylistTrack, len(tracks))
+	for i, t := range tracks {
+		result[i] = spotify.PlaylistTrack{Track: t}
+	}
+	return result
+}
+
+func (f *FakePlayer) SavedTracks(ctx context.Context) ([]spotify.SavedTrack, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := make([]spotify.SavedTrack, len(f.saved))
+	for i, t := range f.saved {
+		result[i] = spotify.SavedTrack{FullTrack: t}
+	}
+	return result, nil
+}
+
+func (f *FakePlayer) TracksSaved(ctx context.Context, ids []spotify.ID) ([]bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := make([]bool, len(ids))
+	for i, id := range ids {
+		result[i] = f.savedIndex(id) >= 0
+	}
+	return result, nil
+}
+
+// SaveTracks は実APIと同じく、新しく保存した曲を Liked Songs の先頭に加える
+func (f *FakePlayer) SaveTracks(ctx context.Context, ids ...spotify.ID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, id := range ids {
+		if f.savedIndex(id) >= 0 {
+			continue
+		}
+		t, ok := f.trackByURI(spotify.URI("spotify:track:" + string(id)))
+		if !ok {
+			return spotify.Error{Message: "Non existing id: '" + string(id) + "'", Status: http.StatusBadRequest}
+		}
+		f.saved = append([]spotify.FullTrack{t}, f.saved...)
+	}
+	return nil
+}
+
+func (f *FakePlayer) RemoveSavedTracks(ctx context.Context, ids ...spotify.ID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, id := range ids {
+		if i := f.savedIndex(id); i >= 0 {
+			f.saved = append(f.saved[:i:i], f.saved[i+1:]...)
+		}
+	}
+	return nil
+}
+
+func (f *FakePlayer) savedIndex(id spotify.ID) int {
+	for i, t := range f.saved {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *FakePlayer) PlayTrackInContext(ctx context.Context, contextURI spotify.URI, offset int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	tracks, contextType, ok := f.contextTracks(contextURI)
+	if !ok {
+		return errNotFound
+	}
+	if contextType == "artist" {
+		// 実APIと同じく、アーティストのコンテキストでは位置を指定できない
+		return spotify.Error{Message: "Can't have offset for context type: ARTIST", Status: http.StatusBadRequest}
+	}
+	return f.start(tracks, offset, contextURI, contextType)
+}
+
+func (f *FakePlayer) PlayContext(ctx context.Context, contextURI spotify.URI) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	tracks, contextType, ok := f.contextTracks(contextURI)
+	if !ok {
+		return errNotFound
+	}
+	return f.start(tracks, 0, contextURI, contextType)
+}
+
+func (f *FakePlayer) PlayTrackURIInContext(ctx context.Context, contextURI, trackURI spotify.URI) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	tracks, contextType, ok := f.contextTracks(contextURI)
+	if !ok {
+		return errNotFound
+	}
+	if contextType == "artist" {
+		return spotify.Error{Message: "Can't have offset for context type: ARTIST", Status: http.StatusBadRequest}
+	}
+	offset := slices.IndexFunc(tracks, func(t spotify.FullTrack) bool { return t.URI == trackURI })
+	if offset < 0 {
+		return spotify.Error{Message: "Invalid offset: " + string(trackURI), Status: http.StatusBadRequest}
+	}
+	return f.start(tracks, offset, contextURI, contextType)
+}
+
+// contextTracks はコンテキストURI（Liked Songs、プレイリスト、アルバム、アーティスト、番組）の曲を返す
+func (f *FakePlayer) contextTracks(contextURI spotify.URI) ([]spotify.FullTrack, string, bool) {
+	uri := string(contextURI)
+	switch {
+	case strings.HasSuffix(uri, ":collection"):
+		return f.saved, "collection", true
+	case strings.HasPrefix(uri, "spotify:album:"):
+		tracks := f.albumTracks(spotify.ID(strings.TrimPrefix(uri, "spotify:album:")))
+		return tracks, "album", len(tracks) > 0
+	case strings.HasPrefix(uri, "spotify:artist:"):
+		tracks := f.artistTracks(spotify.ID(strings.TrimPrefix(uri, "spotify:artist:")))
+		return tracks, "artist", len(tracks) > 0
+	case strings.HasPrefix(uri, "spotify:show:"):
+		tracks := f.showTracks(spotify.ID(strings.TrimPrefix(uri, "spotify:show:")))
+		return tracks, "show", len(tracks) > 0
+	}
+	for _, pl := range f.playlists {
+		if pl.URI == contextURI {
+			return f.playlistTracks[pl.ID], "playlist", true
+		}
+	}
+	return nil, "", false
+}
+
+func (f *FakePlayer) albumTracks(albumID spotify.ID) []spotify.FullTrack {
+	var tracks []spotify.FullTrack
+	for _, t := range f.catalog() {
+		if t.Album.ID == albumID {
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+func (f *FakePlayer) artistTracks(artistID spotify.ID) []spotify.FullTrack {
+	var tracks []spotify.FullTrack
+	for _, t := range f.catalog() {
+		if len(t.Artists) > 0 && t.Artists[0].ID == artistID {
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+func (f *FakePlayer) PlayTrackFromURIList(ctx context.Context, uris []spotify.URI, offset int) error {
+	if len(uris) == 0 {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	tracks := make([]spotify.FullTrack, 0, len(uris))
+	for _, uri := range uris {
+		t, ok := f.trackByURI(uri)
+		if !ok {
+			return spotify.Error{Message: "Invalid track uri: " + string(uri), Status: http.StatusBadRequest}
+		}
+		tracks = append(tracks, t)
+	}
+	return f.start(tracks, offset, "", "")
+}
+
+func (f *FakePlayer) PlayLikedSongs(ctx context.Context, userID string, offset int) error {
+	return f.PlayTrackInContext(ctx, spotify.URI("spotify:user:"+userID+":collection"), offset)
+}
+
+func (f *FakePlayer) PlayTrackAlone(ctx context.Context, trackURI spotify.URI) error {
+	return f.PlayTrackFromURIList(ctx, []spotify.URI{trackURI}, 0)
+}
+
+func (f *FakePlayer) ToggleShuffle(ctx context.Context, shuffle bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	if err := f.checkDevice(); err != nil {
+		return err
+	}
+	if shuffle == f.shuffle {
+		return nil
+	}
+	f.shuffle = shuffle
+	if len(f.order) == 0 {
+		return nil
+	}
+	current := f.order[f.pos]
+	if shuffle {
+		f.shuffleFrom(current)
+	} else {
+		for i := range f.order {
+			f.order[i] = i
+		}
+		f.pos = current
+	}
+	return nil
+}
+
+func (f *FakePlayer) SetRepeat(ctx context.Context, state string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	if err := f.checkDevice(); err != nil {
+		return err
+	}
+	switch state {
+	case "off", "context", "track":
+		f.repeat = state
+		return nil
+	}
+	return spotify.Error{Message: "Invalid repeat state: " + state, Status: http.StatusBadRequest}
+}
+
+// fakeSearchLimit は種類ごとの検索結果の1ページの件数（APIのデフォルトと同じ）
+const fakeSearchLimit = 20
+
+func (f *FakePlayer) Search(ctx context.Context, query string) (*SearchResults, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	all := f.searchAll(query)
+	results := &SearchResults{Paging: make(map[spotify.SearchType]SearchPaging)}
+	for _, t := range []spotify.SearchType{spotify.SearchTypeTrack, spotify.SearchTypeArtist, spotify.SearchTypeAlbum,
+		spotify.SearchTypePlaylist, spotify.SearchTypeShow, spotify.SearchTypeEpisode} {
+		searchPage(results, all, t, 0)
+	}
+	return results, nil
+}
+
+func (f *FakePlayer) SearchMore(ctx context.Context, query string, searchType spotify.SearchType, offset int) (*SearchResults, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	results := &SearchResults{Paging: make(map[spotify.SearchType]SearchPaging)}
+	searchPage(results, f.searchAll(query), searchType, offset)
+	return results, nil
+}
+
+// searchAll はトラック、アーティスト、アルバム、プレイリスト、番組、エピソードから一致するものを全て返す
+// 番組は名前と配信者、エピソードは名前と番組名で探す
+func (f *FakePlayer) searchAll(query string) *SearchResults {
+	q := strings.ToLower(query)
+	match := func(s string) bool { return strings.Contains(strings.ToLower(s), q) }
+
+	results := &SearchResults{}
+	seenArtists := make(map[spotify.ID]bool)
+	seenAlbums := make(map[spotify.ID]bool)
+	for _, t := range f.catalog() {
+		artist := t.Artists[0]
+		if match(t.Name) || match(artist.Name) || match(t.Album.Name) {
+			results.Tracks = append(results.Tracks, t)
+		}
+		if match(artist.Name) && !seenArtists[artist.ID] {
+			seenArtists[artist.ID] = true
+			results.Artists = append(results.Artists, spotify.FullArtist{SimpleArtist: artist})
+		}
+		if match(t.Album.Name) && !seenAlbums[t.Album.ID] {
+			seenAlbums[t.Album.ID] = true
+			album := t.Album
+			album.Artists = t.Artists
+			results.Albums = append(results.Albums, album)
+		}
+	}
+	for _, pl := range f.playlists {
+		if match(pl.Name) {
+			results.Playlists = append(results.Playlists, pl)
+		}
+	}
+	for _, show := range f.shows {
+		if match(show.Name) || match(show.Publisher) {
+			results.Shows = append(results.Shows, spotify.FullShow{SimpleShow: show})
+		}
+		for _, ep := range f.showEpisodes[show.ID] {
+			if match(ep.Name) || match(show.Name) {
+				results.Episodes = append(results.Episodes, ep)
+			}
+		}
+	}
+	return results
+}
+
+// searchPage は all の searchType の結果のうち、offset から1ページ分を results に入れる
+func searchPage(results, all *SearchResults, searchType spotify.SearchType, offset int) {
+	page := func(total int) (int, int) {
+		start, end := pageBounds(total, offset, fakeSearchLimit)
+		results.Paging[searchType] = newSearchPaging(total, start, end-start)
+		return start, end
+	}
+	switch searchType {
+	case spotify.SearchTypeTrack:
+		start, end := page(len(all.Tracks))
+		results.Tracks = all.Tracks[start:end]
+	case spotify.SearchTypeArtist:
+		start, end := page(len(all.Artists))
+		results.Artists = all.Artists[start:end]
+	case spotify.SearchTypeAlbum:
+		start, end := page(len(all.Albums))
+		results.Albums = all.Albums[start:end]
+	case spotify.SearchTypePlaylist:
+		start, end := page(len(all.Playlists))
+		results.Playlists = all.Playlists[start:end]
+	case spotify.SearchTypeShow:
+		start, end := page(len(all.Shows))
+		results.Shows = all.Shows[start:end]
+	case spotify.SearchTypeEpisode:
+		start, end := page(len(all.Episodes))
+		results.Episodes = all.Episodes[start:end]
+	}
+}
+
+// Album はカタログのアルバムの曲からアルバムを作る（曲はカタログの順に1枚目のディスクに並べる）
+func (f *FakePlayer) Album(ctx context.Context, albumID spotify.ID) (*Album, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tracks := f.albumTracks(albumID)
+	if len(tracks) == 0 {
+		return nil, errNotFound
+	}
+	return fakeAlbum(tracks), nil
+}
+
+func fakeAlbum(tracks []spotify.FullTrack) *Album {
+	album := &Album{FullAlbum: spotify.FullAlbum{SimpleAlbum: tracks[0].Album}}
+	album.Artists = tracks[0].Artists
+	for i, t := range tracks {
+		st := t.SimpleTrack
+		st.DiscNumber = 1
+		st.TrackNumber = spotify.Numeric(i + 1)
+		album.Tracks.Tracks = append(album.Tracks.Tracks, st)
+	}
+	album.Tracks.Total = spotify.Numeric(len(tracks))
+	return album
+}
+
+// SavedAlbumsPage は SetSavedAlbums で保存したアルバムを offset から1ページ分返す
+func (f *FakePlayer) SavedAlbumsPage(ctx context.Context, offset int) ([]spotify.SavedAlbum, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var albums []spotify.SavedAlbum
+	for _, id := range f.savedAlbums {
+		if tracks := f.albumTracks(id); len(tracks) > 0 {
+			albums = append(albums, spotify.SavedAlbum{FullAlbum: fakeAlbum(tracks).FullAlbum})
+		}
+	}
+	start, end := pageBounds(len(albums), offset, savedAlbumsPageSize)
+	return albums[start:end], len(albums), nil
+}
+
+func (f *FakePlayer) AlbumsSaved(ctx context.Context, ids []spotify.ID) ([]bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	result := make([]bool, len(ids))
+	for i, id := range ids {
+		result[i] = slices.Contains(f.savedAlbums, id)
+	}
+	return result, nil
+}
+
+// SaveAlbums は実APIと同じく、新しく保存したアルバムを保存したアルバムの先頭に加える
+func (f *FakePlayer) SaveAlbums(ctx context.Context, ids ...spotify.ID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, id := range ids {
+		if slices.Contains(f.savedAlbums, id) {
+			continue
+		}
+		if len(f.albumTracks(id)) == 0 {
+			return spotify.Error{Message: "Non existing id: '" + string(id) + "'", Status: http.StatusBadRequest}
+		}
+		f.savedAlbums = append([]spotify.ID{id}, f.savedAlbums...)
+	}
+	return nil
+}
+
+func (f *FakePlayer) RemoveSavedAlbums(ctx context.Context, ids ...spotify.ID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.savedAlbums = slices.DeleteFunc(f.savedAlbums, func(id spotify.ID) bool { return slices.Contains(ids, id) })
+	return nil
+}
+
+func (f *FakePlayer) ArtistTopTracks(ctx context.Context, artistID spotify.ID, country string) ([]spotify.FullTrack, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tracks := f.artistTracks(artistID)
+	if len(tracks) == 0 {
+		return nil, errNotFound
+	}
+	return tracks[:min(len(tracks), 10)], nil
+}
+
+func (f *FakePlayer) Artist(ctx context.Context, artistID spotify.ID) (*spotify.FullArtist, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tracks := f.artistTracks(artistID)
+	if len(tracks) == 0 {
+		return nil, errNotFound
+	}
+	return &spotify.FullArtist{SimpleArtist: tracks[0].Artists[0]}, nil
+}
+
+// ArtistAlbumsPage はカタログのアーティストの曲のアルバムを返す
+// デモのアルバムは全てアルバム扱いで、シングルとコンピレーションはない
+func (f *FakePlayer) ArtistAlbumsPage(ctx context.Context, artistID spotify.ID, albumType spotify.AlbumType, offset int) ([]spotify.SimpleAlbum, int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if albumType != spotify.AlbumTypeAlbum {
+		return nil, 0, nil
+	}
+	var albums []spotify.SimpleAlbum
+	seen := make(map[spotify.ID]bool)
+	for _, t := range f.artistTracks(artistID) {
+		if !seen[t.Album.ID] {
+			seen[t.Album.ID] = true
+			album := t.Album
+			album.Artists = t.Artists
+			albums = append(albums, album)
+		}
+	}
+	start, end := pageBounds(len(albums), offset, fakeSearchLimit)
+	return albums[start:end], len(albums), nil
+}
+
+// RelatedArtists はカタログのほかのアーティストを返す
+func (f *FakePlayer) RelatedArtists(ctx context.Context, artistID spotify.ID) ([]spotify.FullArtist, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var artists []spotify.FullArtist
+	seen := map[spotify.ID]bool{artistID: true}
+	for _, t := range f.catalog() {
+		if a := t.Artists[0]; !seen[a.ID] {
+			seen[a.ID] = true
+			artists = append(artists, spotify.FullArtist{SimpleArtist: a})
+		}
+	}
+	return artists, nil
+}
+
+func (f *FakePlayer) CurrentUser(ctx context.Context) (*spotify.PrivateUser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	u := f.user
+	return &u, nil
+}
+
+func (f *FakePlayer) GetQueue(ctx context.Context) (*spotify.Queue, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	q := &spotify.Queue{}
+	if f.current == nil {
+		return q, nil
+	}
+	q.CurrentlyPlaying = *f.current
+	q.Items = append(q.Items, f.queue...)
+	// 実APIと同様に、キューの後ろにコンテキストの続きを最大20件まで並べる
+	for i := 1; len(q.Items) < 20 && i < len(f.order); i++ {
+		next := f.pos + i
+		if next >= len(f.order) {
+			if f.repeat != "context" {
+				break
+			}
+			next -= len(f.order)
+		}
+		q.Items = append(q.Items, f.list[f.order[next]])
+	}
+	return q, nil
+}
+
+// AddToQueue は実APIと同じく、ユーザーが追加したキューの最後（コンテキストの続きより前）に加える
+func (f *FakePlayer) AddToQueue(ctx context.Context, uri spotify.URI) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	if err := f.checkDevice(); err != nil {
+		return err
+	}
+	if f.current == nil {
+		return errRestricted
+	}
+	t, ok := f.trackByURI(uri)
+	if !ok {
+		return spotify.Error{Message: "Invalid track uri: " + string(uri), Status: http.StatusBadRequest}
+	}
+	f.queue = append(f.queue, t)
+	return nil
+}
+
+func (f *FakePlayer) PlayerDevices(ctx context.Context) ([]Device, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Device(nil), f.devices...), nil
+}
+
+func (f *FakePlayer) SetVolume(ctx context.Context, volume int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.checkDevice(); err != nil {
+		return err
+	}
+	if !f.activeDevice().SupportsVolume {
+		return spotify.Error{Message: "Player command failed: Cannot control device volume", Status: http.StatusForbidden}
+	}
+	if volume < 0 || volume > 100 {
+		return spotify.Error{Message: fmt.Sprintf("Invalid volume: %d", volume), Status: http.StatusBadRequest}
+	}
+	f.activeDevice().Volume = spotify.Numeric(volume)
+	return nil
+}
+
+func (f *FakePlayer) TransferPlayback(ctx context.Context, deviceID spotify.ID, play bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sync()
+	var target *Device
+	for i := range f.devices {
+		if f.devices[i].ID == deviceID {
+			target = &f.devices[i]
+		}
+	}
+	if target == nil {
+		return spotify.Error{Message: "Device not found", Status: http.StatusNotFound}
+	}
+	if target.Restricted {
+		return errRestricted
+	}
+	for i := range f.devices {
+		f.devices[i].Active = f.devices[i].ID == deviceID
+	}
+	if play && f.current != nil {
+		f.playing = true
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package spotify
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestFakeSearchShowsAndEpisodes(t *testing.T) {
+	f := NewDemoPlayer()
+	ctx := context.Background()
+
+	tests := []struct {
+		query    string
+		shows    []spotify.ID
+		episodes []spotify.ID
+	}{
+		// 番組は名前と配信者、エピソードは名前と番組名で見つかる
+		{"liner", []spotify.ID{"demo-liner-notes"}, []spotify.ID{"demo-ep-001", "demo-ep-002", "demo-ep-003"}},
+		{"demo radio", []spotify.ID{"demo-signal-hour"}, nil},
+		{"static", nil, []spotify.ID{"demo-ep-102"}},
+		{"no such show", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r, err := f.Search(ctx, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var shows, episodes []spotify.ID
+			for _, s := range r.Shows {
+				shows = append(shows, s.ID)
+			}
+			for _, ep := range r.Episodes {
+				episodes = append(episodes, ep.ID)
+				if ep.Show.ID == "" || ep.URI != spotify.URI("spotify:episode:"+string(ep.ID)) {
+					t.Errorf("episode %s has show %q and URI %q", ep.ID, ep.Show.ID, ep.URI)
+				}
+			}
+			if !slices.Equal(shows, tt.shows) || !slices.Equal(episodes, tt.episodes) {
+				t.Errorf("shows = %v, episodes = %v; want %v, %v", shows, episodes, tt.shows, tt.episodes)
+			}
+			for _, st := range []spotify.SearchType{spotify.SearchTypeShow, spotify.SearchTypeEpisode} {
+				if _, ok := r.Paging[st]; !ok {
+					t.Errorf("no paging for search type %v", st)
+				}
+			}
+		})
+	}
+
+	more, err := f.SearchMore(ctx, "liner", spotify.SearchTypeEpisode, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(more.Episodes) != 1 || more.Episodes[0].ID != "demo-ep-003" || more.Paging[spotify.SearchTypeEpisode].Total != 3 {
+		t.Errorf("second page = %+v, paging %+v", more.Episodes, more.Paging[spotify.SearchTypeEpisode])
+	}
+}
+
+func TestFakePlayEpisode(t *testing.T) {
+	now := time.Unix(0, 0)
+	f := NewDemoPlayer()
+	f.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// 検索結果のエピソードだけを再生する
+	if err := f.PlayTrackAlone(ctx, "spotify:episode:demo-ep-002"); err != nil {
+		t.Fatal(err)
+	}
+	state, err := f.PlayerState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Item == nil || state.Item.URI != "spotify:episode:demo-ep-002" || state.Item.Artists[0].Name != "Liner Notes" {
+		t.Fatalf("playing %+v, want the episode with its show", state.Item)
+	}
+
+	// 番組をコンテキストとして再生すると、エピソードを順に再生する
+	if err := f.PlayContext(ctx, "spotify:show:demo-signal-hour"); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(59 * time.Minute)
+	if state, err = f.PlayerState(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if state.Item == nil || state.Item.URI != "spotify:episode:demo-ep-102" || state.PlaybackContext.Type != "show" {
+		t.Errorf("playing %v in %q, want the second episode of the show", state.Item, state.PlaybackContext.Type)
+	}
+
+	if err := f.AddToQueue(ctx, "spotify:episode:demo-ep-001"); err != nil {
+		t.Errorf("adding an episode to the queue: %v", err)
+	}
+}
+
+func TestFakeSaveAlbums(t *testing.T) {
+	f := NewFakePlayer(nil)
+	f.AddPlaylist(spotify.SimplePlaylist{ID: "playlist"}, []spotify.FullTrack{
+		FakeTrack("a1", "A1", "Artist", "First", time.Minute),
+		FakeTrack("b1", "B1", "Artist", "Second", time.Minute),
+	})
+	ctx := context.Background()
+	saved := func() []bool {
+		t.Helper()
+		result, err := f.AlbumsSaved(ctx, []spotify.ID{"first", "second"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if err := f.SaveAlbums(ctx, "first", "second", "first"); err != nil {
+		t.Fatal(err)
+	}
+	if got := saved(); !slices.Equal(got, []bool{true, true}) {
+		t.Errorf("saved = %v after saving both", got)
+	}
+	// 新しく保存したアルバムが先頭になる
+	albums, total, err := f.SavedAlbumsPage(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || albums[0].ID != "second" {
+		t.Errorf("saved albums = %d, first %q; want second first", total, albums[0].ID)
+	}
+
+	if err := f.RemoveSavedAlbums(ctx, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if got := saved(); !slices.Equal(got, []bool{false, true}) {
+		t.Errorf("saved = %v after removing first", got)
+	}
+	if err := f.SaveAlbums(ctx, "missing"); err == nil {
+		t.Error("saved an album that isn't in the catalog")
+	}
+}
@@ -0,0 +1,326 @@
+package spotify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"spotify-tui/internal/config"
+	"spotify-tui/internal/logger"
+	"sync"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// managedEndTolerance は曲が最後まで再生されたとみなす、曲の終わりまでの残り時間
+// ポーリングの間隔やレスポンスの遅れで、前回の状態から見積もった位置が曲の長さに届かないことがある
+const managedEndTolerance = time.Second
+
+// managedStartGrace は管理キューの曲の再生を始めてから、別の曲の再生状態を無視する時間
+// 再生を始める前に取得した状態が後から届くことがあるため
+const managedStartGrace = 3 * time.Second
+
+// ErrQueueIndex は管理キューにない位置を指定した場合のエラー
+var ErrQueueIndex = errors.New("no such item in the queue")
+
+// QueueEntry は管理キューの1曲
+// 再起動後も表示や移動ができるよう、URI と一緒に曲名やアーティストも保存する
+type QueueEntry struct {
+	URI      spotify.URI `json:"uri"`
+	Name     string      `json:"name"`
+	Artist   string      `json:"artist,omitempty"`
+	ArtistID spotify.ID  `json:"artist_id,omitempty"`
+	AlbumID  spotify.ID  `json:"album_id,omitempty"`
+}
+
+// ManagedQueue はアプリが管理する再生キュー
+// Spotify のキューは曲を削除したり並べ替えたりできないので、曲の順番をこちらで持ち、
+// 再生中の曲が最後まで再生されたら次の曲を自分で再生する
+// 曲の終わりは Observe に渡された再生状態の変化から判断する
+type ManagedQueue struct {
+	mu      sync.Mutex
+	player  Player
+	path    string // 空の場合は保存しない
+	now     func() time.Time
+	entries []QueueEntry
+
+	last      *playbackSample // 前回 Observe に渡された再生状態
+	active    spotify.URI     // 管理キューから再生を始めて、再生中の曲
+	startedAt time.Time       // active の再生を始めた時刻
+	resume    *resumePoint    // 管理キューの曲を再生し終えたら戻るコンテキスト
+}
+
+// playbackSample は再生状態を受け取った時点の、曲と再生位置
+type playbackSample struct {
+	uri      spotify.URI
+	progress time.Duration
+	duration time.Duration
+	playing  bool
+	at       time.Time
+}
+
+// resumePoint は管理キューの曲を再生する前に、Spotify が次に再生しようとしたコンテキストと曲
+type resumePoint struct {
+	context spotify.URI
+	track   spotify.URI
+}
+
+// NewManagedQueue は空の管理キューを作成する
+// path に Load と Save で読み書きし、path が空の場合は保存しない
+// now が nil の場合は time.Now を使う（テストでは FakePlayer と同じ時計を渡す）
+func NewManagedQueue(player Player, path string, now func() time.Time) *ManagedQueue {
+	if now == nil {
+		now = time.Now
+	}
+	return &ManagedQueue{player: player, path: path, now: now}
+}
+
+// Load は保存したキューを読み込む（ファイルがなければ空のまま）
+func (q *ManagedQueue) Load() error {
+	if q.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(q.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entries []QueueEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.entries = entries
+	return nil
+}
+
+// Save はキューをファイルに書き込む
+// 途中で終了してもファイルが壊れないよう、一時ファイルに書き込んでからリネームする
+func (q *ManagedQueue) Save() error {
+	if q.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(q.Entries(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(q.path), 0700); err != nil {
+		return err
+	}
+	return config.WriteFileAtomic(q.path, data, 0600)
+}
+
+// Entries はキューの曲を再生する順に返す
+func (q *ManagedQueue) Entries() []QueueEntry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return slices.Clone(q.entries)
+}
+
+// Add は曲をキューの最後に加える
+func (q *ManagedQueue) Add(e QueueEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.entries = append(q.entries, e)
+}
+
+// InsertNext は曲をキューの先頭に加え、再生中の曲の次に再生する
+func (q *ManagedQueue) InsertNext(e QueueEntry) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.entries = slices.Insert(q.entries, 0, e)
+}
+
+// Remove は i 番目の曲をキューから除く
+func (q *ManagedQueue) Remove(i int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if i < 0 || i >= len(q.entries) {
+		return ErrQueueIndex
+	}
+	q.entries = slices.Delete(q.entries, i, i+1)
+	return nil
+}
+
+// Move は from 番目の曲を to 番目に移す
+func (q *ManagedQueue) Move(from, to int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if from < 0 || from >= len(q.entries) || to < 0 || to >= len(q.entries) {
+		return ErrQueueIndex
+	}
+	e := q.entries[from]
+	q.entries = slices.Insert(slices.Delete(q.entries, from, from+1), to, e)
+	return nil
+}
+
+// Clear はキューを空にする
+func (q *ManagedQueue) Clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.entries = nil
+}
+
+// Play は i 番目の曲をすぐに再生し、キューから除く
+func (q *ManagedQueue) Play(ctx context.Context, i int) (QueueEntry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if i < 0 || i >= len(q.entries) {
+		return QueueEntry{}, ErrQueueIndex
+	}
+	// 選んで再生した場合は、それまで再生していたコンテキストには戻らない
+	q.resume = nil
+	return q.start(ctx, i)
+}
+
+// Next は次の曲へ進む
+// キューに曲があればその先頭の曲を再生してそれを返し、キューの曲を再生し終えていればその前に再生していたコンテキストに戻る
+func (q *ManagedQueue) Next(ctx context.Context) (*QueueEntry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.entries) == 0 {
+		if q.active != "" && q.resume != nil {
+			return nil, q.resumeContext(ctx)
+		}
+		q.active = ""
+		return nil, q.player.Next(ctx)
+	}
+	if q.active == "" {
+		// Spotify で次の曲に進めて、キューの曲を再生し終えた後に戻る位置を調べる
+		if err := q.player.Next(ctx); err != nil {
+			return nil, err
+		}
+		state, err := q.player.PlayerState(ctx)
+		if err != nil {
+			return nil, err
+		}
+		q.setResume(state)
+	}
+	e, err := q.start(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// Observe はポーリングで取得した再生状態を受け取る
+// 前回の状態から見て再生中の曲が最後まで再生されていれば、キューの先頭の曲を再生してそれを返す
+// キューの曲を再生し終えてキューが空になった場合は、その前に再生していたコンテキストに戻る
+func (q *ManagedQueue) Observe(ctx context.Context, state *spotify.PlayerState) (*QueueEntry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+	playing := spotify.URI("")
+	if state != nil && state.Item != nil {
+		playing = state.Item.URI
+	}
+	if q.active != "" && playing != q.active && now.Sub(q.startedAt) < managedStartGrace {
+		// 再生を始める前に取得した状態かもしれないので無視する
+		return nil, nil
+	}
+
+	prev := q.last
+	q.last = newPlaybackSample(state, now)
+	if prev == nil || !prev.ended(state, now) {
+		// 別の曲を選んで再生した場合は、管理キューからの再生をやめる
+		if q.active != "" && playing != q.active {
+			q.active = ""
+			q.resume = nil
+		}
+		return nil, nil
+	}
+
+	if len(q.entries) == 0 {
+		if q.active == "" || q.resume == nil {
+			q.active = ""
+			return nil, nil
+		}
+		return nil, q.resumeContext(ctx)
+	}
+
+	// Spotify がコンテキストの次の曲に進んでいれば、キューの曲を再生し終えた後にそこから再開する
+	if q.active == "" {
+		q.setResume(state)
+	}
+	e, err := q.start(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// setResume はキューの曲を再生し終えた後に戻る位置を、Spotify が再生しようとしている曲にする
+// コンテキストのない曲（URI のリストで再生した曲など）と、位置を指定できないアーティストのコンテキストには戻れない
+// コンテキストを最後まで再生して止まっている場合は、再生し終えた曲に戻らないよう戻る位置を持たない
+func (q *ManagedQueue) setResume(state *spotify.PlayerState) {
+	q.resume = nil
+	if state != nil && state.Item != nil && state.Playing && state.PlaybackContext.URI != "" && state.PlaybackContext.Type != "artist" {
+		q.resume = &resumePoint{context: state.PlaybackContext.URI, track: state.Item.URI}
+	}
+}
+
+// resumeContext はキューの曲を再生する前に再生していたコンテキストに戻る
+func (q *ManagedQueue) resumeContext(ctx context.Context) error {
+	resume := q.resume
+	q.active = ""
+	q.resume = nil
+	logger.Info("Managed queue finished, resuming context", "context", resume.context, "track", resume.track)
+	if err := q.player.PlayTrackURIInContext(ctx, resume.context, resume.track); err != nil {
+		return fmt.Errorf("failed to resume playback: %w", err)
+	}
+	q.last = &playbackSample{uri: resume.track, playing: true, at: q.now()}
+	return nil
+}
+
+// start は i 番目の曲を再生し、キューから除く
+// 再生できなかった場合は、次の機会に再生できるようキューに残す
+func (q *ManagedQueue) start(ctx context.Context, i int) (QueueEntry, error) {
+	e := q.entries[i]
+	logger.Debug("Managed queue starting track", "uri", e.URI)
+	if err := q.player.PlayTrackAlone(ctx, e.URI); err != nil {
+		return QueueEntry{}, err
+	}
+	q.entries = slices.Delete(q.entries, i, i+1)
+	q.active = e.URI
+	q.startedAt = q.now()
+	q.last = &playbackSample{uri: e.URI, playing: true, at: q.startedAt}
+	return e, nil
+}
+
+func newPlaybackSample(state *spotify.PlayerState, at time.Time) *playbackSample {
+	s := &playbackSample{at: at}
+	if state == nil || state.Item == nil {
+		return s
+	}
+	s.uri = state.Item.URI
+	s.progress = time.Duration(state.Progress) * time.Millisecond
+	s.duration = state.Item.TimeDuration()
+	s.playing = state.Playing
+	return s
+}
+
+// ended は、この状態の後に state になったのが、曲が最後まで再生されたためかどうかを返す
+// 経過時間から見積もった位置が曲の終わりに届いていて、曲が変わったか、先頭に戻ったか、終わりで止まっていれば終わったとみなす
+// 曲のリピート中は同じ曲を繰り返すのが正しいので、終わったとみなさない
+func (s *playbackSample) ended(state *spotify.PlayerState, at time.Time) bool {
+	if !s.playing || s.duration <= 0 || s.progress+at.Sub(s.at) < s.duration-managedEndTolerance {
+		return false
+	}
+	if state == nil || state.Item == nil {
+		return true
+	}
+	if state.RepeatState == "track" {
+		return false
+	}
+	progress := time.Duration(state.Progress) * time.Millisecond
+	return state.Item.URI != s.uri || progress < s.progress ||
+		!state.Playing && progress >= s.duration-managedEndTolerance
+}
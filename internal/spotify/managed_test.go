@@ -0,0 +1,312 @@
+package spotify
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// testClock はテストで進める時計
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time          { return c.now }
+func (c *testClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// managedFixture は3分の曲のプレイリスト（track0〜track2）を再生中の FakePlayer と、
+// プレイリストにない曲（extra0、extra1）を入れた管理キュー
+type managedFixture struct {
+	clock  *testClock
+	player *FakePlayer
+	queue  *ManagedQueue
+	tracks []spotify.FullTrack
+	extras []spotify.FullTrack
+}
+
+const testTrackLength = 3 * time.Minute
+
+func newManagedFixture(t *testing.T, entries int) *managedFixture {
+	t.Helper()
+	clock := &testClock{now: time.Unix(1700000000, 0)}
+	f := &managedFixture{clock: clock, player: NewFakePlayer(clock.Now)}
+	for i := 0; i < 3; i++ {
+		f.tracks = append(f.tracks, FakeTrack(fmt.Sprintf("track%d", i), fmt.Sprintf("Track %d", i), "Artist", "Album", testTrackLength))
+	}
+	for i := 0; i < 2; i++ {
+		f.extras = append(f.extras, FakeTrack(fmt.Sprintf("extra%d", i), fmt.Sprintf("Extra %d", i), "Other", "Other Album", testTrackLength))
+	}
+	f.player.SetDevices([]Device{{PlayerDevice: spotify.PlayerDevice{ID: "device", Active: true}}})
+	f.player.SetSavedTracks(f.extras)
+	f.player.AddPlaylist(spotify.SimplePlaylist{ID: "playlist"}, f.tracks)
+	if err := f.player.PlayContext(context.Background(), "spotify:playlist:playlist"); err != nil {
+		t.Fatal(err)
+	}
+
+	f.queue = NewManagedQueue(f.player, "", clock.Now)
+	for _, e := range f.extras[:entries] {
+		f.queue.Add(QueueEntry{URI: e.URI, Name: e.Name})
+	}
+	return f
+}
+
+// observe は時計を d 進めて、その時点の再生状態を管理キューに渡す
+func (f *managedFixture) observe(t *testing.T, d time.Duration) *QueueEntry {
+	t.Helper()
+	f.clock.Advance(d)
+	entry, err := f.queue.Observe(context.Background(), f.state(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entry
+}
+
+func (f *managedFixture) state(t *testing.T) *spotify.PlayerState {
+	t.Helper()
+	state, err := f.player.PlayerState(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+// expectPlaying は再生中の曲とコンテキストを確かめる
+func (f *managedFixture) expectPlaying(t *testing.T, uri, contextURI spotify.URI) {
+	t.Helper()
+	state := f.state(t)
+	if state.Item == nil || state.Item.URI != uri || state.PlaybackContext.URI != contextURI {
+		var got spotify.URI
+		if state.Item != nil {
+			got = state.Item.URI
+		}
+		t.Errorf("playing %q in %q, want %q in %q", got, state.PlaybackContext.URI, uri, contextURI)
+	}
+}
+
+func TestPlaybackSampleEnded(t *testing.T) {
+	const uri, other = spotify.URI("spotify:track:a"), spotify.URI("spotify:track:b")
+	at := time.Unix(0, 0)
+	end := testTrackLength
+	state := func(uri spotify.URI, progress time.Duration, playing bool) *spotify.PlayerState {
+		s := &spotify.PlayerState{CurrentlyPlaying: spotify.CurrentlyPlaying{
+			Item:     &spotify.FullTrack{SimpleTrack: spotify.SimpleTrack{URI: uri, Duration: spotify.Numeric(end.Milliseconds())}},
+			Progress: spotify.Numeric(progress.Milliseconds()),
+			Playing:  playing,
+		}}
+		s.RepeatState = "off"
+		return s
+	}
+	repeatTrack := state(uri, time.Second, true)
+	repeatTrack.RepeatState = "track"
+
+	tests := []struct {
+		name     string
+		progress time.Duration // 前回の位置
+		playing  bool          // 前回再生中だったか
+		duration time.Duration
+		elapsed  time.Duration
+		state    *spotify.PlayerState
+		want     bool
+	}{
+		{"next track at the end", end - 2*time.Second, true, end, 2 * time.Second, state(other, time.Second, true), true},
+		{"next track within the tolerance", end - 3*time.Second, true, end, 3*time.Second - managedEndTolerance, state(other, 0, true), true},
+		{"next track just before the tolerance", end - 3*time.Second, true, end, 3*time.Second - managedEndTolerance - time.Millisecond, state(other, 0, true), false},
+		{"skipped mid-track", time.Minute, true, end, time.Second, state(other, 0, true), false},
+		{"same track restarted", end - time.Second, true, end, 2 * time.Second, state(uri, time.Second, true), true},
+		{"still playing past the estimate", end - time.Second, true, end, 2 * time.Second, state(uri, end-500*time.Millisecond, true), false},
+		{"stopped at the end", end - time.Second, true, end, 5 * time.Second, state(uri, end, false), true},
+		{"paused within the tolerance", end - 2*time.Second, true, end, 5 * time.Second, state(uri, end-managedEndTolerance, false), true},
+		{"paused before the end", end - 2*time.Second, true, end, 5 * time.Second, state(uri, end-2*time.Second, false), false},
+		{"was paused", end - time.Second, false, end, time.Minute, state(other, 0, true), false},
+		{"unknown duration", time.Minute, true, 0, time.Hour, state(other, 0, true), false},
+		{"nothing playing afterwards", end - time.Second, true, end, 2 * time.Second, nil, true},
+		{"no item afterwards", end - time.Second, true, end, 2 * time.Second, &spotify.PlayerState{}, true},
+		{"repeating the track", end - time.Second, true, end, 2 * time.Second, repeatTrack, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &playbackSample{uri: uri, progress: tt.progress, duration: tt.duration, playing: tt.playing, at: at}
+			if got := s.ended(tt.state, at.Add(tt.elapsed)); got != tt.want {
+				t.Errorf("ended = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManagedQueueTakesOverAtTheEnd(t *testing.T) {
+	f := newManagedFixture(t, 2)
+	const playlist = spotify.URI("spotify:playlist:playlist")
+
+	// 曲の途中では何もしない
+	if e := f.observe(t, 0); e != nil {
+		t.Fatalf("started %s at the beginning", e.URI)
+	}
+	if e := f.observe(t, testTrackLength-2*time.Second); e != nil {
+		t.Fatalf("started %s before the end", e.URI)
+	}
+
+	// 曲が終わったら、Spotify が進んだ曲の代わりに管理キューの先頭を再生する
+	e := f.observe(t, 3*time.Second)
+	if e == nil || e.URI != f.extras[0].URI {
+		t.Fatalf("started %v at the end, want %s", e, f.extras[0].URI)
+	}
+	f.expectPlaying(t, f.extras[0].URI, "")
+
+	// 管理キューの曲を順に再生する
+	f.observe(t, testTrackLength/2)
+	e = f.observe(t, testTrackLength/2+time.Second)
+	if e == nil || e.URI != f.extras[1].URI {
+		t.Fatalf("started %v after the first entry, want %s", e, f.extras[1].URI)
+	}
+	if n := len(f.queue.Entries()); n != 0 {
+		t.Errorf("%d entries left", n)
+	}
+
+	// 管理キューの曲を再生し終えたら、Spotify が進もうとしたコンテキストの曲に戻る
+	f.observe(t, testTrackLength/2)
+	if e := f.observe(t, testTrackLength/2+time.Second); e != nil {
+		t.Errorf("started %s from an empty queue", e.URI)
+	}
+	f.expectPlaying(t, f.tracks[1].URI, playlist)
+
+	// 戻った後はコンテキストのとおりに再生する
+	f.observe(t, testTrackLength/2)
+	if e := f.observe(t, testTrackLength/2+time.Second); e != nil {
+		t.Errorf("started %s after resuming", e.URI)
+	}
+	f.expectPlaying(t, f.tracks[2].URI, playlist)
+}
+
+func TestManagedQueueEndOfContext(t *testing.T) {
+	f := newManagedFixture(t, 1)
+	if err := f.player.PlayTrackInContext(context.Background(), "spotify:playlist:playlist", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// コンテキストの最後の曲が終わって Spotify が止まっても、管理キューの曲を再生する
+	f.observe(t, 0)
+	f.observe(t, testTrackLength-2*time.Second)
+	e := f.observe(t, 3*time.Second)
+	if e == nil || e.URI != f.extras[0].URI {
+		t.Fatalf("started %v after the context ended, want %s", e, f.extras[0].URI)
+	}
+
+	// 戻るコンテキストがないので、再生し終えたら止まる
+	f.observe(t, testTrackLength/2)
+	f.observe(t, testTrackLength/2+time.Second)
+	if state := f.state(t); state.Playing {
+		t.Errorf("playing %s after the last entry", state.Item.URI)
+	}
+}
+
+func TestManagedQueuePause(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("before the end", func(t *testing.T) {
+		f := newManagedFixture(t, 1)
+		f.observe(t, 0)
+		f.observe(t, testTrackLength-3*time.Second)
+		if err := f.player.Pause(ctx); err != nil {
+			t.Fatal(err)
+		}
+		// 一時停止している間に、見積もった位置が曲の終わりを過ぎても再生しない
+		if e := f.observe(t, time.Minute); e != nil {
+			t.Errorf("started %s while paused", e.URI)
+		}
+		if e := f.observe(t, time.Minute); e != nil {
+			t.Errorf("started %s while still paused", e.URI)
+		}
+		f.expectPlaying(t, f.tracks[0].URI, "spotify:playlist:playlist")
+
+		// 再開して最後まで再生したら、管理キューの曲を再生する
+		if err := f.player.Play(ctx); err != nil {
+			t.Fatal(err)
+		}
+		f.observe(t, time.Second)
+		if e := f.observe(t, 3*time.Second); e == nil || e.URI != f.extras[0].URI {
+			t.Errorf("started %v after resuming to the end, want %s", e, f.extras[0].URI)
+		}
+	})
+
+	t.Run("at the end", func(t *testing.T) {
+		f := newManagedFixture(t, 1)
+		f.observe(t, 0)
+		f.observe(t, testTrackLength-2*time.Second)
+		f.clock.Advance(2*time.Second - managedEndTolerance/2)
+		if err := f.player.Pause(ctx); err != nil {
+			t.Fatal(err)
+		}
+		// 曲の終わりで止まっているのは、再生し終えたのと区別できないので次の曲を再生する
+		if e := f.observe(t, 5*time.Second); e == nil || e.URI != f.extras[0].URI {
+			t.Errorf("started %v when paused at the end, want %s", e, f.extras[0].URI)
+		}
+	})
+}
+
+func TestManagedQueueStartGrace(t *testing.T) {
+	ctx := context.Background()
+	f := newManagedFixture(t, 2)
+	f.observe(t, 0)
+	stale := f.state(t) // 管理キューの曲を再生する前に取得した状態
+
+	if _, err := f.queue.Play(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// 再生を始める前に取得した状態が後から届いても、管理キューからの再生をやめない
+	f.clock.Advance(managedStartGrace - time.Second)
+	if e, err := f.queue.Observe(ctx, stale); err != nil || e != nil {
+		t.Fatalf("Observe(stale) = %v, %v", e, err)
+	}
+	f.observe(t, 0)
+	f.observe(t, testTrackLength-managedStartGrace)
+	if e := f.observe(t, 2*time.Second); e == nil || e.URI != f.extras[1].URI {
+		t.Fatalf("started %v at the end of the entry, want %s", e, f.extras[1].URI)
+	}
+
+	// 猶予を過ぎてから別の曲を選んだら、管理キューからの再生をやめる
+	f.clock.Advance(managedStartGrace)
+	if err := f.player.PlayTrackInContext(ctx, "spotify:playlist:playlist", 1); err != nil {
+		t.Fatal(err)
+	}
+	if e := f.observe(t, 0); e != nil {
+		t.Fatalf("started %s after choosing another track", e.URI)
+	}
+	f.observe(t, testTrackLength-time.Second)
+	if e := f.observe(t, 2*time.Second); e != nil {
+		t.Errorf("started %s from an empty queue", e.URI)
+	}
+	// 管理キューの曲の前のコンテキストには戻らず、選んだコンテキストのとおりに進む
+	f.expectPlaying(t, f.tracks[2].URI, "spotify:playlist:playlist")
+}
+
+func TestManagedQueueSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue", "default.json")
+	q := NewManagedQueue(nil, path, nil)
+	entries := []QueueEntry{
+		{URI: "spotify:track:a", Name: "A", Artist: "Artist", ArtistID: "artist", AlbumID: "album"},
+		{URI: "spotify:episode:b", Name: "B"},
+	}
+	for _, e := range entries {
+		q.Add(e)
+	}
+	if err := q.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewManagedQueue(nil, path, nil)
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Entries(); !slices.Equal(got, entries) {
+		t.Errorf("loaded %+v, want %+v", got, entries)
+	}
+
+	missing := NewManagedQueue(nil, filepath.Join(t.TempDir(), "missing.json"), nil)
+	if err := missing.Load(); err != nil || len(missing.Entries()) != 0 {
+		t.Errorf("Load of a missing file = %v, %d entries", err, len(missing.Entries()))
+	}
+}
@@ -0,0 +1,72 @@
+package spotify
+
+import (
+	"context"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// Player はUIが利用するSpotify操作をまとめたインターフェース
+// 実装は Client（Web API）と FakePlayer（インメモリ）の2つ
+type Player interface {
+	CurrentlyPlaying(ctx context.Context) (*spotify.CurrentlyPlaying, error)
+	PlayerState(ctx context.Context) (*spotify.PlayerState, error)
+
+	Play(ctx context.Context) error
+	Pause(ctx context.Context) error
+	Next(ctx context.Context) error
+	SkipToQueueItem(ctx context.Context, index int, uri spotify.URI) (lostContext bool, err error)
+	Previous(ctx context.Context) error
+	Seek(ctx context.Context, position time.Duration) error
+
+	UserPlaylists(ctx context.Context) ([]spotify.SimplePlaylist, error)
+	UserPlaylistsPage(ctx context.Context, offset int) ([]spotify.SimplePlaylist, int, error)
+	PlaylistTracks(ctx context.Context, playlistID spotify.ID) ([]spotify.PlaylistTrack, error)
+	PlaylistTracksPage(ctx context.Context, playlistID spotify.ID, offset int) ([]spotify.PlaylistTrack, int, error)
+	CreatePlaylist(ctx context.Context, userID, name string) (*spotify.FullPlaylist, error)
+	RenamePlaylist(ctx context.Context, playlistID spotify.ID, name string) error
+	UnfollowPlaylist(ctx context.Context, playlistID spotify.ID) error
+	FollowPlaylist(ctx context.Context, playlistID spotify.ID, public bool) error
+	AddPlaylistItems(ctx context.Context, playlistID spotify.ID, uris []spotify.URI, position int) (string, error)
+	RemovePlaylistItem(ctx context.Context, playlistID spotify.ID, uri spotify.URI, position int, snapshotID string) (string, error)
+	MovePlaylistItem(ctx context.Context, playlistID spotify.ID, from, to int, snapshotID string) (string, error)
+	SavedTracks(ctx context.Context) ([]spotify.SavedTrack, error)
+	TracksSaved(ctx context.Context, ids []spotify.ID) ([]bool, error)
+	SaveTracks(ctx context.Context, ids ...spotify.ID) error
+	RemoveSavedTracks(ctx context.Context, ids ...spotify.ID) error
+	SavedAlbumsPage(ctx context.Context, offset int) ([]spotify.SavedAlbum, int, error)
+	AlbumsSaved(ctx context.Context, ids []spotify.ID) ([]bool, error)
+	SaveAlbums(ctx context.Context, ids ...spotify.ID) error
+	RemoveSavedAlbums(ctx context.Context, ids ...spotify.ID) error
+	Album(ctx context.Context, albumID spotify.ID) (*Album, error)
+	ArtistTopTracks(ctx context.Context, artistID spotify.ID, country string) ([]spotify.FullTrack, error)
+	Artist(ctx context.Context, artistID spotify.ID) (*spotify.FullArtist, error)
+	ArtistAlbumsPage(ctx context.Context, artistID spotify.ID, albumType spotify.AlbumType, offset int) ([]spotify.SimpleAlbum, int, error)
+	RelatedArtists(ctx context.Context, artistID spotify.ID) ([]spotify.FullArtist, error)
+
+	PlayTrackInContext(ctx context.Context, contextURI spotify.URI, offset int) error
+	PlayContext(ctx context.Context, contextURI spotify.URI) error
+	PlayTrackURIInContext(ctx context.Context, contextURI, trackURI spotify.URI) error
+	PlayTrackFromURIList(ctx context.Context, uris []spotify.URI, offset int) error
+	PlayLikedSongs(ctx context.Context, userID string, offset int) error
+	PlayTrackAlone(ctx context.Context, trackURI spotify.URI) error
+
+	ToggleShuffle(ctx context.Context, shuffle bool) error
+	SetRepeat(ctx context.Context, state string) error
+
+	Search(ctx context.Context, query string) (*SearchResults, error)
+	SearchMore(ctx context.Context, query string, searchType spotify.SearchType, offset int) (*SearchResults, error)
+	CurrentUser(ctx context.Context) (*spotify.PrivateUser, error)
+	GetQueue(ctx context.Context) (*spotify.Queue, error)
+	AddToQueue(ctx context.Context, uri spotify.URI) error
+
+	PlayerDevices(ctx context.Context) ([]Device, error)
+	SetVolume(ctx context.Context, volume int) error
+	TransferPlayback(ctx context.Context, deviceID spotify.ID, play bool) error
+}
+
+var (
+	_ Player = (*Client)(nil)
+	_ Player = (*FakePlayer)(nil)
+)
@@ -0,0 +1,112 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// 再生を始めてから PlayerState に反映されるまで少し時間がかかるので、何度か確かめる
+const (
+	skipVerifyAttempts = 5
+	skipVerifyInterval = 300 * time.Millisecond
+)
+
+// ErrQueueChanged はキューが変わっていて、指定した曲がキューになかった場合のエラー
+var ErrQueueChanged = errors.New("the queue has changed")
+
+// skipToQueueItem はキューの index 番目（0 が次の曲）の曲 uri から再生する
+// Next を繰り返すと遅く、途中でキューが変わると行き過ぎるので、その曲から再生し直す
+//   - 再生中のコンテキスト（プレイリストやアルバム）にある曲なら、コンテキストの中のその曲から再生する
+//   - そうでなければ、キューのその曲以降を URI のリストとして再生する
+//
+// どちらも再生後に PlayerState で曲を確かめ、確かめられなければ今どの曲を再生しているかをエラーで返す
+// Spotify のキューに追加した曲は、飛ばしてもキューに残る
+// URI のリストで再生するとコンテキストから外れ、キューの後にコンテキストの続きを再生しなくなる
+// その場合は lostContext が true になる
+func skipToQueueItem(ctx context.Context, p Player, index int, uri spotify.URI) (lostContext bool, err error) {
+	state, err := p.PlayerState(ctx)
+	if err != nil {
+		return false, err
+	}
+	queue, err := p.GetQueue(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// 取得し直したキューで曲の位置を探す（位置がずれていれば最初に見つかった位置を使う）
+	items := queue.Items
+	if index < 0 || index >= len(items) || items[index].URI != uri {
+		index = -1
+		for i, t := range items {
+			if t.URI == uri {
+				index = i
+				break
+			}
+		}
+	}
+	if index < 0 {
+		return false, fmt.Errorf("%w: the selected track is no longer in it", ErrQueueChanged)
+	}
+	target := items[index]
+
+	// コンテキストの中での位置は曲の URI で指定する（アーティストのコンテキストは位置を指定できない）
+	inContext := state.PlaybackContext.URI != ""
+	if c := state.PlaybackContext; inContext && c.Type != "artist" {
+		err := p.PlayTrackURIInContext(ctx, c.URI, target.URI)
+		if err == nil {
+			err = verifyPlaying(ctx, p, target)
+		}
+		if err == nil || IsNoActiveDevice(err) || ctx.Err() != nil {
+			return false, err
+		}
+		// キューに追加した曲など、コンテキストにない曲は URI のリストで再生する
+	}
+
+	uris := make([]spotify.URI, 0, len(items)-index)
+	for _, t := range items[index:] {
+		// ローカルファイルは URI のリストで再生できない
+		if !strings.HasPrefix(string(t.URI), "spotify:local:") {
+			uris = append(uris, t.URI)
+		}
+	}
+	if err := p.PlayTrackFromURIList(ctx, uris, 0); err != nil {
+		return false, fmt.Errorf("couldn't play %q: %w", target.Name, err)
+	}
+	return inContext, verifyPlaying(ctx, p, target)
+}
+
+// verifyPlaying は target の再生が始まったことを PlayerState で確かめる
+// 確かめられなかった場合は、代わりに再生している曲をエラーで返す
+func verifyPlaying(ctx context.Context, p Player, target spotify.FullTrack) error {
+	var playing string
+	for attempt := 0; attempt < skipVerifyAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(skipVerifyInterval):
+			}
+		}
+		state, err := p.PlayerState(ctx)
+		if err != nil {
+			return err
+		}
+		if state.Item == nil {
+			playing = ""
+			continue
+		}
+		if state.Item.URI == target.URI {
+			return nil
+		}
+		playing = state.Item.Name
+	}
+	if playing == "" {
+		return fmt.Errorf("started %q, but nothing is playing", target.Name)
+	}
+	return fmt.Errorf("started %q, but %q is playing", target.Name, playing)
+}
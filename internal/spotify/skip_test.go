@@ -0,0 +1,63 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+func TestSkipToQueueItem(t *testing.T) {
+	ctx := context.Background()
+	const playlist = spotify.URI("spotify:playlist:playlist")
+
+	tests := []struct {
+		name string
+		// setup は再生を始めてキューに曲を加え、飛ぶ先のキューの位置を返す
+		setup       func(f *managedFixture) int
+		want        spotify.URI
+		wantContext spotify.URI
+		lostContext bool
+	}{
+		{"track in the context", func(f *managedFixture) int {
+			return 1 // track2
+		}, "spotify:track:track2", playlist, false},
+		{"queued track", func(f *managedFixture) int {
+			for _, e := range f.extras {
+				if err := f.player.AddToQueue(ctx, e.URI); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return 1 // extra1
+		}, "spotify:track:extra1", "", true},
+		{"no context", func(f *managedFixture) int {
+			if err := f.player.PlayTrackFromURIList(ctx, []spotify.URI{f.tracks[0].URI, f.tracks[1].URI, f.tracks[2].URI}, 0); err != nil {
+				t.Fatal(err)
+			}
+			return 1 // track2
+		}, "spotify:track:track2", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newManagedFixture(t, 0)
+			index := tt.setup(f)
+			lost, err := f.player.SkipToQueueItem(ctx, index, tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lost != tt.lostContext {
+				t.Errorf("lostContext = %v, want %v", lost, tt.lostContext)
+			}
+			f.expectPlaying(t, tt.want, tt.wantContext)
+		})
+	}
+
+	t.Run("queue changed", func(t *testing.T) {
+		f := newManagedFixture(t, 0)
+		if _, err := f.player.SkipToQueueItem(ctx, 0, f.extras[0].URI); !errors.Is(err, ErrQueueChanged) {
+			t.Errorf("err = %v, want ErrQueueChanged", err)
+		}
+		f.expectPlaying(t, f.tracks[0].URI, playlist)
+	})
+}
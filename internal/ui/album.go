@@ -0,0 +1,289 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"spotify-tui/internal/spotify"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+	spotifysdk "github.com/zmb3/spotify/v2"
+)
+
+// albumPage はメインパネルに表示するアルバムのページ
+type albumPage struct {
+	id          spotifysdk.ID
+	uri         spotifysdk.URI
+	name        string
+	artists     []spotifysdk.SimpleArtist
+	releaseDate string
+	label       string
+	tracks      []spotifysdk.SimpleTrack // ディスク、曲番号の順（アルバムのコンテキストでの位置と同じ）
+	loading     bool
+	failed      bool // 読み込みに失敗した
+	index       int
+}
+
+func newAlbumPage(a *spotify.Album) albumPage {
+	return albumPage{
+		id:          a.ID,
+		uri:         a.URI,
+		name:        a.Name,
+		artists:     a.Artists,
+		releaseDate: a.ReleaseDate,
+		label:       a.Label,
+		tracks:      a.Tracks.Tracks,
+	}
+}
+
+// discCount はアルバムのディスクの枚数を返す
+func (p albumPage) discCount() int {
+	discs := 1
+	for _, t := range p.tracks {
+		discs = max(discs, int(t.DiscNumber))
+	}
+	return discs
+}
+
+// savedAlbumsPage はサイドバーから開く、保存したアルバムの一覧
+type savedAlbumsPage struct {
+	albums  entrySection
+	index   int
+	loading bool // 最初のページを読み込み中
+}
+
+// appendSavedAlbums は offset からのページを一覧に加える
+func (p savedAlbumsPage) appendSavedAlbums(albums []spotifysdk.SavedAlbum, offset, total int) savedAlbumsPage {
+	var entries []searchEntry
+	if offset > 0 {
+		entries = append(entries, p.albums.entries...)
+	}
+	for _, a := range albums {
+		entries = append(entries, albumEntry(a.SimpleAlbum))
+	}
+	p.loading = false
+	p.albums.entries = entries
+	p.albums.page = searchPage{total: total}
+	if next := offset + len(albums); len(albums) > 0 && next < total {
+		p.albums.page.next = next
+	}
+	return p
+}
+
+// openAlbum はアルバムのページをメインパネルに開く
+// 表示中のページは pageBack に積み、back で戻れるようにする
+func (m Model) openAlbum(id spotifysdk.ID) (Model, tea.Cmd) {
+	if id == "" {
+		return m, nil
+	}
+	m = m.pushPage()
+	m.searchMode = false
+	m.searchInput.Blur()
+	m.view = viewAlbum
+	m.focus = FocusMain
+	return m.loadAlbum(albumPage{id: id})
+}
+
+// loadAlbum はページを表示して、アルバムの情報と曲を読み込み直す
+func (m Model) loadAlbum(page albumPage) (Model, tea.Cmd) {
+	m.pageLoadID++
+	m.album = page
+	m.album.loading = true
+	m.album.failed = false
+	return m, m.fetchAlbum(page.id)
+}
+
+// openSavedAlbums は保存したアルバムの一覧をメインパネルに開き、最初のページを読み込む
+func (m Model) openSavedAlbums() (Model, tea.Cmd) {
+	m.pageLoadID++
+	m.view = viewSavedAlbums
+	m.savedAlbums = savedAlbumsPage{albums: entrySection{title: "Saved Albums"}, loading: true}
+	return m, m.fetchSavedAlbums(0)
+}
+
+// moveSavedAlbumsSelection は一覧の選択を移動し、最後に近づいたら続きを読み込む
+func (m Model) moveSavedAlbumsSelection(index int) (Model, tea.Cmd) {
+	sec := &m.savedAlbums.albums
+	m.savedAlbums.index = max(min(index, len(sec.entries)-1), 0)
+	if sec.page.next == 0 || sec.page.loading || len(sec.entries)-1-m.savedAlbums.index >= searchMorePrefetch {
+		return m, nil
+	}
+	sec.page.loading = true
+	return m, m.fetchSavedAlbums(sec.page.next)
+}
+
+// selectedTrackAlbum はフォーカスしているリストで選択中の曲のアルバムを返す
+// 曲を選んでいない場合（サイドバーなど）は再生中の曲のアルバムを返す
+func (m Model) selectedTrackAlbum() spotifysdk.ID {
+	switch m.focus {
+	case FocusMain:
+		switch m.view {
+		case viewArtist:
+			if section, i, ok := m.artist.entryAt(m.artist.index); ok {
+				return m.artist.sections[section].entries[i].albumID
+			}
+		case viewAlbum:
+			if len(m.album.tracks) > 0 {
+				return m.album.id
+			}
+		case viewSavedAlbums:
+			if i := m.savedAlbums.index; i < len(m.savedAlbums.albums.entries) {
+				return m.savedAlbums.albums.entries[i].id
+			}
+		default:
+			if item, ok := m.trackList.SelectedItem().(trackItem); ok && item.index < len(m.tracks) {
+				return m.tracks[item.index].Track.Album.ID
+			}
+		}
+	case FocusQueue:
+		if i := m.queueList.Index(); i >= 0 && i < len(m.queue) {
+			return m.queue[i].Album.ID
+		}
+	}
+	return m.playingAlbum()
+}
+
+// playingAlbum は再生中の曲のアルバムを返す
+func (m Model) playingAlbum() spotifysdk.ID {
+	if m.currentTrack == nil || m.currentTrack.Item == nil {
+		return ""
+	}
+	return m.currentTrack.Item.Album.ID
+}
+
+// playAlbumTrack はアルバムのコンテキストで offset 番目の曲から再生する
+func (m Model) playAlbumTrack(offset int) tea.Cmd {
+	if offset >= len(m.album.tracks) {
+		return nil
+	}
+	uri, name := m.album.uri, m.album.name
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
+		if err := m.client.PlayTrackInContext(m.ctx, uri, offset); err != nil {
+			return playerError(err, cmd)
+		}
+		return playStartedMsg(name)
+	}
+	return cmd
+}
+
+func (m Model) fetchAlbum(albumID spotifysdk.ID) tea.Cmd {
+	loadID := m.pageLoadID
+	return func() tea.Msg {
+		album, err := m.client.Album(m.ctx, albumID)
+		if err != nil {
+			return albumPageMsg{loadID: loadID, err: err}
+		}
+		return albumPageMsg{loadID: loadID, page: newAlbumPage(album)}
+	}
+}
+
+func (m Model) fetchSavedAlbums(offset int) tea.Cmd {
+	loadID := m.pageLoadID
+	return func() tea.Msg {
+		albums, total, err := m.client.SavedAlbumsPage(m.ctx, offset)
+		return savedAlbumsMsg{loadID: loadID, albums: albums, offset: offset, total: total, err: err}
+	}
+}
+
+func (m Model) renderAlbumPage(width, height int) string {
+	p := m.album
+	if p.loading {
+		return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, "Loading album...")
+	}
+	if p.failed {
+		return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, "Couldn't load the album")
+	}
+
+	// アーティスト、発売日、レーベル、曲数と合計時間
+	var info []string
+	var artists []string
+	for _, a := range p.artists {
+		artists = append(artists, a.Name)
+	}
+	for _, s := range []string{strings.Join(artists, ", "), p.releaseDate, p.label} {
+		if s != "" {
+			info = append(info, s)
+		}
+	}
+	var total time.Duration
+	for _, t := range p.tracks {
+		total += t.TimeDuration()
+	}
+	summary := fmt.Sprintf(" %d tracks, %s", len(p.tracks), formatDuration(total))
+
+	lines := []string{
+		m.theme.Title.Render(truncate(" 💿 "+p.name+m.likedMark(p.uri), width)),
+		m.theme.MutedText.Render(truncate(" "+strings.Join(info, " · "), width)),
+		m.theme.MutedText.Render(truncate(summary, width)),
+		"",
+	}
+	if len(p.tracks) == 0 {
+		lines = append(lines, " No tracks")
+	}
+
+	// 複数枚組のアルバムはディスクごとに見出しを付ける
+	var rows []string
+	selectedRow, disc := 0, 0
+	multiDisc := p.discCount() > 1
+	for i, t := range p.tracks {
+		if multiDisc && int(t.DiscNumber) != disc {
+			disc = int(t.DiscNumber)
+			if len(rows) > 0 {
+				rows = append(rows, "")
+			}
+			rows = append(rows, m.theme.Title.Render(truncate(fmt.Sprintf(" Disc %d", disc), width)))
+		}
+
+		number := fmt.Sprintf("%3d", t.TrackNumber)
+		isPlaying := t.URI != "" && string(t.URI) == m.playingTrackURI
+		if isPlaying {
+			number = "  ♫"
+		}
+		duration := formatDuration(t.TimeDuration())
+		// 曲名の幅: 選択マーク(2) + 曲番号(3) + 空白(2) と、空白(1) + 再生時間を除いた残り
+		// 保存済みの曲は♥が切れないよう、名前の方を短くする
+		nameWidth := max(width-7-1-runewidth.StringWidth(duration), 1)
+		mark := m.likedMark(t.URI)
+		name := truncate(t.Name, max(nameWidth-runewidth.StringWidth(mark), 1)) + mark
+		name = runewidth.FillRight(name, nameWidth)
+
+		style := m.theme.Track
+		marker := "  "
+		switch {
+		case i == p.index:
+			style = m.theme.SelectedTrack
+			marker = " ▶"
+			selectedRow = len(rows)
+		case isPlaying:
+			style = m.theme.PlayingTrack
+		}
+		rows = append(rows, style.Width(width).Render(truncate(marker+number+"  "+name+" "+duration, width)))
+	}
+
+	// 選択中の行が見えるようにスクロールする
+	visibleLines := max(height-len(lines), 1)
+	start := min(max(selectedRow-visibleLines/3, 0), max(len(rows)-visibleLines, 0))
+	end := min(start+visibleLines, len(rows))
+	lines = append(lines, rows[start:end]...)
+
+	inner := strings.Join(lines, "\n")
+	return lipgloss.Place(width, height, lipgloss.Left, lipgloss.Top, inner)
+}
+
+func (m Model) renderSavedAlbums(width, height int) string {
+	p := m.savedAlbums
+	if p.loading {
+		return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, "Loading albums...")
+	}
+	if len(p.albums.entries) == 0 {
+		return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, "No saved albums")
+	}
+
+	lines := m.renderSections([]entrySection{p.albums}, p.index, width, height)
+	inner := strings.Join(lines, "\n")
+	return lipgloss.Place(width, height, lipgloss.Left, lipgloss.Top, inner)
+}
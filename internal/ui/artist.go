@@ -0,0 +1,346 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"spotify-tui/internal/auth"
+	"spotify-tui/internal/spotify"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	spotifysdk "github.com/zmb3/spotify/v2"
+)
+
+// artistSection はアーティストページのセクション
+type artistSection int
+
+const (
+	artistTopTracks artistSection = iota
+	artistAlbums
+	artistSingles
+	artistCompilations
+	artistRelated
+
+	artistSectionCount // セクションの数
+)
+
+// artistSections はアーティストページのセクションの見出しと、アルバムのセクションで読み込む種類
+var artistSections = [artistSectionCount]struct {
+	title     string
+	albumType spotifysdk.AlbumType // アルバムのセクション以外は 0
+}{
+	{"Top Tracks", 0},
+	{"Albums", spotifysdk.AlbumTypeAlbum},
+	{"Singles", spotifysdk.AlbumTypeSingle},
+	{"Compilations", spotifysdk.AlbumTypeCompilation},
+	{"Related Artists", 0},
+}
+
+// artistPage はメインパネルに表示するアーティストのページ
+type artistPage struct {
+	id        spotifysdk.ID
+	uri       spotifysdk.URI
+	name      string
+	followers int
+	genres    []string
+	loading   bool
+	failed    bool // 読み込みに失敗した
+	sections  [artistSectionCount]entrySection
+	index     int // 全セクションを通した選択位置
+}
+
+// count はページの全セクションの項目数を返す
+func (p artistPage) count() int {
+	n := 0
+	for _, section := range p.sections {
+		n += len(section.entries)
+	}
+	return n
+}
+
+// entryAt は全セクションを通した位置 index の項目のセクションと、セクション内の位置を返す
+func (p artistPage) entryAt(index int) (artistSection, int, bool) {
+	for s, section := range p.sections {
+		if index < len(section.entries) {
+			return artistSection(s), index, true
+		}
+		index -= len(section.entries)
+	}
+	return 0, 0, false
+}
+
+// newArtistPage はアーティストの情報と人気曲、関連アーティストからページを作る
+// アルバムのセクションは最初のページを appendArtistAlbums で加える
+func newArtistPage(a *spotifysdk.FullArtist, top []spotifysdk.FullTrack, related []spotifysdk.FullArtist) artistPage {
+	p := artistPage{
+		id:        a.ID,
+		uri:       a.URI,
+		name:      a.Name,
+		followers: int(a.Followers.Count),
+		genres:    a.Genres,
+	}
+	for s := range p.sections {
+		p.sections[s].title = artistSections[s].title
+	}
+	for _, t := range top {
+		p.sections[artistTopTracks].entries = append(p.sections[artistTopTracks].entries, trackEntry(t))
+	}
+	for _, r := range related {
+		p.sections[artistRelated].entries = append(p.sections[artistRelated].entries, artistEntry(r))
+	}
+	return p
+}
+
+// appendArtistAlbums はアルバムのセクションに offset からのページを加える
+// 選択中の項目が変わらないよう、後ろのセクションを選んでいた場合は位置をずらす
+func (p artistPage) appendArtistAlbums(section artistSection, albums []spotifysdk.SimpleAlbum, offset, total int) artistPage {
+	start := 0
+	for s := range section {
+		start += len(p.sections[s].entries)
+	}
+	sec := &p.sections[section]
+	if p.index >= start+len(sec.entries) && p.index < p.count() {
+		p.index += len(albums)
+	}
+	entries := make([]searchEntry, 0, len(sec.entries)+len(albums))
+	entries = append(entries, sec.entries...)
+	for _, a := range albums {
+		e := albumEntry(a)
+		// アーティストのページなので、アーティスト名の代わりに年だけを表示する
+		e.detail = ""
+		if len(a.ReleaseDate) >= 4 {
+			e.detail = a.ReleaseDate[:4]
+		}
+		entries = append(entries, e)
+	}
+	sec.entries = entries
+	sec.page = searchPage{total: total}
+	if next := offset + len(albums); len(albums) > 0 && next < total {
+		sec.page.next = next
+	}
+	return p
+}
+
+// openArtist はアーティストのページをメインパネルに開く
+// 表示中のページは pageBack に積み、back で戻れるようにする
+func (m Model) openArtist(id spotifysdk.ID) (Model, tea.Cmd) {
+	if id == "" {
+		return m, nil
+	}
+	m = m.pushPage()
+	m.searchMode = false
+	m.searchInput.Blur()
+	m.view = viewArtist
+	m.focus = FocusMain
+	return m.loadArtist(artistPage{id: id})
+}
+
+// loadArtist はページを表示して、アーティストの情報を読み込み直す
+func (m Model) loadArtist(page artistPage) (Model, tea.Cmd) {
+	m.pageLoadID++
+	m.artist = page
+	m.artist.loading = true
+	m.artist.failed = false
+	return m, m.fetchArtistPage(page.id)
+}
+
+// openArtistEntry はアーティストページで選択した項目を開く
+// 人気曲は再生し、アルバムと関連アーティストはそのページを開く
+func (m Model) openArtistEntry() (Model, tea.Cmd) {
+	section, i, ok := m.artist.entryAt(m.artist.index)
+	if !ok {
+		return m, nil
+	}
+	e := m.artist.sections[section].entries[i]
+	switch section {
+	case artistTopTracks:
+		return m, m.playArtistTopTracks(i)
+	case artistRelated:
+		return m.openArtist(e.id)
+	}
+	return m.openAlbum(e.id)
+}
+
+// moveArtistSelection はアーティストページの選択を移動し、アルバムのセクションの最後に近づいたら続きを読み込む
+func (m Model) moveArtistSelection(index int) (Model, tea.Cmd) {
+	m.artist.index = max(min(index, m.artist.count()-1), 0)
+
+	section, i, ok := m.artist.entryAt(m.artist.index)
+	if !ok || artistSections[section].albumType == 0 {
+		return m, nil
+	}
+	sec := &m.artist.sections[section]
+	if sec.page.next == 0 || sec.page.loading || len(sec.entries)-1-i >= searchMorePrefetch {
+		return m, nil
+	}
+	sec.page.loading = true
+	return m, m.fetchArtistAlbums(m.artist.id, section, sec.page.next)
+}
+
+// selectedTrackArtist はフォーカスしているリストで選択中の曲のアーティストを返す
+// 曲を選んでいない場合（サイドバーなど）は再生中の曲のアーティストを返す
+func (m Model) selectedTrackArtist() spotifysdk.ID {
+	switch m.focus {
+	case FocusMain:
+		switch m.view {
+		case viewArtist:
+			if section, i, ok := m.artist.entryAt(m.artist.index); ok {
+				return m.artist.sections[section].entries[i].artistID
+			}
+		case viewAlbum:
+			if i := m.album.index; i < len(m.album.tracks) {
+				return firstArtistID(m.album.tracks[i].Artists)
+			}
+		case viewSavedAlbums:
+			if i := m.savedAlbums.index; i < len(m.savedAlbums.albums.entries) {
+				return m.savedAlbums.albums.entries[i].artistID
+			}
+		default:
+			if item, ok := m.trackList.SelectedItem().(trackItem); ok && item.index < len(m.tracks) {
+				return firstArtistID(m.tracks[item.index].Track.Artists)
+			}
+		}
+	case FocusQueue:
+		if i := m.queueList.Index(); i >= 0 && i < len(m.queue) {
+			return firstArtistID(m.queue[i].Artists)
+		}
+	}
+	return m.playingArtist()
+}
+
+// playingArtist は再生中の曲のアーティストを返す
+func (m Model) playingArtist() spotifysdk.ID {
+	if m.currentTrack == nil || m.currentTrack.Item == nil {
+		return ""
+	}
+	return firstArtistID(m.currentTrack.Item.Artists)
+}
+
+// playingArtistName はアーティストのコンテキストで再生しているときに、そのアーティスト名を返す
+// 再生中の曲のアーティストから探し、見つからなければ再生を始めたときの名前を使う
+func (m Model) playingArtistName() string {
+	if m.currentTrack.Item != nil {
+		for _, a := range m.currentTrack.Item.Artists {
+			if a.URI == m.currentTrack.PlaybackContext.URI {
+				return a.Name
+			}
+		}
+	}
+	if m.playingPlaylistName != "" {
+		return m.playingPlaylistName
+	}
+	return "Artist"
+}
+
+func firstArtistID(artists []spotifysdk.SimpleArtist) spotifysdk.ID {
+	if len(artists) == 0 {
+		return ""
+	}
+	return artists[0].ID
+}
+
+// playArtistTopTracks はアーティストの人気曲を offset 番目から再生する
+// 先頭の曲はアーティストのコンテキストで再生し、それ以外はアーティストのコンテキストの中のその曲から再生する
+// Web API はアーティストのコンテキストで位置を指定できない（400 になる）ので、その場合は人気曲のURIのリストで再生する
+// どちらの場合もプレイヤーバーにはアーティスト名を表示する
+func (m Model) playArtistTopTracks(offset int) tea.Cmd {
+	entries := m.artist.sections[artistTopTracks].entries
+	if offset >= len(entries) {
+		return nil
+	}
+	if offset == 0 {
+		return m.playContext(m.artist.uri, m.artist.name)
+	}
+	contextURI, name := m.artist.uri, m.artist.name
+	uris := make([]spotifysdk.URI, len(entries))
+	for i, e := range entries {
+		uris[i] = e.uri
+	}
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
+		err := m.client.PlayTrackURIInContext(m.ctx, contextURI, uris[offset])
+		if err != nil && !spotify.IsNoActiveDevice(err) && m.ctx.Err() == nil {
+			err = m.client.PlayTrackFromURIList(m.ctx, uris, offset)
+		}
+		if err != nil {
+			return playerError(err, cmd)
+		}
+		return playStartedMsg(name)
+	}
+	return cmd
+}
+
+// fetchArtistPage はアーティストの情報、人気曲、アルバムの最初のページ、関連アーティストを読み込む
+func (m Model) fetchArtistPage(artistID spotifysdk.ID) tea.Cmd {
+	loadID := m.pageLoadID
+	country := ""
+	if m.user != nil {
+		country = m.user.Country
+	}
+	return func() tea.Msg {
+		artist, err := m.client.Artist(m.ctx, artistID)
+		if err != nil {
+			return artistPageMsg{loadID: loadID, err: err}
+		}
+		top, err := m.client.ArtistTopTracks(m.ctx, artistID, country)
+		if err != nil {
+			return artistPageMsg{loadID: loadID, err: err}
+		}
+		// 関連アーティストは新しく登録したアプリでは使えない（404 になる）ので、
+		// 再ログインが必要な場合以外はエラーにせずセクションを空にする
+		related, err := m.client.RelatedArtists(m.ctx, artistID)
+		if err != nil && (errors.Is(err, auth.ErrReloginRequired) || m.ctx.Err() != nil) {
+			return artistPageMsg{loadID: loadID, err: err}
+		}
+
+		page := newArtistPage(artist, top, related)
+		for s, section := range artistSections {
+			if section.albumType == 0 {
+				continue
+			}
+			albums, total, err := m.client.ArtistAlbumsPage(m.ctx, artistID, section.albumType, 0)
+			if err != nil {
+				return artistPageMsg{loadID: loadID, err: err}
+			}
+			page = page.appendArtistAlbums(artistSection(s), albums, 0, total)
+		}
+		page.index = 0
+		return artistPageMsg{loadID: loadID, page: page}
+	}
+}
+
+// fetchArtistAlbums はアルバムのセクションの続きのページを読み込む
+func (m Model) fetchArtistAlbums(artistID spotifysdk.ID, section artistSection, offset int) tea.Cmd {
+	loadID := m.pageLoadID
+	albumType := artistSections[section].albumType
+	return func() tea.Msg {
+		albums, total, err := m.client.ArtistAlbumsPage(m.ctx, artistID, albumType, offset)
+		return artistAlbumsMsg{loadID: loadID, section: section, albums: albums, offset: offset, total: total, err: err}
+	}
+}
+
+func (m Model) renderArtistPage(width, height int) string {
+	p := m.artist
+	if p.loading {
+		return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, "Loading artist...")
+	}
+	if p.failed {
+		return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, "Couldn't load the artist")
+	}
+
+	title := m.theme.Title.Render(truncate(" 🎤 "+p.name, width))
+	info := fmt.Sprintf(" %d followers", p.followers)
+	if len(p.genres) > 0 {
+		info += " · " + strings.Join(p.genres, ", ")
+	}
+	lines := []string{title, m.theme.MutedText.Render(truncate(info, width)), ""}
+	if p.count() == 0 {
+		lines = append(lines, " No tracks or albums")
+	}
+	lines = append(lines, m.renderSections(p.sections[:], p.index, width, height-len(lines))...)
+
+	inner := strings.Join(lines, "\n")
+	return lipgloss.Place(width, height, lipgloss.Left, lipgloss.Top, inner)
+}
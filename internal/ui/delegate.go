@@ -6,15 +6,16 @@ import (
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 	"github.com/mattn/go-runewidth"
 )
 
 // TrackDelegate はトラックリスト用のカスタムデリゲート
-type TrackDelegate struct{}
+type TrackDelegate struct {
+	theme Theme
+}
 
-func NewTrackDelegate() TrackDelegate {
-	return TrackDelegate{}
+func NewTrackDelegate(theme Theme) TrackDelegate {
+	return TrackDelegate{theme: theme}
 }
 
 func (d TrackDelegate) Height() int                             { return 2 }
@@ -43,28 +44,30 @@ func (d TrackDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 
 	width := m.Width()
 
-	// 幅を制限
-	titleLine = truncateText(titleLine, width-2)
+	// 幅を制限（Liked Songs に保存済みの曲は名前の後に♥を付ける）
+	titleLine = likedTitle(titleLine, track.liked, width-2)
 	artistLine = truncateText(artistLine, width-2)
 
-	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF"))
-	artistStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#B3B3B3"))
+	titleStyle := d.theme.Track
+	artistStyle := d.theme.MutedText
 
 	if isSelected {
-		titleStyle = titleStyle.Background(lipgloss.Color("#282828")).Bold(true).Foreground(lipgloss.Color("#1DB954"))
-		artistStyle = artistStyle.Background(lipgloss.Color("#282828"))
+		titleStyle = d.theme.SelectedTrack
+		artistStyle = d.theme.SelectedMuted
 	} else if isPlaying {
-		titleStyle = titleStyle.Foreground(lipgloss.Color("#1DB954")).Bold(true)
+		titleStyle = d.theme.PlayingTrack
 	}
 
 	fmt.Fprintf(w, "%s\n%s", titleStyle.Width(width).Render(titleLine), artistStyle.Width(width).Render(artistLine))
 }
 
 // QueueDelegate はキューリスト用のカスタムデリゲート
-type QueueDelegate struct{}
+type QueueDelegate struct {
+	theme Theme
+}
 
-func NewQueueDelegate() QueueDelegate {
-	return QueueDelegate{}
+func NewQueueDelegate(theme Theme) QueueDelegate {
+	return QueueDelegate{theme: theme}
 }
 
 func (d QueueDelegate) Height() int                             { return 2 }
@@ -80,26 +83,38 @@ func (d QueueDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 	isSelected := index == m.Index()
 	width := m.Width()
 
-	// タイトル行
+	// タイトル行（Up Next の曲には印を付ける）
 	titleLine := fmt.Sprintf("   %s", q.name)
+	if q.upNext {
+		titleLine = fmt.Sprintf(" » %s", q.name)
+	}
 	// アーティスト行（灰色）
 	artistLine := fmt.Sprintf("   %s", q.artist)
 
 	// 幅を制限
-	titleLine = truncateText(titleLine, width)
+	titleLine = likedTitle(titleLine, q.liked, width)
 	artistLine = truncateText(artistLine, width)
 
-	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF"))
-	artistStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#B3B3B3"))
+	titleStyle := d.theme.Track
+	artistStyle := d.theme.MutedText
 
 	if isSelected {
-		titleStyle = titleStyle.Background(lipgloss.Color("#282828")).Bold(true).Foreground(lipgloss.Color("#1DB954"))
-		artistStyle = artistStyle.Background(lipgloss.Color("#282828"))
+		titleStyle = d.theme.SelectedTrack
+		artistStyle = d.theme.SelectedMuted
 	}
 
 	fmt.Fprintf(w, "%s\n%s", titleStyle.Width(width).Render(titleLine), artistStyle.Width(width).Render(artistLine))
 }
 
+// likedTitle は title を width に収め、liked なら末尾に♥を付ける
+// 長い名前でも♥が切れないよう、名前の方を短くする
+func likedTitle(title string, liked bool, width int) string {
+	if !liked {
+		return truncateText(title, width)
+	}
+	return truncateText(title, width-2) + " ♥"
+}
+
 // truncateText はテキストを指定幅（表示幅）に収まるようにカットし、末尾に...を追加する
 func truncateText(text string, width int) string {
 	if runewidth.StringWidth(text) <= width {
@@ -116,6 +131,8 @@ type queueItem struct {
 	name   string
 	artist string
 	uri    string
+	liked  bool
+	upNext bool // Up Next の曲（Spotify のキューの曲ではない）
 }
 
 func (i queueItem) FilterValue() string { return i.name }
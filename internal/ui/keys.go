@@ -0,0 +1,388 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+)
+
+// action はキーに割り当てる操作（設定ファイルの keys のキー）
+type action string
+
+const (
+	actQuit             action = "quit"
+	actHelp             action = "help"
+	actPlayPause        action = "play_pause"
+	actNext             action = "next"
+	actPrevious         action = "previous"
+	actShuffle          action = "shuffle"
+	actRepeat           action = "repeat"
+	actSeekBackward     action = "seek_backward"
+	actSeekForward      action = "seek_forward"
+	actSeekBackwardLong action = "seek_backward_long"
+	actSeekForwardLong  action = "seek_forward_long"
+	actSeekPercent      action = "seek_percent"
+	actVolumeUp         action = "volume_up"
+	actVolumeDown       action = "volume_down"
+	actMute             action = "mute"
+	actDevices          action = "devices"
+	actAccounts         action = "accounts"
+	actRelogin          action = "relogin"
+	actTheme            action = "theme"
+	actSearch           action = "search"
+
+	actFocusNext   action = "focus_next"
+	actFocusPrev   action = "focus_prev"
+	actSelect      action = "select"
+	actUp          action = "up"
+	actDown        action = "down"
+	actGoTop       action = "go_top"
+	actGoBottom    action = "go_bottom"
+	actGoLiked     action = "go_liked"
+	actGoPlaylists action = "go_playlists"
+	actGoQueue     action = "go_queue"
+
+	actArtist        action = "artist"
+	actPlayingArtist action = "playing_artist"
+	actAlbum         action = "album"
+	actPlayingAlbum  action = "playing_album"
+	actBack          action = "back"
+	actLike          action = "like"
+	actLikePlaying   action = "like_playing"
+	actLikeAlbum     action = "like_album"
+	actAddToQueue    action = "add_to_queue"
+	actAddToUpNext   action = "add_to_up_next"
+	actPlayNext      action = "play_next"
+	actClearUpNext   action = "clear_up_next"
+
+	actCreatePlaylist       action = "create_playlist"
+	actRenamePlaylist       action = "rename_playlist"
+	actRemove               action = "remove"
+	actAddToPlaylist        action = "add_to_playlist"
+	actAddPlayingToPlaylist action = "add_playing_to_playlist"
+	actMoveUp               action = "move_up"
+	actMoveDown             action = "move_down"
+	actUndo                 action = "undo"
+
+	actLayoutMode    action = "layout_mode"
+	actToggleSidebar action = "toggle_sidebar"
+	actToggleQueue   action = "toggle_queue"
+	actGrowPanel     action = "grow_panel"
+	actShrinkPanel   action = "shrink_panel"
+
+	actSearchSubmit action = "search_submit"
+	actSearchCancel action = "search_cancel"
+	actSearchDelete action = "search_delete"
+	actSearchUp     action = "search_up"
+	actSearchDown   action = "search_down"
+
+	actSearchNextSection   action = "search_next_section"
+	actSearchPrevSection   action = "search_prev_section"
+	actSearchArtist        action = "search_artist"
+	actSearchAlbum         action = "search_album"
+	actSearchLike          action = "search_like"
+	actSearchAddToPlaylist action = "search_add_to_playlist"
+	actSearchAddToQueue    action = "search_add_to_queue"
+	actSearchAddToUpNext   action = "search_add_to_up_next"
+
+	actPickerUp     action = "picker_up"
+	actPickerDown   action = "picker_down"
+	actPickerSelect action = "picker_select"
+	actPickerClose  action = "picker_close"
+	actTransferOnly action = "transfer_only"
+)
+
+// keyScope は同時に有効になるキーの範囲
+// 同じスコープ内で同じキー（または chord の前半）を複数のアクションに割り当てると衝突になる
+type keyScope int
+
+const (
+	scopeMain   keyScope = iota // メイン画面（プレイヤーとナビゲーション）
+	scopeSearch                 // 検索モード
+	scopePicker                 // デバイス選択・アカウント切り替え・プレイリスト選択
+)
+
+// keyGroup はヘルプに表示するアクションのまとまり
+type keyGroup struct {
+	title   string
+	scope   keyScope
+	actions []actionHelp
+}
+
+type actionHelp struct {
+	action action
+	help   string
+}
+
+// keyGroups はヘルプの表示順に並べた全アクション
+var keyGroups = []keyGroup{
+	{"Player", scopeMain, []actionHelp{
+		{actPlayPause, "play/pause"},
+		{actNext, "next track"},
+		{actPrevious, "previous track"},
+		{actShuffle, "toggle shuffle"},
+		{actRepeat, "cycle repeat"},
+		{actSeekBackward, "seek -5s"},
+		{actSeekForward, "seek +5s"},
+		{actSeekBackwardLong, "seek -30s"},
+		{actSeekForwardLong, "seek +30s"},
+		{actSeekPercent, "jump to 0%–90% (n-th key × 10%)"},
+		{actVolumeUp, "volume up"},
+		{actVolumeDown, "volume down"},
+		{actMute, "mute"},
+		{actDevices, "devices"},
+		{actAccounts, "switch account"},
+		{actRelogin, "log in again"},
+		{actTheme, "next theme"},
+		{actSearch, "search"},
+		{actLike, "like/unlike selected track or album"},
+		{actLikePlaying, "like/unlike playing track"},
+		{actLikeAlbum, "like/unlike album of selected track"},
+		{actAddToQueue, "add selected track to queue"},
+		{actAddToUpNext, "add selected track to Up Next"},
+		{actPlayNext, "play selected track next (Up Next)"},
+		{actClearUpNext, "clear Up Next"},
+		{actHelp, "help"},
+		{actQuit, "quit"},
+	}},
+	{"Navigation", scopeMain, []actionHelp{
+		{actFocusNext, "next panel"},
+		{actFocusPrev, "previous panel"},
+		{actUp, "up"},
+		{actDown, "down"},
+		{actSelect, "open/play"},
+		{actGoTop, "go to top"},
+		{actGoBottom, "go to bottom"},
+		{actGoLiked, "go to Liked Songs"},
+		{actGoPlaylists, "go to playlists"},
+		{actGoQueue, "go to queue"},
+		{actArtist, "open artist of selected track"},
+		{actPlayingArtist, "open artist of playing track"},
+		{actAlbum, "open album of selected track"},
+		{actPlayingAlbum, "open album of playing track"},
+		{actBack, "back"},
+		{actLayoutMode, "cycle layout mode"},
+		{actToggleSidebar, "show/hide sidebar"},
+		{actToggleQueue, "show/hide queue"},
+		{actGrowPanel, "widen panel"},
+		{actShrinkPanel, "narrow panel"},
+	}},
+	{"Playlists", scopeMain, []actionHelp{
+		{actCreatePlaylist, "create playlist"},
+		{actRenamePlaylist, "rename playlist"},
+		{actRemove, "delete playlist (sidebar) / remove track (also Up Next)"},
+		{actAddToPlaylist, "add selected track to playlist"},
+		{actAddPlayingToPlaylist, "add playing track to playlist"},
+		{actMoveUp, "move track up (also Up Next)"},
+		{actMoveDown, "move track down (also Up Next)"},
+		{actUndo, "undo last removal"},
+	}},
+	{"Search", scopeSearch, []actionHelp{
+		{actSearchSubmit, "search/play"},
+		{actSearchCancel, "close search"},
+		{actSearchDelete, "delete character"},
+		{actSearchUp, "previous result"},
+		{actSearchDown, "next result"},
+		{actSearchNextSection, "next section"},
+		{actSearchPrevSection, "previous section"},
+		{actSearchArtist, "open artist of result"},
+		{actSearchAlbum, "open album of result"},
+		{actSearchLike, "like/unlike track/album"},
+		{actSearchAddToPlaylist, "add track to playlist"},
+		{actSearchAddToQueue, "add track/episode to queue"},
+		{actSearchAddToUpNext, "add track/episode to Up Next"},
+	}},
+	{"Pickers", scopePicker, []actionHelp{
+		{actPickerUp, "up"},
+		{actPickerDown, "down"},
+		{actPickerSelect, "select"},
+		{actTransferOnly, "transfer only (devices)"},
+		{actPickerClose, "close"},
+	}},
+}
+
+// reservedKey は常に終了に使うので割り当てられないキー
+const reservedKey = "ctrl+c"
+
+// keyMap は設定ファイルの keys とデフォルト値（config.DefaultKeys）から作ったキー割り当て
+// key.Binding のキーには "g l" のような chord もそのまま入れ、照合は match で行う
+type keyMap struct {
+	bindings map[action]key.Binding
+}
+
+func newKeyMap(bindings map[string][]string) keyMap {
+	km := keyMap{bindings: map[action]key.Binding{}}
+	for _, g := range keyGroups {
+		for _, a := range g.actions {
+			var keys, names []string
+			for _, spec := range bindings[string(a.action)] {
+				seq := parseKeySpec(spec)
+				if len(seq) == 0 {
+					continue
+				}
+				keys = append(keys, strings.Join(seq, "\x00"))
+				names = append(names, keySpecName(seq))
+			}
+			if len(keys) == 0 {
+				km.bindings[a.action] = key.NewBinding(key.WithDisabled())
+				continue
+			}
+			km.bindings[a.action] = key.NewBinding(
+				key.WithKeys(keys...),
+				key.WithHelp(strings.Join(names, "/"), a.help),
+			)
+		}
+	}
+	return km
+}
+
+// parseKeySpec は "g l" や "space" のような設定の表記を、押すキーの並びに変換する
+func parseKeySpec(spec string) []string {
+	seq := strings.Fields(spec)
+	for i, k := range seq {
+		if k == "space" {
+			seq[i] = " "
+		}
+	}
+	return seq
+}
+
+// keySpecName はヘルプに表示するキーの並びの名前を返す
+func keySpecName(seq []string) string {
+	names := make([]string, len(seq))
+	for i, k := range seq {
+		if k == " " {
+			k = "space"
+		}
+		names[i] = k
+	}
+	return strings.Join(names, " ")
+}
+
+func (km keyMap) binding(a action) key.Binding {
+	return km.bindings[a]
+}
+
+// keyIndex は押されたキーの並びが、アクションに割り当てたキーの何番目かを返す（ない場合は -1）
+// seek_percent のように、どのキーで呼び出したかで動作が変わるアクションに使う
+func (km keyMap) keyIndex(a action, seq []string) int {
+	joined := strings.Join(seq, "\x00")
+	for i, k := range km.bindings[a].Keys() {
+		if k == joined {
+			return i
+		}
+	}
+	return -1
+}
+
+// match はスコープ内で、押されたキーの並びに一致するアクションを返す
+// 並びがいずれかの chord の前半に一致する場合は prefix が true になる
+func (km keyMap) match(scope keyScope, seq []string) (a action, prefix bool) {
+	joined := strings.Join(seq, "\x00")
+	for _, g := range keyGroups {
+		if g.scope != scope {
+			continue
+		}
+		for _, ah := range g.actions {
+			b := km.bindings[ah.action]
+			if !b.Enabled() {
+				continue
+			}
+			for _, k := range b.Keys() {
+				if k == joined {
+					return ah.action, false
+				}
+				if strings.HasPrefix(k, joined+"\x00") {
+					prefix = true
+				}
+			}
+		}
+	}
+	return "", prefix
+}
+
+// conflicts はスコープ内で衝突しているキー割り当てを説明する文字列を返す
+// 同じキーの並びを複数のアクションに割り当てた場合と、
+// あるアクションのキーが別のアクションの chord の前半になっている場合が衝突になる
+func (km keyMap) conflicts() []string {
+	type entry struct {
+		action action
+		seq    string
+	}
+	var problems []string
+	for _, scope := range []keyScope{scopeMain, scopeSearch, scopePicker} {
+		var entries []entry
+		for _, g := range keyGroups {
+			if g.scope != scope {
+				continue
+			}
+			for _, ah := range g.actions {
+				for _, k := range km.bindings[ah.action].Keys() {
+					entries = append(entries, entry{ah.action, k})
+				}
+			}
+		}
+
+		for i, a := range entries {
+			name := keySpecName(strings.Split(a.seq, "\x00"))
+			if strings.Split(a.seq, "\x00")[0] == reservedKey {
+				problems = append(problems, fmt.Sprintf("%s: %q is reserved for quitting", a.action, name))
+			}
+			if scope == scopeSearch && len([]rune(a.seq)) == 1 {
+				problems = append(problems, fmt.Sprintf("%s: %q would block typing it in search", a.action, name))
+			}
+			for _, b := range entries[i+1:] {
+				if a.action == b.action {
+					continue
+				}
+				other := keySpecName(strings.Split(b.seq, "\x00"))
+				switch {
+				case a.seq == b.seq:
+					problems = append(problems, fmt.Sprintf("%s and %s are both bound to %q", a.action, b.action, name))
+				case strings.HasPrefix(b.seq, a.seq+"\x00"):
+					problems = append(problems, fmt.Sprintf("%s (%q) is the start of %s (%q)", a.action, name, b.action, other))
+				case strings.HasPrefix(a.seq, b.seq+"\x00"):
+					problems = append(problems, fmt.Sprintf("%s (%q) is the start of %s (%q)", b.action, other, a.action, name))
+				}
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// ValidateKeys は設定ファイルのキー割り当てに衝突がないかを検証する
+// 起動時とアカウント切り替え時に呼び、衝突があればその内容を返す
+func ValidateKeys(bindings map[string][]string) error {
+	problems := newKeyMap(bindings).conflicts()
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("conflicting key bindings:\n  %s", strings.Join(problems, "\n  "))
+}
+
+// ShortHelp はプレイヤーバーに表示する主なキーを返す（help.KeyMap）
+func (km keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		km.binding(actPlayPause),
+		km.binding(actNext),
+		km.binding(actPrevious),
+		km.binding(actFocusNext),
+		km.binding(actSearch),
+		km.binding(actHelp),
+		km.binding(actQuit),
+	}
+}
+
+// FullHelp はヘルプ画面に表示する全てのキーをグループごとに返す（help.KeyMap）
+func (km keyMap) FullHelp() [][]key.Binding {
+	groups := make([][]key.Binding, len(keyGroups))
+	for i, g := range keyGroups {
+		for _, ah := range g.actions {
+			groups[i] = append(groups[i], km.binding(ah.action))
+		}
+	}
+	return groups
+}
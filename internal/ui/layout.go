@@ -1,5 +1,7 @@
 package ui
 
+import "spotify-tui/internal/config"
+
 // Layout constants
 const (
 	// Bottom bar: 4 content lines + 2 border lines = 6 total
@@ -9,11 +11,93 @@ const (
 
 	// Top panel title and spacing
 	topPanelTitleLines = 2 // title + empty line
+
+	// Progress bar: "[" + bar + "] mm:ss / mm:ss" の時間表示部分の幅
+	progressBarReserved = 20
+
+	// panelResizeStep は実行中にパネル幅を変えるときの1回の変化量（全体に対する%）
+	panelResizeStep = 4
+	// minPanelPercent と maxPanelPercent はサイドバーとキューの幅の範囲（%）
+	minPanelPercent = 10
+	maxPanelPercent = 50
+	// minMainPercent はメインパネルの最小の幅（%）
+	minMainPercent = 20
 )
 
+// LayoutMode は画面の構成
+type LayoutMode int
+
+const (
+	// LayoutAuto は端末の大きさから Columns、Stacked、Mini の順に収まるものを選ぶ
+	LayoutAuto LayoutMode = iota
+	// LayoutColumns はサイドバー、メイン、キューを横に並べる
+	LayoutColumns
+	// LayoutStacked はフォーカスのあるパネルだけを全幅で表示し、Tab で切り替える
+	LayoutStacked
+	// LayoutMini はプレイヤーバーだけを表示する
+	LayoutMini
+)
+
+// layoutModes は Auto 以外のモードを大きい順に並べたもの
+var layoutModes = []LayoutMode{LayoutColumns, LayoutStacked, LayoutMini}
+
+// minLayoutSize はモードごとの最小の端末サイズ
+var minLayoutSize = map[LayoutMode]struct{ width, height int }{
+	LayoutColumns: {100, 15},
+	LayoutStacked: {40, 12},
+	LayoutMini:    {progressBarReserved + 10, bottomBarHeight},
+}
+
+func (mode LayoutMode) String() string {
+	switch mode {
+	case LayoutColumns:
+		return config.LayoutColumns
+	case LayoutStacked:
+		return config.LayoutStacked
+	case LayoutMini:
+		return config.LayoutMini
+	}
+	return config.LayoutAuto
+}
+
+// parseLayoutMode は設定ファイルの layout_mode を LayoutMode に変換する
+func parseLayoutMode(s string) LayoutMode {
+	for _, mode := range layoutModes {
+		if mode.String() == s {
+			return mode
+		}
+	}
+	return LayoutAuto
+}
+
+// fits は width x height の端末にモードが収まるかを返す
+func (mode LayoutMode) fits(width, height int) bool {
+	size := minLayoutSize[mode]
+	return width >= size.width && height >= size.height
+}
+
+// resolveLayoutMode は実際に使うモードを返す
+// 指定したモードが収まらないときは、それより小さいモードのうち収まるものを使う
+// どのモードも収まらなければ LayoutAuto を返す
+func resolveLayoutMode(mode LayoutMode, width, height int) LayoutMode {
+	for _, m := range layoutModes {
+		if m < mode {
+			continue
+		}
+		if m.fits(width, height) {
+			return m
+		}
+	}
+	return LayoutAuto
+}
+
 // Layout holds calculated dimensions for UI rendering
 type Layout struct {
+	// Mode は実際に使うモード（LayoutAuto なら端末が小さすぎて何も表示できない）
+	Mode LayoutMode
+
 	// Panel widths (including border)
+	// Stacked ではどのパネルも全幅で、Columns で非表示のパネルは幅0
 	LeftWidth  int
 	MainWidth  int
 	RightWidth int
@@ -29,14 +113,47 @@ type Layout struct {
 	ListHeight          int // height for list components
 	BottomBarHeight     int // total bottom bar height
 	BottomContentHeight int // content area for bottom bar
+
+	// Player bar position (including border)
+	PlayerX     int
+	PlayerWidth int
 }
 
-// CalculateLayout computes all UI dimensions based on terminal size
-func CalculateLayout(width, height int) Layout {
-	// 3:4:3 horizontal split
-	leftWidth := (width * 3) / 10
-	mainWidth := (width * 4) / 10
+// CalculateLayout computes all UI dimensions based on terminal size,
+// the layout mode and the sidebar:main:queue width ratios (3:4:3 by default)
+// サイドバーとキューは比率を0にすると非表示になる
+func CalculateLayout(width, height int, mode LayoutMode, ratios config.PanelRatios) Layout {
+	mode = resolveLayoutMode(mode, width, height)
+	switch mode {
+	case LayoutAuto:
+		return Layout{Mode: LayoutAuto}
+	case LayoutMini:
+		return Layout{
+			Mode:                LayoutMini,
+			BottomBarHeight:     bottomBarHeight,
+			BottomContentHeight: bottomContentLines,
+			PlayerWidth:         width,
+			MainContentWidth:    width - 4,
+		}
+	}
+
+	// Horizontal split by ratios
+	if ratios.Main <= 0 {
+		ratios = config.DefaultPanelRatios
+	}
+	total := ratios.Sidebar + ratios.Main + ratios.Queue
+	leftWidth := (width * ratios.Sidebar) / total
+	mainWidth := (width * ratios.Main) / total
 	rightWidth := width - leftWidth - mainWidth
+	if ratios.Queue <= 0 {
+		mainWidth += rightWidth
+		rightWidth = 0
+	}
+	playerX, playerWidth := leftWidth, mainWidth
+	if mode == LayoutStacked {
+		leftWidth, mainWidth, rightWidth = width, width, width
+		playerX, playerWidth = 0, width
+	}
 
 	// Vertical split - ensure total height never exceeds terminal height
 	topPanelHeight := height - bottomBarHeight
@@ -61,19 +178,39 @@ func CalculateLayout(width, height int) Layout {
 	}
 
 	return Layout{
+		Mode: mode,
+
 		LeftWidth:  leftWidth,
 		MainWidth:  mainWidth,
 		RightWidth: rightWidth,
 
 		// Content width = panel width - border(2) - padding(2)
-		LeftContentWidth:  leftWidth - 4,
+		LeftContentWidth:  max(leftWidth-4, 0),
 		MainContentWidth:  mainWidth - 4,
-		RightContentWidth: rightWidth - 4,
+		RightContentWidth: max(rightWidth-4, 0),
 
 		TopPanelHeight:      topPanelHeight,
 		TopContentHeight:    topContentHeight,
 		ListHeight:          listHeight,
 		BottomBarHeight:     bottomBarHeight,
 		BottomContentHeight: bottomContentLines,
+
+		PlayerX:     playerX,
+		PlayerWidth: playerWidth,
 	}
 }
+
+// progressBarWidth は幅 width のプレイヤーバーに描画するシークバーの長さを返す
+func progressBarWidth(width int) int {
+	return width - progressBarReserved
+}
+
+// ProgressBarBounds はシークバーの画面上の位置（左端のX、Y）と長さを返す
+// マウスクリックでのシークに使う
+func (l Layout) ProgressBarBounds() (x, y, width int) {
+	// player bar: border(1) + padding(1) + "[" の直後から始まる
+	x = l.PlayerX + 1 + 1 + 1
+	// bottom bar: border(1) + track info line(1) の次の行
+	y = l.TopPanelHeight + 1 + 1
+	return x, y, progressBarWidth(l.PlayerWidth - 4)
+}
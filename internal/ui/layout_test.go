@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"testing"
+
+	"spotify-tui/internal/config"
+)
+
+func TestCalculateLayout(t *testing.T) {
+	// columns は Columns での上段の高さ（34）のレイアウト
+	columns := func(left, main, right int) Layout {
+		return Layout{
+			Mode:      LayoutColumns,
+			LeftWidth: left, MainWidth: main, RightWidth: right,
+			LeftContentWidth: max(left-4, 0), MainContentWidth: main - 4, RightContentWidth: max(right-4, 0),
+			TopPanelHeight: 34, TopContentHeight: 32, ListHeight: 30,
+			BottomBarHeight: 6, BottomContentHeight: 4,
+			PlayerX: left, PlayerWidth: main,
+		}
+	}
+	mini := func(width int) Layout {
+		return Layout{Mode: LayoutMini, BottomBarHeight: 6, BottomContentHeight: 4, PlayerWidth: width, MainContentWidth: width - 4}
+	}
+	stacked := func(width, top int) Layout {
+		return Layout{
+			Mode:      LayoutStacked,
+			LeftWidth: width, MainWidth: width, RightWidth: width,
+			LeftContentWidth: width - 4, MainContentWidth: width - 4, RightContentWidth: width - 4,
+			TopPanelHeight: top, TopContentHeight: top - 2, ListHeight: top - 4,
+			BottomBarHeight: 6, BottomContentHeight: 4,
+			PlayerWidth: width,
+		}
+	}
+
+	tests := []struct {
+		name          string
+		width, height int
+		mode          LayoutMode
+		ratios        config.PanelRatios
+		want          Layout
+	}{
+		// モードの選択
+		{"auto wide", 120, 40, LayoutAuto, config.DefaultPanelRatios, columns(36, 48, 36)},
+		{"auto narrow", 99, 40, LayoutAuto, config.DefaultPanelRatios, stacked(99, 34)},
+		{"auto short", 120, 14, LayoutAuto, config.DefaultPanelRatios, stacked(120, 8)},
+		{"auto tiny", 39, 40, LayoutAuto, config.DefaultPanelRatios, mini(39)},
+		{"auto too small", 29, 40, LayoutAuto, config.DefaultPanelRatios, Layout{Mode: LayoutAuto}},
+		{"columns", 120, 40, LayoutColumns, config.DefaultPanelRatios, columns(36, 48, 36)},
+		{"columns narrow", 99, 40, LayoutColumns, config.DefaultPanelRatios, stacked(99, 34)},
+		{"columns short", 100, 14, LayoutColumns, config.DefaultPanelRatios, stacked(100, 8)},
+		{"stacked", 120, 40, LayoutStacked, config.DefaultPanelRatios, stacked(120, 34)},
+		{"stacked narrow", 39, 40, LayoutStacked, config.DefaultPanelRatios, mini(39)},
+		{"stacked short", 120, 11, LayoutStacked, config.DefaultPanelRatios, mini(120)},
+		{"mini", 120, 40, LayoutMini, config.DefaultPanelRatios, mini(120)},
+		{"mini narrow", 29, 40, LayoutMini, config.DefaultPanelRatios, Layout{Mode: LayoutAuto}},
+		{"mini short", 120, 5, LayoutMini, config.DefaultPanelRatios, Layout{Mode: LayoutAuto}},
+
+		// 各モードの最小サイズちょうど
+		{"columns minimum", 100, 15, LayoutColumns, config.DefaultPanelRatios, Layout{
+			Mode:      LayoutColumns,
+			LeftWidth: 30, MainWidth: 40, RightWidth: 30,
+			LeftContentWidth: 26, MainContentWidth: 36, RightContentWidth: 26,
+			TopPanelHeight: 9, TopContentHeight: 7, ListHeight: 5,
+			BottomBarHeight: 6, BottomContentHeight: 4,
+			PlayerX: 30, PlayerWidth: 40,
+		}},
+		{"stacked minimum", 40, 12, LayoutStacked, config.DefaultPanelRatios, stacked(40, 6)},
+		{"mini minimum", 30, 6, LayoutMini, config.DefaultPanelRatios, mini(30)},
+
+		// パネル幅の比率
+		{"equal ratios", 100, 40, LayoutColumns, config.PanelRatios{Sidebar: 1, Main: 1, Queue: 1}, Layout{
+			Mode:      LayoutColumns,
+			LeftWidth: 33, MainWidth: 33, RightWidth: 34,
+			LeftContentWidth: 29, MainContentWidth: 29, RightContentWidth: 30,
+			TopPanelHeight: 34, TopContentHeight: 32, ListHeight: 30,
+			BottomBarHeight: 6, BottomContentHeight: 4,
+			PlayerX: 33, PlayerWidth: 33,
+		}},
+		{"widest side panels", 120, 40, LayoutColumns, config.PanelRatios{Sidebar: maxPanelPercent, Main: minMainPercent, Queue: maxPanelPercent}, columns(50, 20, 50)},
+		{"no queue", 120, 40, LayoutColumns, config.PanelRatios{Sidebar: 3, Main: 4, Queue: 0}, columns(51, 69, 0)},
+		{"no sidebar", 120, 40, LayoutColumns, config.PanelRatios{Sidebar: 0, Main: 4, Queue: 3}, columns(0, 68, 52)},
+		{"main only", 120, 40, LayoutColumns, config.PanelRatios{Main: 1}, columns(0, 120, 0)},
+		{"zero main uses default", 120, 40, LayoutColumns, config.PanelRatios{Sidebar: 3, Main: 0, Queue: 3}, columns(36, 48, 36)},
+		{"zero ratios use default", 120, 40, LayoutColumns, config.PanelRatios{}, columns(36, 48, 36)},
+		{"ratios ignored when stacked", 120, 40, LayoutStacked, config.PanelRatios{Sidebar: 0, Main: 1, Queue: 0}, stacked(120, 34)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateLayout(tt.width, tt.height, tt.mode, tt.ratios)
+			if got != tt.want {
+				t.Errorf("CalculateLayout(%d, %d, %v, %+v)\n got  %+v\n want %+v", tt.width, tt.height, tt.mode, tt.ratios, got, tt.want)
+			}
+			if got.Mode == LayoutColumns && got.LeftWidth+got.MainWidth+got.RightWidth != tt.width {
+				t.Errorf("panel widths %d+%d+%d don't fill the width %d", got.LeftWidth, got.MainWidth, got.RightWidth, tt.width)
+			}
+			if got.TopPanelHeight+got.BottomBarHeight > tt.height {
+				t.Errorf("height %d+%d exceeds the terminal height %d", got.TopPanelHeight, got.BottomBarHeight, tt.height)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	spotifysdk "github.com/zmb3/spotify/v2"
+)
+
+// trackID はトラックのURIからIDを返す（エピソードやローカルファイルなど、トラック以外は空）
+func trackID(uri spotifysdk.URI) spotifysdk.ID {
+	id, ok := strings.CutPrefix(string(uri), "spotify:track:")
+	if !ok {
+		return ""
+	}
+	return spotifysdk.ID(id)
+}
+
+// albumID はアルバムのURIからIDを返す（アルバム以外は空）
+func albumID(uri spotifysdk.URI) spotifysdk.ID {
+	id, ok := strings.CutPrefix(string(uri), "spotify:album:")
+	if !ok {
+		return ""
+	}
+	return spotifysdk.ID(id)
+}
+
+// albumURI はアルバムのIDからURIを返す
+func albumURI(id spotifysdk.ID) spotifysdk.URI {
+	if id == "" {
+		return ""
+	}
+	return spotifysdk.URI("spotify:album:" + string(id))
+}
+
+// libraryEntryURIs は項目のうちライブラリに保存できるもの（トラックとアルバム）のURIを返す
+func libraryEntryURIs(entries []searchEntry) []spotifysdk.URI {
+	var uris []spotifysdk.URI
+	for _, e := range entries {
+		if e.kind == searchTrack || e.kind == searchAlbum {
+			uris = append(uris, e.uri)
+		}
+	}
+	return uris
+}
+
+// checkLiked は保存済みか分からない曲とアルバムを、行ごとではなく種類ごとにまとめて問い合わせる
+// 結果は URI ごとに liked に残し、問い合わせ中の項目は likedPending で重複して問い合わせない
+func (m Model) checkLiked(uris ...spotifysdk.URI) (Model, tea.Cmd) {
+	var trackIDs, albumIDs []spotifysdk.ID
+	var trackURIs, albumURIs []spotifysdk.URI
+	for _, uri := range uris {
+		if m.likedPending[uri] {
+			continue
+		}
+		if _, ok := m.liked[uri]; ok {
+			continue
+		}
+		if id := trackID(uri); id != "" {
+			trackIDs = append(trackIDs, id)
+			trackURIs = append(trackURIs, uri)
+		} else if id := albumID(uri); id != "" {
+			albumIDs = append(albumIDs, id)
+			albumURIs = append(albumURIs, uri)
+		} else {
+			continue
+		}
+		m.likedPending[uri] = true
+	}
+
+	var cmds []tea.Cmd
+	if len(trackIDs) > 0 {
+		cmds = append(cmds, func() tea.Msg {
+			liked, err := m.client.TracksSaved(m.ctx, trackIDs)
+			return likedMsg{uris: trackURIs, liked: liked, err: err}
+		})
+	}
+	if len(albumIDs) > 0 {
+		cmds = append(cmds, func() tea.Msg {
+			liked, err := m.client.AlbumsSaved(m.ctx, albumIDs)
+			return likedMsg{uris: albumURIs, liked: liked, err: err}
+		})
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// setLiked は問い合わせた結果を反映する
+// 問い合わせ中にユーザーが切り替えた項目は、切り替えた状態を優先する
+// 結果を待っていた切り替え（likeQueued）は、保存済みかが分かったところで行う
+func (m Model) setLiked(msg likedMsg) (Model, tea.Cmd) {
+	for i, uri := range msg.uris {
+		if !m.likedPending[uri] {
+			continue
+		}
+		delete(m.likedPending, uri)
+		if msg.err == nil && i < len(msg.liked) {
+			m.liked[uri] = msg.liked[i]
+		}
+	}
+	m = m.refreshLikedItems()
+
+	var cmds []tea.Cmd
+	for _, uri := range msg.uris {
+		if !m.likeQueued[uri] || m.likedPending[uri] {
+			continue
+		}
+		delete(m.likeQueued, uri)
+		if _, ok := m.liked[uri]; ok {
+			var cmd tea.Cmd
+			m, cmd = m.toggleLike(uri)
+			cmds = append(cmds, cmd)
+		}
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// toggleLike は曲を Liked Songs に、アルバムをライブラリに保存する（保存済みなら削除する）
+// 表示はすぐに切り替え、失敗した場合は元に戻す
+// 保存済みか分からないまま切り替えると保存済みの項目をもう一度保存してしまうので、
+// 問い合わせの結果が届いてから切り替える（届く前にもう一度押すと取り消す）
+func (m Model) toggleLike(uri spotifysdk.URI) (Model, tea.Cmd) {
+	if trackID(uri) == "" && albumID(uri) == "" {
+		return m, nil
+	}
+	if _, ok := m.liked[uri]; !ok || m.likedPending[uri] {
+		if m.likeQueued[uri] {
+			delete(m.likeQueued, uri)
+		} else {
+			m.likeQueued[uri] = true
+		}
+		return m.checkLiked(uri)
+	}
+
+	liked := !m.liked[uri]
+	m.liked[uri] = liked
+	m = m.refreshLikedItems()
+	return m, func() tea.Msg {
+		return likeSavedMsg{uri: uri, liked: liked, err: m.saveLiked(uri, liked)}
+	}
+}
+
+// saveLiked は曲やアルバムをライブラリに保存する（liked が false なら削除する）
+func (m Model) saveLiked(uri spotifysdk.URI, liked bool) error {
+	if id := trackID(uri); id != "" {
+		if liked {
+			return m.client.SaveTracks(m.ctx, id)
+		}
+		return m.client.RemoveSavedTracks(m.ctx, id)
+	}
+	id := albumID(uri)
+	if liked {
+		return m.client.SaveAlbums(m.ctx, id)
+	}
+	return m.client.RemoveSavedAlbums(m.ctx, id)
+}
+
+// refreshLikedItems はトラックリストとキューの保存済みの表示を更新する
+func (m Model) refreshLikedItems() Model {
+	selected := m.trackList.Index()
+	items := make([]list.Item, len(m.trackList.Items()))
+	for i, item := range m.trackList.Items() {
+		if t, ok := item.(trackItem); ok {
+			t.liked = m.liked[spotifysdk.URI(t.uri)]
+			item = t
+		}
+		items[i] = item
+	}
+	m.trackList.SetItems(items)
+	m.trackList.Select(selected)
+
+	selected = m.queueList.Index()
+	items = make([]list.Item, len(m.queueList.Items()))
+	for i, item := range m.queueList.Items() {
+		if q, ok := item.(queueItem); ok {
+			q.liked = m.liked[spotifysdk.URI(q.uri)]
+			item = q
+		}
+		items[i] = item
+	}
+	m.queueList.SetItems(items)
+	m.queueList.Select(selected)
+	return m
+}
+
+// selectedTrackURI はフォーカスしているリストで選択中の曲を返す
+// サイドバーにフォーカスがある場合は再生中の曲を返し、曲以外を選んでいる場合は空を返す
+func (m Model) selectedTrackURI() spotifysdk.URI {
+	switch m.focus {
+	case FocusMain:
+		switch m.view {
+		case viewArtist:
+			if section, i, ok := m.artist.entryAt(m.artist.index); ok && section == artistTopTracks {
+				return m.artist.sections[section].entries[i].uri
+			}
+		case viewAlbum:
+			if i := m.album.index; i < len(m.album.tracks) {
+				return m.album.tracks[i].URI
+			}
+		case viewTracks:
+			if item, ok := m.trackList.SelectedItem().(trackItem); ok && item.index < len(m.tracks) {
+				return m.tracks[item.index].Track.URI
+			}
+		}
+		return ""
+	case FocusQueue:
+		if i := m.queueList.Index(); i >= 0 && i < len(m.queue) {
+			return m.queue[i].URI
+		}
+		return ""
+	}
+	return spotifysdk.URI(m.playingTrackURI)
+}
+
+// selectedLibraryURI はフォーカスしているリストで選択中の、ライブラリに保存できる項目を返す
+// アーティストのページのアルバムと保存したアルバムの一覧ではアルバムを、それ以外は selectedTrackURI と同じ曲を返す
+func (m Model) selectedLibraryURI() spotifysdk.URI {
+	if m.focus == FocusMain {
+		switch m.view {
+		case viewArtist:
+			if section, i, ok := m.artist.entryAt(m.artist.index); ok && section != artistRelated {
+				return m.artist.sections[section].entries[i].uri
+			}
+			return ""
+		case viewSavedAlbums:
+			if i := m.savedAlbums.index; i < len(m.savedAlbums.albums.entries) {
+				return m.savedAlbums.albums.entries[i].uri
+			}
+			return ""
+		}
+	}
+	return m.selectedTrackURI()
+}
+
+// likedMark は保存済みの曲の名前の後に付ける印を返す
+func (m Model) likedMark(uri spotifysdk.URI) string {
+	if m.liked[uri] {
+		return " ♥"
+	}
+	return ""
+}
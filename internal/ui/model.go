@@ -2,12 +2,19 @@ package ui
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"time"
 
+	"spotify-tui/internal/auth"
+	"spotify-tui/internal/config"
+	"spotify-tui/internal/logger"
 	"spotify-tui/internal/spotify"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 	spotifysdk "github.com/zmb3/spotify/v2"
 )
 
@@ -19,9 +26,37 @@ const (
 	FocusQueue
 )
 
+// mainView はメインパネルに表示するページ
+type mainView int
+
+const (
+	viewTracks      mainView = iota // サイドバーで開いたプレイリストの曲
+	viewArtist                      // アーティストのページ
+	viewAlbum                       // アルバムのページ
+	viewSavedAlbums                 // 保存したアルバムの一覧
+)
+
+// mainPage は back で戻るために積んでおくメインパネルのページ
+// 保存したアルバムの一覧は1つしかないので、Model の savedAlbums をそのまま使う
+type mainPage struct {
+	view   mainView
+	artist artistPage
+	album  albumPage
+}
+
 type Model struct {
 	ctx    context.Context
-	client *spotify.Client
+	client spotify.Player
+
+	// Account
+	// ctx はアカウントごとに作り直し、切り替え時に古いアカウントのリクエストをキャンセルする
+	baseCtx        context.Context
+	cancelRequests context.CancelFunc
+	profile        string
+	accounts       AccountSwitcher
+	showAccounts   bool
+	accountNames   []string
+	accountIndex   int
 
 	// UI State
 	width  int
@@ -29,8 +64,12 @@ type Model struct {
 	focus  FocusPanel
 
 	// Sidebar
-	playlists     list.Model
-	selectedIndex int
+	playlists      list.Model
+	selectedIndex  int
+	playlistsCount int // 読み込み済みのプレイリスト数（Liked Songsと保存したアルバムを除く）
+	playlistsTotal int
+	// 読み込み直すたびに番号を進め、前の読み込みのページが後から届いても捨てる
+	playlistsLoadID int
 
 	// Main Panel
 	tracks              []spotifysdk.PlaylistTrack
@@ -40,10 +79,43 @@ type Model struct {
 	playingPlaylistName string
 	isLikedSongs        bool
 	loadingTracks       bool
+	tracksTotal         int
 	searchMode          bool
-	searchQuery         string
-	searchResults       []spotifysdk.FullTrack
+	searchInput         textinput.Model
+	searchedQuery       string        // searchResults を検索した検索語
+	searchResults       []searchEntry // セクション（searchSections）の順に並べた検索結果
 	searchIndex         int
+	searchPages         [searchKindCount]searchPage // セクションごとの続きのページ
+
+	// Search-as-you-type
+	// 入力が止まってから searchDebounce 後に検索し、新しい検索を始めたら前の検索を中断する
+	// 検索ごとに番号を進め、最新の番号でない結果は捨てる
+	searchSeq    int
+	searchCancel context.CancelFunc
+
+	// Search history（新しい順、プロファイルごとに保存する）
+	// historyIndex は表示中の履歴の位置で、履歴を見ていないときは -1
+	searchHistory []string
+	historyIndex  int
+	historyDraft  string // 履歴を見る前に入力していた検索語
+
+	// Pages
+	// view がトラックリスト以外の間は、メインパネルにそのページを表示する
+	// アーティストやアルバムを開くと今のページを pageBack に積み、back で戻る
+	// ページの読み込みは読み込みIDで区別し、別のページを開いた後に届いた結果は捨てる
+	view        mainView
+	artist      artistPage
+	album       albumPage
+	savedAlbums savedAlbumsPage
+	pageBack    []mainPage
+	pageLoadID  int
+
+	// Track paging
+	// ページ取得は読み込みごとにIDとcontextを持ち、
+	// 別のプレイリストを開いたらキャンセルして古い結果を捨てる
+	tracksLoadID int
+	tracksCtx    context.Context
+	tracksCancel context.CancelFunc
 
 	// Player State
 	currentTrack    *spotifysdk.PlayerState
@@ -55,99 +127,599 @@ type Model struct {
 	shuffle         bool
 	repeatState     string
 
+	// Seek
+	// キー連打やクリックのたびにAPIを呼ばないよう、最後の操作から
+	// seekDebounce 経過してから1回だけ Seek を送る
+	seekSeq     int
+	seekPending bool
+
+	// Liked Songs
+	// 曲とアルバムごとにライブラリに保存済みかを URI で覚えておき、表示のたびに問い合わせない
+	liked        map[spotifysdk.URI]bool
+	likedPending map[spotifysdk.URI]bool // 問い合わせ中の項目
+	likeQueued   map[spotifysdk.URI]bool // 問い合わせの結果を待ってから切り替える項目
+
+	// Playlist editing
+	// 曲の追加・削除・並べ替えは位置がずれないよう1つずつ送り、応答を待つ間 editPending にする
+	// 最後の削除（曲かプレイリスト）は undo に覚えておき、元に戻せるようにする
+	editPending bool
+	undo        *undoAction
+	notice      string // 編集の結果（noticeDuration 後に消す）
+	noticeSeq   int
+
+	// Name prompt（プレイリストの作成と名前の変更）
+	namePrompt namePromptKind
+	nameInput  textinput.Model
+	renaming   playlistItem
+
+	// Playlist picker（曲を加えるプレイリストの選択）
+	showPlaylistPicker bool
+	pickerPlaylists    []playlistItem
+	pickerIndex        int
+	pickerTrack        spotifysdk.URI
+
 	// Queue
-	queue     []spotifysdk.FullTrack
-	queueList list.Model
+	// queue は表示するキューで、Up Next（upNextEntries）の後に、最後に取得したキュー（queueFetched）の
+	// 追加済みの曲（queueUserAdded）、追加して反映を待っている曲（queuePending）、残りの曲を続けたもの
+	queue          []spotifysdk.FullTrack
+	queueFetched   []spotifysdk.FullTrack
+	queuePending   []pendingQueueItem
+	queueUserAdded []spotifysdk.URI
+	queueSeq       int
+	queueList      list.Model
+	upNext         *spotify.ManagedQueue // アプリが管理する、編集できるキュー
+	upNextEntries  []spotify.QueueEntry
 
 	// Devices
-	devices      []spotifysdk.PlayerDevice
-	activeDevice *spotifysdk.PlayerDevice
+	devices      []spotify.Device
+	activeDevice *spotify.Device
 	volume       int
 
+	// Device picker
+	showDevices bool
+	deviceIndex int
+	deviceRetry tea.Cmd // デバイス選択後に再実行する再生操作
+
+	// Volume
+	// シークと同様に、最後の操作から volumeDebounce 経過してから SetVolume を送る
+	volumeStep    int
+	muted         bool
+	mutedVolume   int // ミュート前の音量
+	volumeSeq     int
+	volumePending bool
+
 	// User
 	user *spotifysdk.PrivateUser
 
+	// Settings（設定ファイルから読み込む）
+	keys        keyMap
+	pendingKeys []string // 入力途中の chord
+	showHelp    bool
+	polling     config.Polling
+	panels      config.PanelRatios
+	startupView string
+
+	// Layout
+	// layoutMode は設定ファイルかキー操作で選んだモードで、実際のモードは layout().Mode
+	layoutMode  LayoutMode
+	hideSidebar bool
+	hideQueue   bool
+
+	// Theme
+	theme         Theme
+	themes        []config.ThemeDef    // テーマ切り替えの候補
+	themeSettings config.ThemeSettings // 設定ファイルの theme（起動時のテーマを選ぶ）
+
 	// Polling intervals
-	lastQueueFetch  time.Time
-	lastDeviceFetch time.Time
+	lastPlaybackFetch time.Time
+	lastQueueFetch    time.Time
+	lastDeviceFetch   time.Time
 
 	// Error
-	err string
+	err             string
+	reloginRequired bool
+	missingScope    bool // トークンに権限がないため再ログインが必要（期限切れではない）
+
+	// Re-login
+	// 認証が切れたときに、TUIを終了せずブラウザでログインし直せるようにする
+	reauth      Reauthenticator
+	loginURL    string // ログイン待ちの間だけ設定される
+	loginCancel context.CancelFunc
+}
+
+// AccountSwitcher はプロファイルの一覧と、プロファイルごとのクライアント作成を提供する
+type AccountSwitcher interface {
+	Profiles() ([]string, error)
+	Open(profile string) (Account, error)
+}
+
+// Account は切り替え先のアカウント
+type Account struct {
+	Config *config.Config
+	Client spotify.Player
+	Reauth Reauthenticator
+	UpNext *spotify.ManagedQueue
+}
+
+// Reauthenticator は実行中に再ログインする手段（auth.Session が実装する）
+// StartLogin は認可ページのURLと、ログインの完了を待つ関数を返す
+type Reauthenticator interface {
+	StartLogin(ctx context.Context) (string, func() error, error)
 }
 
 type tickMsg time.Time
 type playbackMsg *spotifysdk.PlayerState
-type playlistsMsg []spotifysdk.SimplePlaylist
+type playlistsMsg struct {
+	loadID    int
+	playlists []spotifysdk.SimplePlaylist
+	offset    int
+	total     int
+	err       error // ページの取得に失敗した場合
+}
 type tracksMsg struct {
-	tracks      []spotifysdk.PlaylistTrack
-	playlistURI spotifysdk.URI
+	loadID     int
+	tracks     []spotifysdk.PlaylistTrack
+	playlistID spotifysdk.ID
+	offset     int
+	total      int
+	err        error // ページの取得に失敗した場合
+}
+type savedTracksMsg struct {
+	loadID int
+	tracks []spotifysdk.SavedTrack
+	err    error
+}
+type searchResultsMsg struct {
+	seq     int
+	query   string
+	results *spotify.SearchResults
+}
+type searchMoreMsg struct {
+	seq     int
+	kind    searchKind
+	results *spotify.SearchResults
+	err     error
 }
-type savedTracksMsg []spotifysdk.SavedTrack
-type searchResultsMsg []spotifysdk.FullTrack
+type searchFireMsg int
+type searchHistoryMsg []string
+
+type artistPageMsg struct {
+	loadID int
+	page   artistPage
+	err    error // 読み込みに失敗した場合
+}
+type artistAlbumsMsg struct {
+	loadID  int
+	section artistSection
+	albums  []spotifysdk.SimpleAlbum
+	offset  int
+	total   int
+	err     error
+}
+type albumPageMsg struct {
+	loadID int
+	page   albumPage
+	err    error // 読み込みに失敗した場合
+}
+type savedAlbumsMsg struct {
+	loadID int
+	albums []spotifysdk.SavedAlbum
+	offset int
+	total  int
+	err    error
+}
+type likedMsg struct {
+	uris  []spotifysdk.URI
+	liked []bool
+	err   error
+}
+type likeSavedMsg struct {
+	uri   spotifysdk.URI
+	liked bool
+	err   error
+}
+type playlistEditedMsg struct {
+	edit       playlistEdit
+	snapshotID string
+	err        error
+}
+type playlistCreatedMsg struct {
+	playlist *spotifysdk.FullPlaylist
+	err      error
+}
+type playlistRenamedMsg struct {
+	id   string
+	name string
+	err  error
+}
+type playlistDeletedMsg struct {
+	item playlistItem
+	err  error
+}
+type playlistRestoredMsg struct {
+	item playlistItem
+	err  error
+}
+type noticeClearMsg int
 type userMsg *spotifysdk.PrivateUser
-type queueMsg *spotifysdk.Queue
-type devicesMsg []spotifysdk.PlayerDevice
+type queueMsg struct {
+	queue       *spotifysdk.Queue
+	requestedAt time.Time
+}
+type queueAddMsg spotifysdk.FullTrack
+type queueAddedMsg struct {
+	id    int
+	track spotifysdk.FullTrack
+	err   error
+}
+type upNextLoadedMsg []spotify.QueueEntry
+type upNextStartedMsg spotify.QueueEntry
+type devicesMsg []spotify.Device
 type errorMsg string
+type reloginRequiredMsg struct {
+	missingScope bool
+}
+type accountsMsg []string
+type accountSwitchedMsg Account
+type loginStartedMsg struct {
+	url  string
+	wait func() error
+}
+type loginDoneMsg struct {
+	err error
+}
+type seekFireMsg int
+type seekDoneMsg struct {
+	seq int // 送信したシーク操作の番号（seekSeq）
+	err error
+}
+type volumeFireMsg int
+type volumeDoneMsg struct {
+	seq int // 送信した音量変更の番号（volumeSeq）
+	err error
+}
+type noActiveDeviceMsg struct {
+	retry tea.Cmd
+}
+type deviceTransferredMsg struct {
+	retry tea.Cmd
+}
+
+const (
+	// seekDebounce はシーク操作をまとめる待ち時間
+	seekDebounce = 300 * time.Millisecond
+	// volumeDebounce は音量操作をまとめる待ち時間
+	volumeDebounce = 200 * time.Millisecond
+	// searchDebounce は検索語の入力が止まってから検索するまでの待ち時間
+	searchDebounce = 300 * time.Millisecond
+)
 
-func NewModel(ctx context.Context, client *spotify.Client) Model {
-	delegate := list.NewDefaultDelegate()
-	delegate.SetSpacing(0) // アイテム間のスペースを0に
+func NewModel(ctx context.Context, client spotify.Player, cfg *config.Config) Model {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	// 組み込みのテーマだけで選び、ユーザー定義のテーマは WithThemes で渡す
+	themeDef, err := cfg.Theme.Resolve(config.BuiltinThemes)
+	if err != nil {
+		themeDef = config.BuiltinThemes[0]
+	}
+	theme := NewTheme(themeDef)
 
-	playlistList := list.New([]list.Item{}, delegate, 0, 0)
+	playlistList := list.New([]list.Item{}, newPlaylistDelegate(theme), 0, 0)
 	playlistList.SetShowHelp(false)
 	playlistList.SetFilteringEnabled(false)
 	playlistList.SetShowStatusBar(false)
 	playlistList.SetShowTitle(false)
 
-	trackDelegate := NewTrackDelegate()
+	trackDelegate := NewTrackDelegate(theme)
 	trackList := list.New([]list.Item{}, trackDelegate, 0, 0)
 	trackList.SetShowHelp(false)
 	trackList.SetFilteringEnabled(false)
 	trackList.SetShowStatusBar(false)
 	trackList.SetShowTitle(false)
 
-	queueDelegate := NewQueueDelegate()
+	queueDelegate := NewQueueDelegate(theme)
 	queueList := list.New([]list.Item{}, queueDelegate, 0, 0)
 	queueList.SetShowHelp(false)
 	queueList.SetFilteringEnabled(false)
 	queueList.SetShowStatusBar(false)
 	queueList.SetShowTitle(false)
 
-	return Model{
-		ctx:         ctx,
-		client:      client,
-		focus:       FocusSidebar,
-		playlists:   playlistList,
-		trackList:   trackList,
-		queueList:   queueList,
-		lastUpdate:  time.Now(),
-		repeatState: "off",
+	keys := newKeyMap(cfg.KeyBindings())
+
+	requestCtx, cancel := context.WithCancel(ctx)
+	m := Model{
+		ctx:            requestCtx,
+		client:         client,
+		baseCtx:        ctx,
+		cancelRequests: cancel,
+		profile:        cfg.Profile(),
+		focus:          FocusSidebar,
+		playlists:      playlistList,
+		trackList:      trackList,
+		queueList:      queueList,
+		lastUpdate:     time.Now(),
+		repeatState:    "off",
+		volumeStep:     volumeStep(cfg),
+		keys:           keys,
+		polling:        cfg.PollingIntervals(),
+		panels:         cfg.Panels(),
+		startupView:    cfg.Startup(),
+		layoutMode:     parseLayoutMode(cfg.StartupLayout()),
+		theme:          theme,
+		themes:         config.BuiltinThemes,
+		themeSettings:  cfg.Theme,
+		searchInput:    newSearchInput(keys),
+		nameInput:      newNameInput(keys),
+		historyIndex:   -1,
+		liked:          make(map[spotifysdk.URI]bool),
+		likedPending:   make(map[spotifysdk.URI]bool),
+		likeQueued:     make(map[spotifysdk.URI]bool),
+		upNext:         spotify.NewManagedQueue(client, "", nil),
+	}
+	m = m.setTheme(themeDef)
+	switch m.startupView {
+	case config.StartupSearch:
+		m = m.openSearch()
+	case config.StartupQueue:
+		m.focus = FocusQueue
 	}
+	return m
+}
+
+// WithReauthenticator は再ログインの手段を設定した Model を返す
+// 設定しない場合（デモモードなど）は再ログインを提供しない
+func (m Model) WithReauthenticator(r Reauthenticator) Model {
+	m.reauth = r
+	return m
+}
+
+// WithUpNext は Up Next に使う管理キューを設定した Model を返す
+// 設定しない場合（デモモードなど）は Up Next を保存しない
+func (m Model) WithUpNext(q *spotify.ManagedQueue) Model {
+	m.upNext = q
+	return m
+}
+
+// WithThemes は切り替えられるテーマを設定した Model を返す
+// 起動時のテーマも themes から選び直すので、ユーザー定義のテーマも theme.name に使える
+func (m Model) WithThemes(themes []config.ThemeDef) Model {
+	m.themes = themes
+	if def, err := m.themeSettings.Resolve(themes); err == nil {
+		m = m.setTheme(def)
+	}
+	return m
+}
+
+// WithAccountSwitcher はアカウント切り替えを有効にした Model を返す
+func (m Model) WithAccountSwitcher(s AccountSwitcher) Model {
+	m.accounts = s
+	return m
+}
+
+// switchAccount は切り替え先のアカウントで Model を作り直す
+// 画面サイズと切り替え手段だけを引き継ぎ、プレイリストやキューなどは読み込み直す
+func (m Model) switchAccount(account Account) (Model, tea.Cmd) {
+	m.cancelRequests()
+	if m.loginCancel != nil {
+		m.loginCancel()
+	}
+
+	next := NewModel(m.baseCtx, account.Client, account.Config).WithThemes(m.themes)
+	next.accounts = m.accounts
+	next.reauth = account.Reauth
+	if account.UpNext != nil {
+		next.upNext = account.UpNext
+	}
+	if m.width > 0 {
+		updated, _ := next.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+		next = updated.(Model)
+	}
+	logger.Info("Switched account", "profile", next.profile)
+
+	// tickCmd は既に動いているので、Init のうちデータ取得だけを行う
+	return next, tea.Batch(
+		next.fetchPlaylistsPage(0),
+		next.fetchUser(),
+		next.fetchCurrentPlayback(),
+		next.fetchQueue(),
+		next.fetchDevices(),
+		next.loadSearchHistory(),
+		next.loadUpNext(),
+	)
+}
+
+func (m Model) fetchAccounts() tea.Cmd {
+	return func() tea.Msg {
+		names, err := m.accounts.Profiles()
+		if err != nil {
+			return errorMsg(err.Error())
+		}
+		return accountsMsg(names)
+	}
+}
+
+func (m Model) openAccount(profile string) tea.Cmd {
+	return func() tea.Msg {
+		account, err := m.accounts.Open(profile)
+		if err != nil {
+			return errorMsg(err.Error())
+		}
+		return accountSwitchedMsg(account)
+	}
+}
+
+func volumeStep(cfg *config.Config) int {
+	if cfg.VolumeStep <= 0 {
+		return config.DefaultVolumeStep
+	}
+	return cfg.VolumeStep
 }
 
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
-		m.fetchPlaylists(),
+		m.fetchPlaylistsPage(0),
 		m.fetchUser(),
 		m.fetchQueue(),
 		m.fetchDevices(),
-		tickCmd(),
+		m.loadSearchHistory(),
+		m.loadUpNext(),
+		m.tickCmd(),
 	)
 }
 
-func tickCmd() tea.Cmd {
-	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
+// tickInterval は1秒、再生状態のポーリングがそれより短い場合はその間隔
+func (m Model) tickInterval() time.Duration {
+	if p := time.Duration(m.polling.Playback); p < time.Second {
+		return p
+	}
+	return time.Second
+}
+
+// tick は d 後に fn のメッセージを送るコマンドを返す
+// テストでは遅らせたメッセージを送らないよう差し替える
+var tick = tea.Tick
+
+func (m Model) tickCmd() tea.Cmd {
+	return tick(m.tickInterval(), func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
 
-func (m Model) fetchPlaylists() tea.Cmd {
+// due は前回の取得からポーリング間隔が経過したかを返す
+// tick の揺れで1回分遅れないよう、半 tick 分の余裕を持たせる
+func (m Model) due(last time.Time, interval config.Duration) bool {
+	return time.Since(last)+m.tickInterval()/2 >= time.Duration(interval)
+}
+
+// focusedList はフォーカスしているパネルのリストを返す
+func (m *Model) focusedList() *list.Model {
+	switch m.focus {
+	case FocusMain:
+		return &m.trackList
+	case FocusQueue:
+		return &m.queueList
+	}
+	return &m.playlists
+}
+
+func (m Model) layout() Layout {
+	ratios := m.panels
+	if m.hideSidebar {
+		ratios.Sidebar = 0
+	}
+	if m.hideQueue {
+		ratios.Queue = 0
+	}
+	return CalculateLayout(m.width, m.height, m.layoutMode, ratios)
+}
+
+// resizeLists はレイアウトに合わせてリストの大きさを変える
+// 画面サイズが変わったときと、レイアウトをキーで変えたときに呼ぶ
+func (m Model) resizeLists() Model {
+	layout := m.layout()
+	m.playlists.SetSize(layout.LeftContentWidth, layout.ListHeight)
+	m.trackList.SetSize(layout.MainContentWidth, layout.ListHeight)
+	m.queueList.SetSize(layout.RightContentWidth, layout.ListHeight)
+	// カーソルの1文字分を空けて、入力欄がパネルの幅に収まるようにする
+	m.searchInput.Width = max(layout.MainContentWidth-lipgloss.Width(m.searchInput.Prompt)-1, 1)
+	return m
+}
+
+// visiblePanels は Tab で移動できるパネルを順に返す
+func (m Model) visiblePanels() []FocusPanel {
+	panels := make([]FocusPanel, 0, 3)
+	if !m.hideSidebar {
+		panels = append(panels, FocusSidebar)
+	}
+	panels = append(panels, FocusMain)
+	if !m.hideQueue {
+		panels = append(panels, FocusQueue)
+	}
+	return panels
+}
+
+// cycleFocus は表示しているパネルの中で、フォーカスを step だけ移動する
+func (m Model) cycleFocus(step int) Model {
+	panels := m.visiblePanels()
+	for i, p := range panels {
+		if p == m.focus {
+			m.focus = panels[(i+step+len(panels))%len(panels)]
+			return m
+		}
+	}
+	m.focus = FocusMain
+	return m
+}
+
+// togglePanel はサイドバーかキューの表示を切り替える
+// 隠したパネルにフォーカスがあった場合はメインパネルに移す
+func (m Model) togglePanel(panel FocusPanel) Model {
+	switch panel {
+	case FocusSidebar:
+		m.hideSidebar = !m.hideSidebar
+	case FocusQueue:
+		m.hideQueue = !m.hideQueue
+	}
+	if !slices.Contains(m.visiblePanels(), m.focus) {
+		m.focus = FocusMain
+	}
+	return m.resizeLists()
+}
+
+// showPanel は隠しているパネルを表示してフォーカスする
+func (m Model) showPanel(panel FocusPanel) Model {
+	switch panel {
+	case FocusSidebar:
+		m.hideSidebar = false
+	case FocusQueue:
+		m.hideQueue = false
+	}
+	m.focus = panel
+	return m.resizeLists()
+}
+
+// resizePanel はフォーカスのあるパネルの幅を delta（全体に対する%）だけ変える
+// サイドバーとキューはメインパネルとの間で、メインパネルは両側のパネルとの間で幅をやり取りする
+func (m Model) resizePanel(delta int) Model {
+	r := m.panels
+	total := r.Sidebar + r.Main + r.Queue
+	sidebar, queue := r.Sidebar*100/total, r.Queue*100/total
+	switch m.focus {
+	case FocusSidebar:
+		sidebar += delta
+	case FocusQueue:
+		queue += delta
+	case FocusMain:
+		sidebar -= delta / 2
+		queue -= delta - delta/2
+	}
+	if sidebar < minPanelPercent || sidebar > maxPanelPercent ||
+		queue < minPanelPercent || queue > maxPanelPercent ||
+		100-sidebar-queue < minMainPercent {
+		return m
+	}
+	m.panels = config.PanelRatios{Sidebar: sidebar, Main: 100 - sidebar - queue, Queue: queue}
+	return m.resizeLists()
+}
+
+// reloadPlaylists はサイドバーのプレイリストを最初のページから読み込み直す
+func (m Model) reloadPlaylists() (Model, tea.Cmd) {
+	m.playlistsLoadID++
+	return m, m.fetchPlaylistsPage(0)
+}
+
+func (m Model) fetchPlaylistsPage(offset int) tea.Cmd {
+	loadID := m.playlistsLoadID
 	return func() tea.Msg {
-		playlists, err := m.client.UserPlaylists(m.ctx)
-		if err != nil {
-			return errorMsg(err.Error())
+		playlists, total, err := m.client.UserPlaylistsPage(m.ctx, offset)
+		return playlistsMsg{
+			loadID:    loadID,
+			playlists: playlists,
+			offset:    offset,
+			total:     total,
+			err:       err,
 		}
-		return playlistsMsg(playlists)
 	}
 }
 
@@ -155,83 +727,307 @@ func (m Model) fetchCurrentPlayback() tea.Cmd {
 	return func() tea.Msg {
 		state, err := m.client.PlayerState(m.ctx)
 		if err != nil {
-			return errorMsg(err.Error())
+			return apiError(err)
 		}
 		return playbackMsg(state)
 	}
 }
 
-func (m Model) fetchPlaylistTracks(playlistID spotifysdk.ID) tea.Cmd {
+func (m Model) fetchPlaylistTracks(playlistID spotifysdk.ID, offset int) tea.Cmd {
+	ctx, loadID := m.tracksCtx, m.tracksLoadID
 	return func() tea.Msg {
-		tracks, err := m.client.PlaylistTracks(m.ctx, playlistID)
+		tracks, total, err := m.client.PlaylistTracksPage(ctx, playlistID, offset)
 		if err != nil {
-			return errorMsg(err.Error())
+			if ctx.Err() != nil {
+				// 別のプレイリストに切り替えられたのでエラーにしない
+				return nil
+			}
+			return tracksMsg{loadID: loadID, playlistID: playlistID, offset: offset, err: err}
 		}
-		// プレイリストのURIを構築
-		playlistURI := spotifysdk.URI("spotify:playlist:" + string(playlistID))
 		return tracksMsg{
-			tracks:      tracks,
-			playlistURI: playlistURI,
+			loadID:     loadID,
+			tracks:     tracks,
+			playlistID: playlistID,
+			offset:     offset,
+			total:      total,
+		}
+	}
+}
+
+// openPlaylist はサイドバーのプレイリスト（または Liked Songs、保存したアルバム）を開く
+func (m Model) openPlaylist(item playlistItem) (Model, tea.Cmd) {
+	// アーティストやアルバムのページから開いたのではないので、back で戻るページはなくなる
+	// 読み込み中だったページの結果も捨てる
+	m.view = viewTracks
+	m.pageBack = nil
+	m.pageLoadID++
+	if item.id == "albums" {
+		return m.openSavedAlbums()
+	}
+	m = m.startTracksLoad(item.name)
+	if item.id == "liked" {
+		return m, m.fetchSavedTracks()
+	}
+	return m, m.fetchPlaylistTracks(spotifysdk.ID(item.id), 0)
+}
+
+// pushPage は back で戻れるように表示中のページを pageBack に積む
+// トラックリストは別のページを開いても残るので積まない
+func (m Model) pushPage() Model {
+	if m.view != viewTracks {
+		m.pageBack = append(m.pageBack, mainPage{view: m.view, artist: m.artist, album: m.album})
+	}
+	return m
+}
+
+// goBack は前のページに戻る（なければトラックリストに戻る）
+// 読み込みの途中で離れたページは読み込み直す
+func (m Model) goBack() (Model, tea.Cmd) {
+	n := len(m.pageBack)
+	if n == 0 {
+		// 保存したアルバムの一覧はサイドバーから開いたページなので、そのまま表示する
+		if m.view != viewSavedAlbums {
+			m.view = viewTracks
+		}
+		return m, nil
+	}
+	page := m.pageBack[n-1]
+	m.pageBack = m.pageBack[:n-1]
+	m.view = page.view
+	m.focus = FocusMain
+	m.pageLoadID++
+	switch page.view {
+	case viewArtist:
+		for s := range page.artist.sections {
+			page.artist.sections[s].page.loading = false
+		}
+		if page.artist.loading || page.artist.failed {
+			return m.loadArtist(page.artist)
+		}
+		m.artist = page.artist
+	case viewAlbum:
+		if page.album.loading || page.album.failed {
+			return m.loadAlbum(page.album)
+		}
+		m.album = page.album
+	case viewSavedAlbums:
+		m.savedAlbums.albums.page.loading = false
+		if m.savedAlbums.loading {
+			return m.openSavedAlbums()
 		}
 	}
+	return m, nil
+}
+
+// pageSelection は表示中のページの選択位置と項目数を返す
+func (m Model) pageSelection() (int, int) {
+	switch m.view {
+	case viewArtist:
+		return m.artist.index, m.artist.count()
+	case viewAlbum:
+		return m.album.index, len(m.album.tracks)
+	case viewSavedAlbums:
+		return m.savedAlbums.index, len(m.savedAlbums.albums.entries)
+	}
+	return 0, 0
+}
+
+// movePageSelection は表示中のページの選択を index に移動する
+func (m Model) movePageSelection(index int) (Model, tea.Cmd) {
+	switch m.view {
+	case viewArtist:
+		return m.moveArtistSelection(index)
+	case viewAlbum:
+		m.album.index = max(min(index, len(m.album.tracks)-1), 0)
+	case viewSavedAlbums:
+		return m.moveSavedAlbumsSelection(index)
+	}
+	return m, nil
+}
+
+// openPageEntry は表示中のページで選択した項目を開く
+func (m Model) openPageEntry() (Model, tea.Cmd) {
+	switch m.view {
+	case viewArtist:
+		return m.openArtistEntry()
+	case viewAlbum:
+		return m, m.playAlbumTrack(m.album.index)
+	case viewSavedAlbums:
+		if i := m.savedAlbums.index; i < len(m.savedAlbums.albums.entries) {
+			return m.openAlbum(m.savedAlbums.albums.entries[i].id)
+		}
+	}
+	return m, nil
+}
+
+// startTracksLoad はメインパネルに name の曲を読み込む準備をする
+// 読み込み中の別プレイリストのページ取得を中断し、古い結果を捨てられるよう読み込みIDを進める
+func (m Model) startTracksLoad(name string) Model {
+	if m.tracksCancel != nil {
+		m.tracksCancel()
+	}
+	m.tracksCtx, m.tracksCancel = context.WithCancel(m.ctx)
+	m.tracksLoadID++
+	m.loadingTracks = true
+	m.currentPlaylistName = name
+	return m
+}
+
+// tracksLoadFailed は曲の読み込みをやめて、読み込み中の表示を消す
+// 最初のページから読み込めなかった場合は、前に開いていたプレイリストの曲も表示しない
+func (m Model) tracksLoadFailed(offset int, err error) (Model, tea.Cmd) {
+	if offset == 0 {
+		m.tracks = nil
+		m.trackList.SetItems(nil)
+		m.currentPlaylistURI = ""
+		m.isLikedSongs = false
+	}
+	m.loadingTracks = false
+	m.tracksTotal = len(m.tracks)
+	return m, func() tea.Msg { return apiError(err) }
 }
 
 func (m Model) fetchSavedTracks() tea.Cmd {
+	ctx, loadID := m.tracksCtx, m.tracksLoadID
 	return func() tea.Msg {
-		tracks, err := m.client.SavedTracks(m.ctx)
+		tracks, err := m.client.SavedTracks(ctx)
 		if err != nil {
-			return errorMsg(err.Error())
+			if ctx.Err() != nil {
+				return nil
+			}
+			return savedTracksMsg{loadID: loadID, err: err}
+		}
+		return savedTracksMsg{
+			loadID: loadID,
+			tracks: tracks,
 		}
-		return savedTracksMsg(tracks)
+	}
+}
+
+// startLogin はログイン用のコールバックサーバーを起動する
+func (m Model) startLogin(ctx context.Context) tea.Cmd {
+	return func() tea.Msg {
+		url, wait, err := m.reauth.StartLogin(ctx)
+		if err != nil {
+			return loginDoneMsg{err: err}
+		}
+		return loginStartedMsg{url: url, wait: wait}
+	}
+}
+
+func waitLogin(wait func() error) tea.Cmd {
+	return func() tea.Msg {
+		return loginDoneMsg{err: wait()}
 	}
 }
 
 type playStartedMsg string
 
+// contextLostMsg はキューの曲へ飛んだために、再生していたコンテキストから外れたことを表す
+type contextLostMsg struct {
+	track   string
+	context string // 外れたコンテキストの表示名
+}
+
+// apiError はAPIエラーをメッセージに変換する
+// トークンを更新できない場合は同じエラーを繰り返し表示せず、再ログインが必要な状態にする
+func apiError(err error) tea.Msg {
+	if errors.Is(err, context.Canceled) {
+		// アカウント切り替えなどで意図的にキャンセルしたリクエスト
+		return nil
+	}
+	if errors.Is(err, auth.ErrReloginRequired) {
+		return reloginRequiredMsg{}
+	}
+	if spotify.IsInsufficientScope(err) {
+		// 権限を追加する前にログインしたトークンなので、ログインし直して権限を付与してもらう
+		return reloginRequiredMsg{missingScope: true}
+	}
+	return errorMsg(err.Error())
+}
+
+// playerError は再生操作のエラーをメッセージに変換する
+// アクティブなデバイスがない場合はデバイス選択を開き、選択後に retry を再実行する
+func playerError(err error, retry tea.Cmd) tea.Msg {
+	if spotify.IsNoActiveDevice(err) {
+		return noActiveDeviceMsg{retry: retry}
+	}
+	return apiError(err)
+}
+
 func (m Model) playTrackInPlaylist(offset int) tea.Cmd {
 	playlistName := m.currentPlaylistName
-	return func() tea.Msg {
-		// Liked Songsの場合はURIリストで再生
-		if m.isLikedSongs {
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
+		// Liked Songsなど、位置を指定できるコンテキストがない場合はURIリストで再生
+		if m.currentPlaylistURI == "" {
 			uris := make([]spotifysdk.URI, len(m.tracks))
 			for i, track := range m.tracks {
 				uris[i] = track.Track.URI
 			}
 			if err := m.client.PlayTrackFromURIList(m.ctx, uris, offset); err != nil {
-				return errorMsg(err.Error())
+				return playerError(err, cmd)
 			}
 			return playStartedMsg(playlistName)
 		}
 
-		// 通常のプレイリストはコンテキストで再生
-		if m.currentPlaylistURI == "" {
-			return errorMsg("No playlist context")
-		}
+		// プレイリストとアルバムはコンテキストで再生
 		if err := m.client.PlayTrackInContext(m.ctx, m.currentPlaylistURI, offset); err != nil {
-			return errorMsg(err.Error())
+			return playerError(err, cmd)
 		}
 		return playStartedMsg(playlistName)
 	}
+	return cmd
+}
+
+// playContext はプレイリストやアルバムなどのコンテキストを先頭から再生する
+func (m Model) playContext(uri spotifysdk.URI, name string) tea.Cmd {
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
+		if err := m.client.PlayContext(m.ctx, uri); err != nil {
+			return playerError(err, cmd)
+		}
+		return playStartedMsg(name)
+	}
+	return cmd
 }
 
 func (m Model) playTrackAlone(uri spotifysdk.URI) tea.Cmd {
-	return func() tea.Msg {
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
 		if err := m.client.PlayTrackAlone(m.ctx, uri); err != nil {
-			return errorMsg(err.Error())
+			return playerError(err, cmd)
 		}
 		return nil
 	}
+	return cmd
 }
 
+// skipToQueueIndex はキューの index 番目の曲から再生する
+// 表示しているキューは古いことがあるので、位置と一緒に曲を渡して取得し直したキューで確かめる
 func (m Model) skipToQueueIndex(index int) tea.Cmd {
-	return func() tea.Msg {
-		// index 0 = 現在再生中の次の曲なので、index+1回スキップする
-		skipCount := index + 1
-		if err := m.client.SkipToNth(m.ctx, skipCount); err != nil {
-			return errorMsg(err.Error())
+	if index < len(m.upNextEntries) {
+		return m.playUpNext(index)
+	}
+	if index >= len(m.queue) {
+		return nil
+	}
+	// Up Next の曲は Spotify のキューにはない
+	target := m.queue[index]
+	index -= len(m.upNextEntries)
+	contextName := m.playingContextName()
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
+		lost, err := m.client.SkipToQueueItem(m.ctx, index, target.URI)
+		if err != nil {
+			return playerError(err, cmd)
+		}
+		if lost {
+			return contextLostMsg{track: target.Name, context: contextName}
 		}
 		return nil
 	}
+	return cmd
 }
 
 func (m Model) togglePlayPause() tea.Cmd {
@@ -243,16 +1039,22 @@ func (m Model) togglePlayPause() tea.Cmd {
 			err = m.client.Play(m.ctx)
 		}
 		if err != nil {
-			return errorMsg(err.Error())
+			// デバイス選択後は TransferPlayback で再生を開始するので再実行しない
+			return playerError(err, nil)
 		}
 		return nil
 	}
 }
 
+// nextTrack は次の曲へ進む（Up Next に曲があればその先頭の曲）
 func (m Model) nextTrack() tea.Cmd {
 	return func() tea.Msg {
-		if err := m.client.Next(m.ctx); err != nil {
-			return errorMsg(err.Error())
+		entry, err := m.upNext.Next(m.ctx)
+		if err != nil {
+			return apiError(err)
+		}
+		if entry != nil {
+			return upNextStartedMsg(*entry)
 		}
 		return nil
 	}
@@ -261,23 +1063,122 @@ func (m Model) nextTrack() tea.Cmd {
 func (m Model) previousTrack() tea.Cmd {
 	return func() tea.Msg {
 		if err := m.client.Previous(m.ctx); err != nil {
-			return errorMsg(err.Error())
+			return apiError(err)
 		}
 		return nil
 	}
 }
 
-func (m Model) performSearch(query string) tea.Cmd {
+// seekTo は再生位置を楽観的に更新し、遅延させた Seek をスケジュールする
+func (m Model) seekTo(position time.Duration) (Model, tea.Cmd) {
+	if m.currentTrack == nil || m.duration == 0 {
+		return m, nil
+	}
+	if position < 0 {
+		position = 0
+	}
+	if position > m.duration {
+		position = m.duration
+	}
+	m.progress = position
+	m.lastUpdate = time.Now()
+	m.seekSeq++
+	m.seekPending = true
+	seq := m.seekSeq
+	return m, tick(seekDebounce, func(time.Time) tea.Msg {
+		return seekFireMsg(seq)
+	})
+}
+
+func (m Model) sendSeek(seq int, position time.Duration) tea.Cmd {
 	return func() tea.Msg {
-		if query == "" {
-			return searchResultsMsg([]spotifysdk.FullTrack{})
+		return seekDoneMsg{seq: seq, err: m.client.Seek(m.ctx, position)}
+	}
+}
+
+// setVolume は音量を楽観的に更新し、遅延させた SetVolume をスケジュールする
+func (m Model) setVolume(volume int) (Model, tea.Cmd) {
+	if m.activeDevice == nil {
+		return m, nil
+	}
+	if !m.activeDevice.SupportsVolume {
+		name := m.activeDevice.Name
+		return m, func() tea.Msg {
+			return errorMsg("Volume control is not supported on " + name)
+		}
+	}
+	if volume < 0 {
+		volume = 0
+	}
+	if volume > 100 {
+		volume = 100
+	}
+	m.volume = volume
+	m.muted = false
+	m.volumeSeq++
+	m.volumePending = true
+	seq := m.volumeSeq
+	return m, tick(volumeDebounce, func(time.Time) tea.Msg {
+		return volumeFireMsg(seq)
+	})
+}
+
+// toggleMute はミュートを切り替える
+// ミュート解除時はミュート前の音量に戻す
+func (m Model) toggleMute() (Model, tea.Cmd) {
+	if m.muted {
+		volume := m.mutedVolume
+		if volume == 0 {
+			volume = m.volumeStep
 		}
+		return m.setVolume(volume)
+	}
+	previous := m.volume
+	m, cmd := m.setVolume(0)
+	if m.volumePending && m.volume == 0 {
+		m.muted = true
+		m.mutedVolume = previous
+	}
+	return m, cmd
+}
+
+func (m Model) sendVolume(seq, volume int) tea.Cmd {
+	return func() tea.Msg {
+		return volumeDoneMsg{seq: seq, err: m.client.SetVolume(m.ctx, volume)}
+	}
+}
 
-		results, err := m.client.Search(m.ctx, query)
+// transferPlayback は選択したデバイスへ再生を移す
+// retry がある場合は移した後に再生操作をやり直すので、ここでは再生を開始しない
+func (m Model) transferPlayback(deviceID spotifysdk.ID, play bool, retry tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		if err := m.client.TransferPlayback(m.ctx, deviceID, play && retry == nil); err != nil {
+			return apiError(err)
+		}
+		return deviceTransferredMsg{retry: retry}
+	}
+}
+
+func (m Model) performSearch(ctx context.Context, seq int, query string) tea.Cmd {
+	return func() tea.Msg {
+		results, err := m.client.Search(ctx, query)
 		if err != nil {
-			return errorMsg(err.Error())
+			if ctx.Err() != nil {
+				// 新しい検索に置き換えられたのでエラーにしない
+				return nil
+			}
+			return apiError(err)
 		}
-		return searchResultsMsg(results)
+		return searchResultsMsg{seq: seq, query: query, results: results}
+	}
+}
+
+// performSearchMore は検索結果の1つのセクションの続きのページを取得する
+func (m Model) performSearchMore(seq int, query string, kind searchKind, offset int) tea.Cmd {
+	searchType := searchSectionType(kind)
+	return func() tea.Msg {
+		results, err := m.client.SearchMore(m.ctx, query, searchType, offset)
+		return searchMoreMsg{seq: seq, kind: kind, results: results, err: err}
 	}
 }
 
@@ -285,7 +1186,7 @@ func (m Model) fetchUser() tea.Cmd {
 	return func() tea.Msg {
 		user, err := m.client.CurrentUser(m.ctx)
 		if err != nil {
-			return errorMsg(err.Error())
+			return apiError(err)
 		}
 		return userMsg(user)
 	}
@@ -293,11 +1194,12 @@ func (m Model) fetchUser() tea.Cmd {
 
 func (m Model) fetchQueue() tea.Cmd {
 	return func() tea.Msg {
+		requestedAt := time.Now()
 		queue, err := m.client.GetQueue(m.ctx)
 		if err != nil {
-			return errorMsg(err.Error())
+			return apiError(err)
 		}
-		return queueMsg(queue)
+		return queueMsg{queue: queue, requestedAt: requestedAt}
 	}
 }
 
@@ -305,7 +1207,7 @@ func (m Model) fetchDevices() tea.Cmd {
 	return func() tea.Msg {
 		devices, err := m.client.PlayerDevices(m.ctx)
 		if err != nil {
-			return errorMsg(err.Error())
+			return apiError(err)
 		}
 		return devicesMsg(devices)
 	}
@@ -0,0 +1,489 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	spotifysdk "github.com/zmb3/spotify/v2"
+)
+
+// playlistEditKind はプレイリストの曲の変更の種類
+type playlistEditKind int
+
+const (
+	editAddTrack     playlistEditKind = iota // 曲を最後に加える
+	editRemoveTrack                          // from の位置の曲を削除する
+	editMoveTrack                            // from の位置の曲を to の位置に移す
+	editRestoreTrack                         // 削除した曲を元の位置（to）に戻す（undo）
+)
+
+// playlistEdit はプレイリストの曲の変更
+// 応答が届いたら、開いているプレイリストの曲にも同じ変更を反映する
+type playlistEdit struct {
+	kind     playlistEditKind
+	playlist playlistItem
+	track    spotifysdk.PlaylistTrack
+	from, to int
+}
+
+// undoAction は undo で元に戻せる最後の削除（曲の削除か、プレイリストの削除）
+type undoAction struct {
+	removed  *playlistEdit
+	playlist *playlistItem
+}
+
+// namePromptKind は名前の入力欄で入力する名前の用途
+type namePromptKind int
+
+const (
+	promptNone namePromptKind = iota
+	promptCreate
+	promptRename
+)
+
+// noticeDuration は編集の結果を表示しておく時間（undo のキーを案内するので、エラーより長くする）
+const noticeDuration = 5 * time.Second
+
+// newNameInput はプレイリストの名前の入力欄を作る（キーは検索の入力欄と同じ）
+func newNameInput(km keyMap) textinput.Model {
+	input := textinput.New()
+	input.Prompt = " Name: "
+	input.CharLimit = 100
+	input.KeyMap.DeleteCharacterBackward = km.binding(actSearchDelete)
+	input.KeyMap.AcceptSuggestion = key.NewBinding(key.WithDisabled())
+	input.Cursor.SetMode(cursor.CursorStatic)
+	return input
+}
+
+// sidebarPlaylist はサイドバーのプレイリストを ID で探す（Liked Songs と保存したアルバムは除く）
+func (m Model) sidebarPlaylist(id spotifysdk.ID) (playlistItem, int, bool) {
+	for i, item := range m.playlists.Items() {
+		if pl, ok := item.(playlistItem); ok && pl.ownerID != "" && pl.id == string(id) {
+			return pl, i, true
+		}
+	}
+	return playlistItem{}, -1, false
+}
+
+// ownPlaylist はプレイリストがログイン中のユーザーのものかを返す
+func (m Model) ownPlaylist(item playlistItem) bool {
+	return m.user != nil && item.ownerID != "" && item.ownerID == m.user.ID
+}
+
+// editablePlaylist は曲を追加・削除・並べ替えできるプレイリストか（自分のものか共同編集）を返す
+func (m Model) editablePlaylist(item playlistItem) bool {
+	return m.ownPlaylist(item) || (item.ownerID != "" && item.collaborative)
+}
+
+// openPlaylistItem はメインパネルで開いているプレイリストを返す（Liked Songs などでは false）
+func (m Model) openPlaylistItem() (playlistItem, bool) {
+	id, ok := strings.CutPrefix(string(m.currentPlaylistURI), "spotify:playlist:")
+	if !ok {
+		return playlistItem{}, false
+	}
+	item, _, ok := m.sidebarPlaylist(spotifysdk.ID(id))
+	return item, ok
+}
+
+// selectedSidebarPlaylist はサイドバーで選択中のプレイリストを返す（Liked Songs などでは false）
+func (m Model) selectedSidebarPlaylist() (playlistItem, bool) {
+	item, ok := m.playlists.SelectedItem().(playlistItem)
+	if !ok || item.ownerID == "" {
+		return playlistItem{}, false
+	}
+	return item, true
+}
+
+// setNotice は編集の結果をプレイヤーバーに表示し、noticeDuration 後に消す
+func (m Model) setNotice(text string) (Model, tea.Cmd) {
+	m.noticeSeq++
+	m.notice = text
+	seq := m.noticeSeq
+	return m, tick(noticeDuration, func(time.Time) tea.Msg {
+		return noticeClearMsg(seq)
+	})
+}
+
+// openNamePrompt はプレイリストの名前の入力欄を開く
+// 名前の変更は、サイドバーでは選択中の、メインパネルでは開いているプレイリストが対象
+func (m Model) openNamePrompt(kind namePromptKind) (Model, tea.Cmd) {
+	m.namePrompt = kind
+	m.nameInput.SetValue("")
+	if kind == promptRename {
+		item, ok := m.selectedSidebarPlaylist()
+		if m.focus != FocusSidebar {
+			item, ok = m.openPlaylistItem()
+		}
+		if !ok || !m.ownPlaylist(item) {
+			m.namePrompt = promptNone
+			return m, func() tea.Msg { return errorMsg("Only your own playlists can be renamed") }
+		}
+		m.renaming = item
+		m.nameInput.SetValue(item.name)
+	}
+	m.nameInput.CursorEnd()
+	return m, m.nameInput.Focus()
+}
+
+func (m Model) closeNamePrompt() Model {
+	m.namePrompt = promptNone
+	m.nameInput.Blur()
+	return m
+}
+
+// submitNamePrompt は入力した名前でプレイリストを作成するか、名前を変える
+func (m Model) submitNamePrompt() (Model, tea.Cmd) {
+	name := strings.TrimSpace(m.nameInput.Value())
+	kind, item := m.namePrompt, m.renaming
+	if name == "" {
+		return m, nil
+	}
+	m = m.closeNamePrompt()
+	switch kind {
+	case promptCreate:
+		if m.user == nil {
+			return m, nil
+		}
+		userID := m.user.ID
+		return m, func() tea.Msg {
+			playlist, err := m.client.CreatePlaylist(m.ctx, userID, name)
+			return playlistCreatedMsg{playlist: playlist, err: err}
+		}
+	case promptRename:
+		if name == item.name {
+			return m, nil
+		}
+		return m, func() tea.Msg {
+			err := m.client.RenamePlaylist(m.ctx, spotifysdk.ID(item.id), name)
+			return playlistRenamedMsg{id: item.id, name: name, err: err}
+		}
+	}
+	return m, nil
+}
+
+// openPlaylistPicker は曲を加えるプレイリストの選択を開く
+// 選べるのは自分のプレイリストと共同編集のプレイリストだけ
+func (m Model) openPlaylistPicker(uri spotifysdk.URI) (Model, tea.Cmd) {
+	if uri == "" {
+		return m, nil
+	}
+	if trackID(uri) == "" && !strings.HasPrefix(string(uri), "spotify:episode:") {
+		return m, func() tea.Msg { return errorMsg("Local files cannot be added to playlists") }
+	}
+	m.pickerPlaylists = nil
+	for _, item := range m.playlists.Items() {
+		if pl, ok := item.(playlistItem); ok && m.editablePlaylist(pl) {
+			m.pickerPlaylists = append(m.pickerPlaylists, pl)
+		}
+	}
+	m.pickerTrack = uri
+	m.pickerIndex = 0
+	m.showPlaylistPicker = true
+	return m, nil
+}
+
+// addToPlaylist は選択中の曲をプレイリストの最後に加える
+func (m Model) addToPlaylist(item playlistItem) (Model, tea.Cmd) {
+	edit := playlistEdit{kind: editAddTrack, playlist: item}
+	edit.track.Track.URI = m.pickerTrack
+	return m.sendPlaylistEdit(edit)
+}
+
+// removeSelectedTrack はメインパネルで選択中の曲をプレイリストから削除する
+func (m Model) removeSelectedTrack() (Model, tea.Cmd) {
+	item, index, reason := m.selectedPlaylistTrack()
+	if reason != "" {
+		return m, func() tea.Msg { return errorMsg(reason) }
+	}
+	if index < 0 {
+		return m, nil
+	}
+	return m.sendPlaylistEdit(playlistEdit{
+		kind:     editRemoveTrack,
+		playlist: item,
+		track:    m.tracks[index],
+		from:     index,
+	})
+}
+
+// moveSelectedTrack はメインパネルで選択中の曲を step だけ上下に移す
+func (m Model) moveSelectedTrack(step int) (Model, tea.Cmd) {
+	item, index, reason := m.selectedPlaylistTrack()
+	if reason != "" {
+		return m, func() tea.Msg { return errorMsg(reason) }
+	}
+	to := index + step
+	if index < 0 || to < 0 || to >= len(m.tracks) {
+		return m, nil
+	}
+	return m.sendPlaylistEdit(playlistEdit{
+		kind:     editMoveTrack,
+		playlist: item,
+		track:    m.tracks[index],
+		from:     index,
+		to:       to,
+	})
+}
+
+// selectedPlaylistTrack はメインパネルで開いているプレイリストと、選択中の曲の位置を返す
+// 曲を選んでいない場合は位置が -1 になり、編集できないプレイリストの場合はその理由を返す
+func (m Model) selectedPlaylistTrack() (playlistItem, int, string) {
+	if m.focus != FocusMain || m.view != viewTracks || m.loadingTracks {
+		return playlistItem{}, -1, ""
+	}
+	item, ok := m.openPlaylistItem()
+	if !ok || !m.editablePlaylist(item) {
+		return playlistItem{}, -1, "Only tracks in your own or collaborative playlists can be changed"
+	}
+	selected, ok := m.trackList.SelectedItem().(trackItem)
+	if !ok || selected.index >= len(m.tracks) {
+		return item, -1, ""
+	}
+	return item, selected.index, ""
+}
+
+// sendPlaylistEdit はプレイリストの曲の変更を送る
+// 位置がずれないよう変更は1つずつ送り、応答のスナップショットIDを次の変更に使う
+// 応答を待っている間の変更は無視する
+func (m Model) sendPlaylistEdit(edit playlistEdit) (Model, tea.Cmd) {
+	if m.editPending {
+		return m, nil
+	}
+	m.editPending = true
+	id := spotifysdk.ID(edit.playlist.id)
+	snapshot := edit.playlist.snapshotID
+	uri := edit.track.Track.URI
+	return m, func() tea.Msg {
+		var snapshotID string
+		var err error
+		switch edit.kind {
+		case editAddTrack:
+			snapshotID, err = m.client.AddPlaylistItems(m.ctx, id, []spotifysdk.URI{uri}, -1)
+		case editRemoveTrack:
+			snapshotID, err = m.client.RemovePlaylistItem(m.ctx, id, uri, edit.from, snapshot)
+		case editMoveTrack:
+			snapshotID, err = m.client.MovePlaylistItem(m.ctx, id, edit.from, edit.to, snapshot)
+		case editRestoreTrack:
+			snapshotID, err = m.client.AddPlaylistItems(m.ctx, id, []spotifysdk.URI{uri}, edit.to)
+		}
+		return playlistEditedMsg{edit: edit, snapshotID: snapshotID, err: err}
+	}
+}
+
+// applyPlaylistEdit は送った変更の結果をサイドバーのスナップショットIDと、開いているプレイリストの曲に反映する
+func (m Model) applyPlaylistEdit(msg playlistEditedMsg) (Model, tea.Cmd) {
+	m.editPending = false
+	if msg.err != nil {
+		return m, func() tea.Msg { return apiError(msg.err) }
+	}
+	edit := msg.edit
+	if item, index, ok := m.sidebarPlaylist(spotifysdk.ID(edit.playlist.id)); ok {
+		item.snapshotID = msg.snapshotID
+		m.playlists.SetItem(index, item)
+	}
+
+	var cmds []tea.Cmd
+	open, ok := m.openPlaylistItem()
+	if ok && open.id == edit.playlist.id && !m.loadingTracks {
+		switch edit.kind {
+		case editAddTrack:
+			// 読み込みが終わっている場合は、加えた曲を含む最後のページを読み込む
+			if len(m.tracks) == m.tracksTotal {
+				cmds = append(cmds, m.fetchPlaylistTracks(spotifysdk.ID(edit.playlist.id), len(m.tracks)))
+			}
+		case editRemoveTrack:
+			if edit.from < len(m.tracks) {
+				m.tracks = slices.Delete(slices.Clone(m.tracks), edit.from, edit.from+1)
+				m.tracksTotal--
+				m = m.setTrackItems(min(edit.from, len(m.tracks)-1))
+			}
+		case editMoveTrack:
+			if edit.from < len(m.tracks) && edit.to < len(m.tracks) {
+				tracks := slices.Delete(slices.Clone(m.tracks), edit.from, edit.from+1)
+				m.tracks = slices.Insert(tracks, edit.to, edit.track)
+				m = m.setTrackItems(edit.to)
+			}
+		case editRestoreTrack:
+			if edit.to <= len(m.tracks) {
+				m.tracks = slices.Insert(slices.Clone(m.tracks), edit.to, edit.track)
+				m.tracksTotal++
+				m = m.setTrackItems(edit.to)
+			}
+		}
+	}
+
+	var notice string
+	switch edit.kind {
+	case editAddTrack:
+		notice = "Added to " + edit.playlist.name
+	case editRemoveTrack:
+		m.undo = &undoAction{removed: &edit}
+		notice = fmt.Sprintf("Removed %s from %s — press [%s] to undo",
+			edit.track.Track.Name, edit.playlist.name, m.keys.binding(actUndo).Help().Key)
+	case editRestoreTrack:
+		notice = "Restored " + edit.track.Track.Name
+	}
+	if notice != "" {
+		var cmd tea.Cmd
+		m, cmd = m.setNotice(notice)
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// setTrackItems はトラックリストを m.tracks から作り直し、selected を選択する
+func (m Model) setTrackItems(selected int) Model {
+	m.trackList.SetItems(m.buildTrackItems())
+	m.trackList.Select(max(selected, 0))
+	return m
+}
+
+// deleteSelectedPlaylist はサイドバーで選択中のプレイリストをライブラリから削除する
+func (m Model) deleteSelectedPlaylist() (Model, tea.Cmd) {
+	item, ok := m.selectedSidebarPlaylist()
+	if !ok || m.editPending {
+		return m, nil
+	}
+	m.editPending = true
+	return m, func() tea.Msg {
+		err := m.client.UnfollowPlaylist(m.ctx, spotifysdk.ID(item.id))
+		return playlistDeletedMsg{item: item, err: err}
+	}
+}
+
+// removeSidebarPlaylist はサイドバーから削除したプレイリストを除く
+func (m Model) removeSidebarPlaylist(item playlistItem) (Model, tea.Cmd) {
+	if _, index, ok := m.sidebarPlaylist(spotifysdk.ID(item.id)); ok {
+		m.playlists.RemoveItem(index)
+		m.playlistsCount--
+		m.playlistsTotal--
+	}
+	m.undo = &undoAction{playlist: &item}
+	return m.setNotice(fmt.Sprintf("Deleted playlist %s — press [%s] to undo",
+		item.name, m.keys.binding(actUndo).Help().Key))
+}
+
+// addSidebarPlaylist は作成したプレイリストを、APIの一覧と同じくサイドバーのプレイリストの先頭に加える
+func (m Model) addSidebarPlaylist(pl spotifysdk.SimplePlaylist) (Model, tea.Cmd) {
+	index := len(m.playlists.Items()) - m.playlistsCount
+	m.playlists.InsertItem(index, newPlaylistItem(pl))
+	m.playlists.Select(index)
+	m.playlistsCount++
+	m.playlistsTotal++
+	return m.setNotice("Created playlist " + pl.Name)
+}
+
+// renameSidebarPlaylist は名前を変えたプレイリストのサイドバーとメインパネルの表示を更新する
+func (m Model) renameSidebarPlaylist(id, name string) (Model, tea.Cmd) {
+	if item, index, ok := m.sidebarPlaylist(spotifysdk.ID(id)); ok {
+		item.name = name
+		m.playlists.SetItem(index, item)
+	}
+	if m.currentPlaylistURI == spotifysdk.URI("spotify:playlist:"+id) {
+		m.currentPlaylistName = name
+	}
+	return m.setNotice("Renamed playlist to " + name)
+}
+
+// undoLast は最後の削除を元に戻す
+// 曲は削除した位置に加え直し、プレイリストはフォローし直してサイドバーを読み込み直す
+func (m Model) undoLast() (Model, tea.Cmd) {
+	undo := m.undo
+	switch {
+	case undo == nil || m.editPending:
+		return m, nil
+	case undo.removed != nil:
+		edit := *undo.removed
+		edit.kind = editRestoreTrack
+		edit.to = edit.from
+		m, cmd := m.sendPlaylistEdit(edit)
+		m.undo = nil
+		return m, cmd
+	case undo.playlist != nil:
+		item := *undo.playlist
+		m.undo = nil
+		m.editPending = true
+		return m, func() tea.Msg {
+			err := m.client.FollowPlaylist(m.ctx, spotifysdk.ID(item.id), item.public)
+			return playlistRestoredMsg{item: item, err: err}
+		}
+	}
+	return m, nil
+}
+
+// newPlaylistItem はAPIのプレイリストからサイドバーの項目を作る
+func newPlaylistItem(pl spotifysdk.SimplePlaylist) playlistItem {
+	return playlistItem{
+		id:            string(pl.ID),
+		name:          pl.Name,
+		ownerID:       pl.Owner.ID,
+		collaborative: pl.Collaborative,
+		public:        pl.IsPublic,
+		snapshotID:    pl.SnapshotID,
+	}
+}
+
+func (m Model) renderNamePrompt() string {
+	width := min(m.width-10, 60)
+	title := "➕ New Playlist"
+	if m.namePrompt == promptRename {
+		title = "✏ Rename Playlist"
+	}
+	input := m.nameInput
+	input.Width = max(width-lipgloss.Width(input.Prompt)-1, 1)
+
+	lines := []string{
+		m.theme.Title.Render(truncate(title, width)),
+		"",
+		input.View(),
+		"",
+		truncate(fmt.Sprintf("[%s] Save | [%s] Cancel",
+			m.keys.binding(actSearchSubmit).Help().Key,
+			m.keys.binding(actSearchCancel).Help().Key), width),
+	}
+	if m.err != "" {
+		lines = append(lines, m.theme.ErrorText.Render(truncate("Error: "+m.err, width)))
+	}
+	return m.theme.PlayerBar.Render(strings.Join(lines, "\n"))
+}
+
+func (m Model) renderPlaylistPicker() string {
+	width := min(m.width-10, 60)
+
+	var lines []string
+	lines = append(lines, m.theme.Title.Render(truncate("➕ Add to Playlist", width)), "")
+
+	if len(m.pickerPlaylists) == 0 {
+		lines = append(lines, truncate(fmt.Sprintf(" No playlists you can edit. Press [%s] to create one.",
+			m.keys.binding(actCreatePlaylist).Help().Key), width))
+	}
+	// 選択中の項目が見えるようにスクロールする
+	visible := max(m.height-12, 3)
+	start := min(max(m.pickerIndex-visible/2, 0), max(len(m.pickerPlaylists)-visible, 0))
+	end := min(start+visible, len(m.pickerPlaylists))
+	for i := start; i < end; i++ {
+		line := " " + m.pickerPlaylists[i].name
+		if i == m.pickerIndex {
+			line = m.theme.SelectedTrack.Width(width).Render(truncate(" ▶"+line, width))
+		} else {
+			line = m.theme.Track.Width(width).Render(truncate("  "+line, width))
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, "", truncate(fmt.Sprintf("[%s] Add | [%s] Close",
+		m.keys.binding(actPickerSelect).Help().Key,
+		m.keys.binding(actPickerClose).Help().Key), width))
+	if m.err != "" {
+		lines = append(lines, m.theme.ErrorText.Render(truncate("Error: "+m.err, width)))
+	}
+
+	return m.theme.PlayerBar.Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,324 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	"spotify-tui/internal/spotify"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	spotifysdk "github.com/zmb3/spotify/v2"
+)
+
+// pendingQueueItem はキューに追加して、取得したキューへの反映を待っている曲
+// addedAt は追加のリクエストが成功した時刻で、それより後に取得を始めたキューには反映されている
+type pendingQueueItem struct {
+	id      int
+	track   spotifysdk.FullTrack
+	addedAt time.Time // 追加のリクエストの応答待ちの間はゼロ
+}
+
+// selectedQueueTrack はメインパネルで選択中の、キューに加えられる曲を返す
+// トラックリスト、検索結果、アルバムとアーティストのページの曲（検索結果はエピソードも）が対象
+func (m Model) selectedQueueTrack() (spotifysdk.FullTrack, bool) {
+	if m.searchMode {
+		if m.searchIndex < len(m.searchResults) {
+			return entryQueueTrack(m.searchResults[m.searchIndex])
+		}
+		return spotifysdk.FullTrack{}, false
+	}
+	if m.focus != FocusMain {
+		return spotifysdk.FullTrack{}, false
+	}
+	switch m.view {
+	case viewArtist:
+		if section, i, ok := m.artist.entryAt(m.artist.index); ok {
+			return entryQueueTrack(m.artist.sections[section].entries[i])
+		}
+	case viewAlbum:
+		if i := m.album.index; i < len(m.album.tracks) {
+			return spotifysdk.FullTrack{
+				SimpleTrack: m.album.tracks[i],
+				Album:       spotifysdk.SimpleAlbum{ID: m.album.id, URI: m.album.uri, Name: m.album.name},
+			}, true
+		}
+	case viewTracks:
+		if item, ok := m.trackList.SelectedItem().(trackItem); ok && item.index < len(m.tracks) {
+			// ローカルファイルはキューに加えられない
+			t := m.tracks[item.index].Track
+			return t, t.URI != "" && !strings.HasPrefix(string(t.URI), "spotify:local:")
+		}
+	}
+	return spotifysdk.FullTrack{}, false
+}
+
+// entryQueueTrack は検索結果やアーティストページの曲とエピソードを、キューに表示する形にする
+func entryQueueTrack(e searchEntry) (spotifysdk.FullTrack, bool) {
+	t := spotifysdk.FullTrack{SimpleTrack: spotifysdk.SimpleTrack{ID: e.id, URI: e.uri, Name: e.name}}
+	switch e.kind {
+	case searchTrack:
+		t.Artists = []spotifysdk.SimpleArtist{{ID: e.artistID, Name: e.detail}}
+		t.Album.ID = e.albumID
+	case searchEpisode:
+		// エピソードはアーティストもアルバムも持たない
+	default:
+		return spotifysdk.FullTrack{}, false
+	}
+	return t, true
+}
+
+// addToQueue は曲を再生キューに加える
+// 取得したキューに反映されるまでの間も、追加した曲をすぐにキューに表示する
+func (m Model) addToQueue(track spotifysdk.FullTrack) (Model, tea.Cmd) {
+	m.queueSeq++
+	id := m.queueSeq
+	m.queuePending = append(m.queuePending, pendingQueueItem{id: id, track: track})
+	m = m.setQueue(m.queueFetched)
+	return m, func() tea.Msg {
+		err := m.client.AddToQueue(m.ctx, track.URI)
+		return queueAddedMsg{id: id, track: track, err: err}
+	}
+}
+
+// queueAdded は追加のリクエストの結果を反映する
+// 失敗した場合は表示から除き、アクティブなデバイスがなければデバイスを選んだ後にもう一度加える
+func (m Model) queueAdded(msg queueAddedMsg) (Model, tea.Cmd) {
+	for i, p := range m.queuePending {
+		if p.id != msg.id {
+			continue
+		}
+		if msg.err != nil {
+			m.queuePending = append(m.queuePending[:i:i], m.queuePending[i+1:]...)
+		} else {
+			m.queuePending[i].addedAt = time.Now()
+		}
+		break
+	}
+	if msg.err != nil {
+		m = m.setQueue(m.queueFetched)
+		track := msg.track
+		retry := func() tea.Msg { return queueAddMsg(track) }
+		return m, func() tea.Msg { return playerError(msg.err, retry) }
+	}
+	return m.setNotice("Added " + msg.track.Name + " to queue")
+}
+
+// reconcileQueue は requestedAt に取得を始めたキューを表示する
+// その時点で追加が済んでいた曲はキューに含まれているので、反映を待つのをやめる
+func (m Model) reconcileQueue(items []spotifysdk.FullTrack, requestedAt time.Time) Model {
+	pending := m.queuePending[:0:0]
+	for _, p := range m.queuePending {
+		if p.addedAt.IsZero() || p.addedAt.After(requestedAt) {
+			pending = append(pending, p)
+		} else {
+			m.queueUserAdded = append(m.queueUserAdded, p.track.URI)
+		}
+	}
+	m.queuePending = pending
+	return m.setQueue(items)
+}
+
+// setQueue は Up Next の曲と、取得したキューに反映を待っている曲を加えたものを表示する
+// Spotify はユーザーが追加した曲を、それまでに追加した曲の後、コンテキストの続きより前に再生するので、
+// 取得したキューの先頭に残っている追加済みの曲の後に置く
+// 同じ曲がコンテキストの続きにあることもあるので、曲の URI では反映済みかどうかを判断しない
+func (m Model) setQueue(items []spotifysdk.FullTrack) Model {
+	m.queueFetched = items
+	// 追加済みの曲は再生されると先頭から消えていく
+	for len(m.queueUserAdded) > 0 && !queueHasPrefix(items, m.queueUserAdded) {
+		m.queueUserAdded = m.queueUserAdded[1:]
+	}
+	head := len(m.queueUserAdded)
+	var queue []spotifysdk.FullTrack
+	for _, e := range m.upNextEntries {
+		queue = append(queue, upNextTrack(e))
+	}
+	queue = append(queue, items[:head]...)
+	for _, p := range m.queuePending {
+		queue = append(queue, p.track)
+	}
+	m.queue = append(queue, items[head:]...)
+
+	// queueListを更新（選択位置を保持）
+	selectedIdx := m.queueList.Index()
+	listItems := make([]list.Item, len(m.queue))
+	for i, t := range m.queue {
+		listItems[i] = queueItem{
+			name:   t.Name,
+			artist: artistName(t.Artists),
+			uri:    string(t.URI),
+			liked:  m.liked[t.URI],
+			upNext: i < len(m.upNextEntries),
+		}
+	}
+	m.queueList.SetItems(listItems)
+	// 選択位置を復元（アイテム数が変わった場合は範囲内に収める）
+	if selectedIdx >= len(listItems) {
+		selectedIdx = len(listItems) - 1
+	}
+	if selectedIdx >= 0 {
+		m.queueList.Select(selectedIdx)
+	}
+	return m
+}
+
+// queueHasPrefix はキューが uris の曲で始まるかどうかを返す
+func queueHasPrefix(items []spotifysdk.FullTrack, uris []spotifysdk.URI) bool {
+	if len(items) < len(uris) {
+		return false
+	}
+	for i, uri := range uris {
+		if items[i].URI != uri {
+			return false
+		}
+	}
+	return true
+}
+
+// upNextEntry は曲を Up Next に加える形にする
+func upNextEntry(t spotifysdk.FullTrack) spotify.QueueEntry {
+	e := spotify.QueueEntry{URI: t.URI, Name: t.Name, Artist: artistName(t.Artists), AlbumID: t.Album.ID}
+	if len(t.Artists) > 0 {
+		e.ArtistID = t.Artists[0].ID
+	}
+	return e
+}
+
+// upNextTrack は Up Next の曲をキューに表示する形にする
+func upNextTrack(e spotify.QueueEntry) spotifysdk.FullTrack {
+	t := spotifysdk.FullTrack{
+		SimpleTrack: spotifysdk.SimpleTrack{URI: e.URI, Name: e.Name},
+		Album:       spotifysdk.SimpleAlbum{ID: e.AlbumID},
+	}
+	if e.Artist != "" {
+		t.Artists = []spotifysdk.SimpleArtist{{ID: e.ArtistID, Name: e.Artist}}
+	}
+	return t
+}
+
+// addToUpNext は曲を Up Next に加える
+// next の場合は先頭に加え、再生中の曲の次に再生する
+func (m Model) addToUpNext(track spotifysdk.FullTrack, next bool) (Model, tea.Cmd) {
+	if next {
+		m.upNext.InsertNext(upNextEntry(track))
+	} else {
+		m.upNext.Add(upNextEntry(track))
+	}
+	m = m.refreshUpNext()
+	m, notice := m.setNotice("Added " + track.Name + " to Up Next")
+	return m, tea.Batch(notice, m.saveUpNext())
+}
+
+// selectedUpNext はキューで選択中の曲の、Up Next の中での位置を返す
+func (m Model) selectedUpNext() (int, bool) {
+	if m.focus != FocusQueue {
+		return 0, false
+	}
+	i := m.queueList.Index()
+	return i, i >= 0 && i < len(m.upNextEntries)
+}
+
+// removeUpNext はキューで選択中の Up Next の曲を除く
+func (m Model) removeUpNext() (Model, tea.Cmd) {
+	i, ok := m.selectedUpNext()
+	if !ok {
+		return m, func() tea.Msg {
+			return errorMsg("Spotify's queue can't be changed; only Up Next tracks can be removed")
+		}
+	}
+	if err := m.upNext.Remove(i); err != nil {
+		return m, nil
+	}
+	m = m.refreshUpNext()
+	return m, m.saveUpNext()
+}
+
+// moveUpNext はキューで選択中の Up Next の曲を step だけ上下に移す
+func (m Model) moveUpNext(step int) (Model, tea.Cmd) {
+	i, ok := m.selectedUpNext()
+	if !ok {
+		return m, func() tea.Msg { return errorMsg("Spotify's queue can't be changed; only Up Next tracks can be moved") }
+	}
+	if err := m.upNext.Move(i, i+step); err != nil {
+		return m, nil
+	}
+	m = m.refreshUpNext()
+	m.queueList.Select(i + step)
+	return m, m.saveUpNext()
+}
+
+// clearUpNext は Up Next を空にする
+func (m Model) clearUpNext() (Model, tea.Cmd) {
+	if len(m.upNextEntries) == 0 {
+		return m, nil
+	}
+	m.upNext.Clear()
+	m = m.refreshUpNext()
+	m, notice := m.setNotice("Cleared Up Next")
+	return m, tea.Batch(notice, m.saveUpNext())
+}
+
+// refreshUpNext は Up Next の曲を読み直してキューの表示を更新する
+func (m Model) refreshUpNext() Model {
+	m.upNextEntries = m.upNext.Entries()
+	return m.setQueue(m.queueFetched)
+}
+
+// playUpNext は Up Next の i 番目の曲をすぐに再生する
+func (m Model) playUpNext(i int) tea.Cmd {
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
+		entry, err := m.upNext.Play(m.ctx, i)
+		if err != nil {
+			return playerError(err, cmd)
+		}
+		return upNextStartedMsg(entry)
+	}
+	return cmd
+}
+
+// observePlayback は取得した再生状態を Up Next に渡す
+// 再生中の曲が終わっていれば、Up Next の次の曲の再生が始まる
+func (m Model) observePlayback(state *spotifysdk.PlayerState) tea.Cmd {
+	return func() tea.Msg {
+		entry, err := m.upNext.Observe(m.ctx, state)
+		if err != nil {
+			return apiError(err)
+		}
+		if entry != nil {
+			return upNextStartedMsg(*entry)
+		}
+		return nil
+	}
+}
+
+// upNextStarted は Up Next の曲の再生が始まったことを表示に反映する
+func (m Model) upNextStarted(entry spotify.QueueEntry) (Model, tea.Cmd) {
+	m.playingPlaylistName = "Up Next"
+	m = m.refreshUpNext()
+	m, notice := m.setNotice("Playing " + entry.Name + " from Up Next")
+	return m, tea.Batch(notice, m.saveUpNext(), m.fetchCurrentPlayback())
+}
+
+// saveUpNext は Up Next をファイルに書き込む
+func (m Model) saveUpNext() tea.Cmd {
+	q := m.upNext
+	return func() tea.Msg {
+		if err := q.Save(); err != nil {
+			return errorMsg("Failed to save Up Next: " + err.Error())
+		}
+		return nil
+	}
+}
+
+// loadUpNext は保存した Up Next を読み込む
+func (m Model) loadUpNext() tea.Cmd {
+	q := m.upNext
+	return func() tea.Msg {
+		if err := q.Load(); err != nil {
+			return errorMsg("Failed to load Up Next: " + err.Error())
+		}
+		return upNextLoadedMsg(q.Entries())
+	}
+}
@@ -0,0 +1,518 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"spotify-tui/internal/config"
+	"spotify-tui/internal/spotify"
+
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	spotifysdk "github.com/zmb3/spotify/v2"
+)
+
+// searchKind は検索結果の種類
+type searchKind int
+
+const (
+	searchTrack searchKind = iota
+	searchArtist
+	searchAlbum
+	searchPlaylist
+	searchShow
+	searchEpisode
+
+	searchKindCount // 種類の数
+)
+
+// searchSections は検索画面に表示するセクションの順番と見出し、API で検索する種類
+var searchSections = []struct {
+	kind       searchKind
+	title      string
+	searchType spotifysdk.SearchType
+}{
+	{searchTrack, "Tracks", spotifysdk.SearchTypeTrack},
+	{searchArtist, "Artists", spotifysdk.SearchTypeArtist},
+	{searchAlbum, "Albums", spotifysdk.SearchTypeAlbum},
+	{searchPlaylist, "Playlists", spotifysdk.SearchTypePlaylist},
+	{searchShow, "Shows", spotifysdk.SearchTypeShow},
+	{searchEpisode, "Episodes", spotifysdk.SearchTypeEpisode},
+}
+
+// searchPage は検索結果のセクションごとのページの状態
+type searchPage struct {
+	total   int // API が返した結果の総数
+	next    int // 次のページの offset（0 なら続きはない）
+	loading bool
+}
+
+// searchMorePrefetch は、選択がセクションの最後からこの件数以内に来たら続きのページを読み込む
+const searchMorePrefetch = 5
+
+// searchEntry は検索結果（とアーティストページ）の1行
+// 種類の違う結果を1つのリストで選べるように、表示と再生に必要な情報だけを持つ
+type searchEntry struct {
+	kind     searchKind
+	id       spotifysdk.ID
+	uri      spotifysdk.URI
+	name     string
+	detail   string        // アーティスト名や作成者など、名前の後に表示する情報
+	artistID spotifysdk.ID // トラックとアルバムのアーティスト（アーティストは自身）
+	albumID  spotifysdk.ID // トラックのアルバム（アルバムは自身）
+}
+
+// searchEntries は検索結果をセクションの順に並べる
+func searchEntries(r *spotify.SearchResults) []searchEntry {
+	if r == nil {
+		return nil
+	}
+	var entries []searchEntry
+	for _, section := range searchSections {
+		switch section.kind {
+		case searchTrack:
+			for _, t := range r.Tracks {
+				entries = append(entries, trackEntry(t))
+			}
+		case searchArtist:
+			for _, a := range r.Artists {
+				entries = append(entries, artistEntry(a))
+			}
+		case searchAlbum:
+			for _, a := range r.Albums {
+				entries = append(entries, albumEntry(a))
+			}
+		case searchPlaylist:
+			for _, pl := range r.Playlists {
+				detail := ""
+				if pl.Owner.DisplayName != "" {
+					detail = "by " + pl.Owner.DisplayName
+				}
+				entries = append(entries, searchEntry{kind: searchPlaylist, id: pl.ID, uri: pl.URI, name: pl.Name, detail: detail})
+			}
+		case searchShow:
+			for _, show := range r.Shows {
+				entries = append(entries, searchEntry{kind: searchShow, id: show.ID, uri: show.URI, name: show.Name, detail: show.Publisher})
+			}
+		case searchEpisode:
+			for _, ep := range r.Episodes {
+				entries = append(entries, searchEntry{kind: searchEpisode, id: ep.ID, uri: ep.URI, name: ep.Name, detail: ep.ReleaseDate})
+			}
+		}
+	}
+	return entries
+}
+
+func trackEntry(t spotifysdk.FullTrack) searchEntry {
+	e := searchEntry{kind: searchTrack, id: t.ID, uri: t.URI, name: t.Name, detail: artistName(t.Artists), albumID: t.Album.ID}
+	if len(t.Artists) > 0 {
+		e.artistID = t.Artists[0].ID
+	}
+	return e
+}
+
+func artistEntry(a spotifysdk.FullArtist) searchEntry {
+	e := searchEntry{kind: searchArtist, id: a.ID, uri: a.URI, name: a.Name, artistID: a.ID}
+	if a.Followers.Count > 0 {
+		e.detail = fmt.Sprintf("%d followers", a.Followers.Count)
+	}
+	return e
+}
+
+func albumEntry(a spotifysdk.SimpleAlbum) searchEntry {
+	e := searchEntry{kind: searchAlbum, id: a.ID, uri: a.URI, name: a.Name, detail: artistName(a.Artists), albumID: a.ID}
+	if len(a.ReleaseDate) >= 4 {
+		e.detail += " (" + a.ReleaseDate[:4] + ")"
+	}
+	if len(a.Artists) > 0 {
+		e.artistID = a.Artists[0].ID
+	}
+	return e
+}
+
+// searchEntrySections は検索結果をセクションに分ける
+func (m Model) searchEntrySections() []entrySection {
+	sections := make([]entrySection, 0, len(searchSections))
+	for _, section := range searchSections {
+		var entries []searchEntry
+		for _, e := range m.searchResults {
+			if e.kind == section.kind {
+				entries = append(entries, e)
+			}
+		}
+		sections = append(sections, entrySection{title: section.title, entries: entries, page: m.searchPages[section.kind]})
+	}
+	return sections
+}
+
+// setSearchPages は新しい検索結果のページの位置を設定する
+func (m Model) setSearchPages(r *spotify.SearchResults) Model {
+	m.searchPages = [searchKindCount]searchPage{}
+	if r == nil {
+		return m
+	}
+	for _, section := range searchSections {
+		if p, ok := r.Paging[section.searchType]; ok {
+			m.searchPages[section.kind] = searchPage{total: p.Total, next: p.Next}
+		}
+	}
+	return m
+}
+
+// loadMoreSearch は選択中の結果がセクションの最後に近ければ、そのセクションの続きのページを読み込む
+func (m Model) loadMoreSearch() (Model, tea.Cmd) {
+	if m.searchIndex >= len(m.searchResults) {
+		return m, nil
+	}
+	kind := m.searchResults[m.searchIndex].kind
+	page := m.searchPages[kind]
+	if page.next == 0 || page.loading {
+		return m, nil
+	}
+	last := m.searchIndex
+	for last+1 < len(m.searchResults) && m.searchResults[last+1].kind == kind {
+		last++
+	}
+	if last-m.searchIndex >= searchMorePrefetch {
+		return m, nil
+	}
+	m.searchPages[kind].loading = true
+	return m, m.performSearchMore(m.searchSeq, m.searchedQuery, kind, page.next)
+}
+
+// appendSearchResults は続きのページの結果をセクションの最後に加える
+// 選択中の結果が変わらないよう、後ろのセクションを選んでいた場合は位置をずらす
+func (m Model) appendSearchResults(kind searchKind, r *spotify.SearchResults) Model {
+	var added []searchEntry
+	for _, e := range searchEntries(r) {
+		if e.kind == kind {
+			added = append(added, e)
+		}
+	}
+	at := 0
+	for i, e := range m.searchResults {
+		if e.kind == kind {
+			at = i + 1
+		}
+	}
+	m.searchResults = slices.Concat(m.searchResults[:at], added, m.searchResults[at:])
+	if m.searchIndex >= at {
+		m.searchIndex += len(added)
+	}
+
+	page := &m.searchPages[kind]
+	page.loading = false
+	page.next = 0
+	if p, ok := r.Paging[searchSectionType(kind)]; ok {
+		page.total, page.next = p.Total, p.Next
+	}
+	return m
+}
+
+// searchSectionType は検索結果の種類を API で検索する種類に変換する
+func searchSectionType(kind searchKind) spotifysdk.SearchType {
+	for _, section := range searchSections {
+		if section.kind == kind {
+			return section.searchType
+		}
+	}
+	return 0
+}
+
+// searchSectionStart は index の結果から step 個先（負なら前）のセクションの先頭の位置を返す
+func searchSectionStart(entries []searchEntry, index, step int) int {
+	if len(entries) == 0 {
+		return 0
+	}
+	// セクションの先頭の位置を集める
+	var starts []int
+	for i, e := range entries {
+		if i == 0 || entries[i-1].kind != e.kind {
+			starts = append(starts, i)
+		}
+	}
+	current := 0
+	for i, start := range starts {
+		if start <= index {
+			current = i
+		}
+	}
+	next := (current + step + len(starts)) % len(starts)
+	return starts[next]
+}
+
+// openSearchEntry は選択した検索結果を開く
+// トラックとエピソードはその曲だけを再生し、アーティストとアルバムはそのページを開く
+// アルバムとプレイリストはコンテキストとして再生する（プレイリストはメインパネルに曲を読み込む）
+func (m Model) openSearchEntry(e searchEntry) (Model, tea.Cmd) {
+	var open tea.Cmd
+	switch e.kind {
+	case searchTrack, searchEpisode:
+		return m, m.playTrackAlone(e.uri)
+	case searchShow:
+		return m, m.playContext(e.uri, e.name)
+	case searchArtist:
+		return m.openArtist(e.id)
+	case searchAlbum:
+		m, open = m.openAlbum(e.id)
+	case searchPlaylist:
+		m.searchMode = false
+		m.searchInput.Blur()
+		m.focus = FocusMain
+		m, open = m.openPlaylist(playlistItem{id: string(e.id), name: e.name})
+	}
+	return m, tea.Batch(open, m.playContext(e.uri, e.name))
+}
+
+// searchFilters は検索語に書ける Spotify のフィールドフィルタと入力例
+var searchFilters = []struct {
+	name    string
+	example string
+}{
+	{"artist", "artist:Radiohead"},
+	{"album", "album:\"OK Computer\""},
+	{"track", "track:Creep"},
+	{"year", "year:1997 or year:1990-1999"},
+	{"genre", "genre:rock"},
+}
+
+// newSearchInput は検索語の入力欄を作る
+// 1文字削除のキーは設定の search_delete に合わせ、候補の選択は使わない（↑/↓は履歴と結果の移動に使う）
+func newSearchInput(km keyMap) textinput.Model {
+	input := textinput.New()
+	input.Prompt = " Query: "
+	input.KeyMap.DeleteCharacterBackward = km.binding(actSearchDelete)
+	input.KeyMap.AcceptSuggestion = key.NewBinding(key.WithDisabled())
+	input.KeyMap.NextSuggestion = key.NewBinding(key.WithDisabled())
+	input.KeyMap.PrevSuggestion = key.NewBinding(key.WithDisabled())
+	input.Cursor.SetMode(cursor.CursorStatic)
+	return input
+}
+
+// lastSearchToken は検索語の最後の単語を返す（空白で終わっていれば空）
+func lastSearchToken(query string) string {
+	i := strings.LastIndexAny(query, " \t")
+	return query[i+1:]
+}
+
+// filterCompletion は入力途中のフィルタ名（"ar" など）を補完する残りの文字列（"tist:"）を返す
+// 補完できるのはカーソルが末尾にあり、最後の単語がちょうど1つのフィルタ名の先頭に一致するときだけ
+func filterCompletion(input textinput.Model) string {
+	query := input.Value()
+	if input.Position() != len([]rune(query)) {
+		return ""
+	}
+	token := strings.ToLower(lastSearchToken(query))
+	if token == "" || strings.Contains(token, ":") {
+		return ""
+	}
+	completion := ""
+	for _, f := range searchFilters {
+		if strings.HasPrefix(f.name, token) {
+			if completion != "" {
+				return ""
+			}
+			completion = f.name[len(token):] + ":"
+		}
+	}
+	return completion
+}
+
+// filterExample は最後の単語が値のないフィルタ（"year:"）ならその入力例を返す
+func filterExample(query string) string {
+	token := strings.ToLower(lastSearchToken(query))
+	for _, f := range searchFilters {
+		if token == f.name+":" {
+			return f.example
+		}
+	}
+	return ""
+}
+
+// searchHint は入力欄の下に表示する補完や入力例
+func (m Model) searchHint() string {
+	query := m.searchInput.Value()
+	if completion := filterCompletion(m.searchInput); completion != "" && m.keys.binding(actSearchNextSection).Enabled() {
+		return fmt.Sprintf("%s%s  (%s to complete)", lastSearchToken(query), completion, m.keys.binding(actSearchNextSection).Help().Key)
+	}
+	if example := filterExample(query); example != "" {
+		return "e.g. " + example
+	}
+	if strings.TrimSpace(query) == "" {
+		names := make([]string, len(searchFilters))
+		for i, f := range searchFilters {
+			names[i] = f.name + ":"
+		}
+		hint := "Filters: " + strings.Join(names, " ")
+		if len(m.searchHistory) > 0 {
+			hint += fmt.Sprintf("  (%s for history)", m.keys.binding(actSearchUp).Help().Key)
+		}
+		return hint
+	}
+	return ""
+}
+
+// searchReady は入力中の検索語をそのまま検索してよいかを返す
+// "year:" のように値を入力する前のフィルタで検索すると意味のない結果になるので待つ
+func searchReady(query string) bool {
+	return strings.TrimSpace(query) != "" && !strings.HasSuffix(lastSearchToken(query), ":")
+}
+
+// addSearchHistory は検索語を履歴の先頭に加える（同じ検索語は先頭に移す）
+func addSearchHistory(history []string, query string) []string {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return history
+	}
+	next := make([]string, 0, len(history)+1)
+	next = append(next, query)
+	for _, h := range history {
+		if h != query {
+			next = append(next, h)
+		}
+	}
+	if len(next) > config.MaxSearchHistory {
+		next = next[:config.MaxSearchHistory]
+	}
+	return next
+}
+
+// browseHistory は検索履歴を step だけ移動して入力欄に表示する（1で古い方、-1で新しい方）
+// 最新より新しい方へ進むと、履歴を見る前に入力していた検索語に戻る
+func (m Model) browseHistory(step int) (Model, tea.Cmd) {
+	index := m.historyIndex + step
+	if index >= len(m.searchHistory) {
+		return m, nil
+	}
+	if m.historyIndex < 0 {
+		m.historyDraft = m.searchInput.Value()
+	}
+	m.historyIndex = max(index, -1)
+	if m.historyIndex < 0 {
+		m.searchInput.SetValue(m.historyDraft)
+	} else {
+		m.searchInput.SetValue(m.searchHistory[m.historyIndex])
+	}
+	m.searchInput.CursorEnd()
+	return m.scheduleSearch()
+}
+
+// updateSearchInput は入力欄にメッセージを渡し、検索語が変わったら検索を予約する
+func (m Model) updateSearchInput(msg tea.Msg) (Model, tea.Cmd) {
+	before := m.searchInput.Value()
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	if m.searchInput.Value() == before {
+		return m, cmd
+	}
+	m.historyIndex = -1
+	var search tea.Cmd
+	m, search = m.scheduleSearch()
+	return m, tea.Batch(cmd, search)
+}
+
+// scheduleSearch は入力が止まってから searchDebounce 後に検索する
+// 入力のたびに番号を進め、最後の入力の番号と一致したときだけ検索する
+func (m Model) scheduleSearch() (Model, tea.Cmd) {
+	m.searchSeq++
+	query := m.searchInput.Value()
+	if strings.TrimSpace(query) == "" {
+		// 検索語を消したら結果も消す
+		m.searchedQuery = ""
+		m.searchResults = nil
+		m.searchIndex = 0
+		m.searchPages = [searchKindCount]searchPage{}
+	}
+	if !searchReady(query) {
+		return m.cancelSearch(), nil
+	}
+	seq := m.searchSeq
+	return m, tick(searchDebounce, func(time.Time) tea.Msg {
+		return searchFireMsg(seq)
+	})
+}
+
+// startSearch は入力中の検索語ですぐに検索し、実行中の前の検索を中断する
+func (m Model) startSearch() (Model, tea.Cmd) {
+	query := strings.TrimSpace(m.searchInput.Value())
+	m.searchSeq++
+	if query == "" {
+		return m.cancelSearch(), nil
+	}
+	if m.searchCancel != nil {
+		m.searchCancel()
+	}
+	var ctx context.Context
+	ctx, m.searchCancel = context.WithCancel(m.ctx)
+	return m, m.performSearch(ctx, m.searchSeq, query)
+}
+
+// cancelSearch は実行中の検索を中断する（届いた結果は番号が合わないので捨てられる）
+func (m Model) cancelSearch() Model {
+	if m.searchCancel != nil {
+		m.searchCancel()
+		m.searchCancel = nil
+	}
+	return m
+}
+
+// openSearch は検索モードに入り、入力欄にフォーカスする
+func (m Model) openSearch() Model {
+	m.searchMode = true
+	m.historyIndex = -1
+	m.searchInput.Focus()
+	return m
+}
+
+// closeSearch は検索モードを終了し、検索語と結果を消す
+func (m Model) closeSearch() Model {
+	m = m.cancelSearch()
+	m.searchSeq++
+	m.searchMode = false
+	m.searchInput.Reset()
+	m.searchInput.Blur()
+	m.searchedQuery = ""
+	m.searchResults = nil
+	m.searchIndex = 0
+	m.searchPages = [searchKindCount]searchPage{}
+	m.historyIndex = -1
+	return m
+}
+
+// saveSearchHistory は検索履歴をファイルに書き込む
+func (m Model) saveSearchHistory() tea.Cmd {
+	profile, history := m.profile, slices.Clone(m.searchHistory)
+	return func() tea.Msg {
+		if err := config.SaveSearchHistory(profile, history); err != nil {
+			return errorMsg("Failed to save search history: " + err.Error())
+		}
+		return nil
+	}
+}
+
+// loadSearchHistory は検索履歴をファイルから読み込む
+func (m Model) loadSearchHistory() tea.Cmd {
+	profile := m.profile
+	return func() tea.Msg {
+		history, err := config.LoadSearchHistory(profile)
+		if err != nil {
+			return errorMsg("Failed to load search history: " + err.Error())
+		}
+		return searchHistoryMsg(history)
+	}
+}
+
+// recordSearch は検索語を履歴に加えて保存する
+func (m Model) recordSearch(query string) (Model, tea.Cmd) {
+	history := addSearchHistory(m.searchHistory, query)
+	if slices.Equal(history, m.searchHistory) {
+		return m, nil
+	}
+	m.searchHistory = history
+	return m, m.saveSearchHistory()
+}
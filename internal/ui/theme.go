@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"spotify-tui/internal/config"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme は配色と、それから作る各部品のスタイル
+// 描画関数とデリゲートは Model の theme を使い、テーマを切り替えるとすぐに反映される
+type Theme struct {
+	Name string
+
+	Primary   lipgloss.TerminalColor
+	Text      lipgloss.TerminalColor
+	Muted     lipgloss.TerminalColor
+	Highlight lipgloss.TerminalColor
+	Error     lipgloss.TerminalColor
+
+	Title         lipgloss.Style // パネルの見出し
+	Panel         lipgloss.Style // 上段のパネルの枠
+	FocusedPanel  lipgloss.Style // フォーカスのあるパネルの枠
+	PlayerBar     lipgloss.Style // 下段のパネルとオーバーレイの枠
+	Track         lipgloss.Style
+	SelectedTrack lipgloss.Style
+	SelectedMuted lipgloss.Style // 選択中の項目の2行目（アーティスト名など）
+	PlayingTrack  lipgloss.Style
+	MutedText     lipgloss.Style
+	ErrorText     lipgloss.Style
+}
+
+// NewTheme は配色からスタイルを作る
+// 色のない項目は、選択を反転表示、フォーカスを太い枠にして色がなくても区別できるようにする
+func NewTheme(def config.ThemeDef) Theme {
+	c := def.Colors
+	t := Theme{
+		Name:      def.Name,
+		Primary:   themeColor(c.Primary),
+		Text:      themeColor(c.Text),
+		Muted:     themeColor(c.Muted),
+		Highlight: themeColor(c.Highlight),
+		Error:     themeColor(c.Error),
+	}
+
+	t.Title = lipgloss.NewStyle().
+		Foreground(t.Primary).
+		Bold(true)
+
+	t.Panel = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(t.Muted).
+		Padding(0)
+
+	t.FocusedPanel = t.Panel.
+		BorderForeground(t.Primary)
+	if c.Primary == "" {
+		t.FocusedPanel = t.FocusedPanel.BorderStyle(lipgloss.ThickBorder())
+	}
+
+	t.PlayerBar = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(t.Primary).
+		Padding(0, 1)
+
+	t.Track = lipgloss.NewStyle().
+		Foreground(t.Text)
+
+	t.SelectedTrack = lipgloss.NewStyle().
+		Foreground(t.Primary).
+		Bold(true).
+		Background(t.Highlight)
+
+	t.SelectedMuted = lipgloss.NewStyle().
+		Foreground(t.Muted).
+		Background(t.Highlight)
+	if c.Highlight == "" {
+		t.SelectedTrack = t.SelectedTrack.Reverse(true)
+		t.SelectedMuted = t.SelectedMuted.Reverse(true)
+	}
+
+	t.PlayingTrack = lipgloss.NewStyle().
+		Foreground(t.Primary).
+		Bold(true)
+
+	t.MutedText = lipgloss.NewStyle().
+		Foreground(t.Muted)
+
+	t.ErrorText = lipgloss.NewStyle().
+		Foreground(t.Error).
+		Bold(true)
+
+	return t
+}
+
+// themeColor は設定の色を lipgloss の色に変換する（空なら色を付けない）
+func themeColor(v string) lipgloss.TerminalColor {
+	if v == "" {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.Color(v)
+}
+
+// newPlaylistDelegate はサイドバーのプレイリスト一覧用のデリゲートをテーマの色で作る
+func newPlaylistDelegate(t Theme) list.DefaultDelegate {
+	delegate := list.NewDefaultDelegate()
+	delegate.SetSpacing(0) // アイテム間のスペースを0に
+
+	s := &delegate.Styles
+	s.NormalTitle = s.NormalTitle.Foreground(t.Text)
+	s.NormalDesc = s.NormalDesc.Foreground(t.Muted)
+	s.SelectedTitle = s.SelectedTitle.Foreground(t.Primary).BorderLeftForeground(t.Primary).Bold(true)
+	s.SelectedDesc = s.SelectedDesc.Foreground(t.Muted).BorderLeftForeground(t.Primary)
+	s.DimmedTitle = s.DimmedTitle.Foreground(t.Muted)
+	s.DimmedDesc = s.DimmedDesc.Foreground(t.Muted)
+	return delegate
+}
+
+// setTheme はテーマを切り替え、リストのデリゲートと検索の入力欄のスタイルを作り直す
+func (m Model) setTheme(def config.ThemeDef) Model {
+	m.theme = NewTheme(def)
+	m.playlists.SetDelegate(newPlaylistDelegate(m.theme))
+	m.trackList.SetDelegate(NewTrackDelegate(m.theme))
+	m.queueList.SetDelegate(NewQueueDelegate(m.theme))
+	m.searchInput.PromptStyle = m.theme.Track
+	m.searchInput.TextStyle = m.theme.Track
+	m.searchInput.PlaceholderStyle = m.theme.MutedText
+	m.searchInput.Cursor.Style = m.theme.Track
+	return m
+}
+
+// nextTheme は themes の中で現在のテーマの次のテーマに切り替える
+// 設定ファイルで色を上書きしたテーマも、切り替えた後は元の配色になる
+func (m Model) nextTheme() Model {
+	if len(m.themes) == 0 {
+		return m
+	}
+	next := 0
+	for i, t := range m.themes {
+		if t.Name == m.theme.Name {
+			next = (i + 1) % len(m.themes)
+			break
+		}
+	}
+	return m.setTheme(m.themes[next])
+}
@@ -1,10 +1,14 @@
 package ui
 
 import (
+	"context"
+	"slices"
+	"spotify-tui/internal/config"
 	"spotify-tui/internal/logger"
+	"spotify-tui/internal/spotify"
+	"strings"
 	"time"
 
-	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	spotifysdk "github.com/zmb3/spotify/v2"
@@ -15,75 +19,304 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		key := msg.String()
+		k := msg.String()
+		if k == reservedKey {
+			if m.loginCancel != nil {
+				m.loginCancel()
+			}
+			return m, tea.Quit
+		}
+		single := []string{k}
+
+		// ログイン待ちの間はキャンセルだけ受け付ける
+		if m.loginURL != "" {
+			if a, _ := m.keys.match(scopePicker, single); a == actPickerClose {
+				m.loginCancel()
+			}
+			return m, nil
+		}
+
+		// ヘルプ表示中は閉じるキーだけ受け付ける
+		if m.showHelp {
+			pa, _ := m.keys.match(scopePicker, single)
+			ma, _ := m.keys.match(scopeMain, single)
+			if pa == actPickerClose || ma == actHelp || ma == actQuit {
+				m.showHelp = false
+			}
+			return m, nil
+		}
+
+		// アカウント選択中は特別処理
+		if m.showAccounts {
+			a, _ := m.keys.match(scopePicker, single)
+			if ma, _ := m.keys.match(scopeMain, single); ma == actAccounts {
+				a = actPickerClose
+			}
+			switch a {
+			case actPickerClose:
+				m.showAccounts = false
+			case actPickerUp:
+				if m.accountIndex > 0 {
+					m.accountIndex--
+				}
+			case actPickerDown:
+				if m.accountIndex < len(m.accountNames)-1 {
+					m.accountIndex++
+				}
+			case actPickerSelect:
+				if m.accountIndex >= len(m.accountNames) {
+					return m, nil
+				}
+				m.showAccounts = false
+				name := m.accountNames[m.accountIndex]
+				if name == m.profile {
+					return m, nil
+				}
+				return m, m.openAccount(name)
+			}
+			return m, nil
+		}
+
+		// デバイス選択中は特別処理
+		if m.showDevices {
+			a, _ := m.keys.match(scopePicker, single)
+			if ma, _ := m.keys.match(scopeMain, single); ma == actDevices {
+				a = actPickerClose
+			}
+			switch a {
+			case actPickerClose:
+				m.showDevices = false
+				m.deviceRetry = nil
+			case actPickerUp:
+				if m.deviceIndex > 0 {
+					m.deviceIndex--
+				}
+			case actPickerDown:
+				if m.deviceIndex < len(m.devices)-1 {
+					m.deviceIndex++
+				}
+			case actPickerSelect, actTransferOnly:
+				// 選択は移した先で再生を開始し、transfer_only は再生状態を変えずに移す
+				if m.deviceIndex >= len(m.devices) {
+					return m, nil
+				}
+				device := m.devices[m.deviceIndex]
+				if device.Restricted {
+					return m, func() tea.Msg {
+						return errorMsg(device.Name + " is restricted and cannot be controlled")
+					}
+				}
+				retry := m.deviceRetry
+				if a == actTransferOnly {
+					retry = nil
+				}
+				return m, m.transferPlayback(device.ID, a == actPickerSelect, retry)
+			}
+			return m, nil
+		}
+
+		// プレイリストの名前の入力中は、確定と取り消し以外のキーを入力欄で処理する
+		if m.namePrompt != promptNone {
+			switch a, _ := m.keys.match(scopeSearch, single); a {
+			case actSearchCancel:
+				return m.closeNamePrompt(), nil
+			case actSearchSubmit:
+				return m.submitNamePrompt()
+			}
+			var cmd tea.Cmd
+			m.nameInput, cmd = m.nameInput.Update(msg)
+			return m, cmd
+		}
+
+		// プレイリスト選択中は特別処理
+		if m.showPlaylistPicker {
+			a, _ := m.keys.match(scopePicker, single)
+			switch a {
+			case actPickerClose:
+				m.showPlaylistPicker = false
+			case actPickerUp:
+				if m.pickerIndex > 0 {
+					m.pickerIndex--
+				}
+			case actPickerDown:
+				if m.pickerIndex < len(m.pickerPlaylists)-1 {
+					m.pickerIndex++
+				}
+			case actPickerSelect:
+				if m.pickerIndex >= len(m.pickerPlaylists) {
+					return m, nil
+				}
+				m.showPlaylistPicker = false
+				return m.addToPlaylist(m.pickerPlaylists[m.pickerIndex])
+			}
+			return m, nil
+		}
 
 		// searchMode中は特別処理
 		if m.searchMode {
-			switch key {
-			case "esc":
-				m.searchMode = false
-				m.searchQuery = ""
-				m.searchResults = nil
-				m.searchIndex = 0
+			a, _ := m.keys.match(scopeSearch, single)
+			switch a {
+			case actSearchCancel:
+				return m.closeSearch(), nil
+			case actSearchSubmit:
+				// 表示中の結果が入力中の検索語のものなら選択した結果を開き、
+				// そうでなければ（入力直後や履歴を選んだときは）すぐに検索する
+				query := strings.TrimSpace(m.searchInput.Value())
+				var open, save tea.Cmd
+				if m.historyIndex < 0 && query != "" && query == m.searchedQuery && len(m.searchResults) > 0 {
+					m, open = m.openSearchEntry(m.searchResults[m.searchIndex])
+				} else {
+					m.historyIndex = -1
+					m, open = m.startSearch()
+				}
+				m, save = m.recordSearch(query)
+				return m, tea.Batch(open, save)
+			case actSearchUp:
+				// 入力が空のときと履歴を見ているときは履歴をさかのぼる
+				if m.historyIndex >= 0 || m.searchInput.Value() == "" {
+					return m.browseHistory(1)
+				}
+				if m.searchIndex > 0 {
+					m.searchIndex--
+				}
 				return m, nil
-			case "enter":
-				// 検索結果がある場合は再生、ない場合は検索実行
-				if len(m.searchResults) > 0 {
-					track := m.searchResults[m.searchIndex]
-					return m, m.playTrackAlone(track.URI)
-				} else if m.searchQuery != "" {
-					return m, m.performSearch(m.searchQuery)
+			case actSearchDown:
+				if m.historyIndex >= 0 {
+					return m.browseHistory(-1)
+				}
+				if m.searchIndex < len(m.searchResults)-1 {
+					m.searchIndex++
+				}
+				// セクションの最後に近づいたら続きを読み込む
+				return m.loadMoreSearch()
+			case actSearchNextSection:
+				// 入力途中のフィルタ名があれば補完する
+				if completion := filterCompletion(m.searchInput); completion != "" {
+					m.searchInput.SetValue(m.searchInput.Value() + completion)
+					m.searchInput.CursorEnd()
+					m.historyIndex = -1
+					return m.scheduleSearch()
+				}
+				m.searchIndex = searchSectionStart(m.searchResults, m.searchIndex, 1)
+				return m.loadMoreSearch()
+			case actSearchPrevSection:
+				m.searchIndex = searchSectionStart(m.searchResults, m.searchIndex, -1)
+				return m.loadMoreSearch()
+			case actSearchArtist:
+				if m.searchIndex < len(m.searchResults) {
+					return m.openArtist(m.searchResults[m.searchIndex].artistID)
 				}
 				return m, nil
-			case "backspace", "ctrl+h":
-				if len(m.searchQuery) > 0 {
-					m.searchQuery = m.searchQuery[:len(m.searchQuery)-1]
+			case actSearchAlbum:
+				if m.searchIndex < len(m.searchResults) {
+					return m.openAlbum(m.searchResults[m.searchIndex].albumID)
 				}
 				return m, nil
-			case "up":
-				if len(m.searchResults) > 0 && m.searchIndex > 0 {
-					m.searchIndex--
+			case actSearchLike:
+				if m.searchIndex < len(m.searchResults) {
+					return m.toggleLike(m.searchResults[m.searchIndex].uri)
 				}
 				return m, nil
-			case "down":
-				if len(m.searchResults) > 0 && m.searchIndex < len(m.searchResults)-1 {
-					m.searchIndex++
+			case actSearchAddToQueue:
+				if track, ok := m.selectedQueueTrack(); ok {
+					return m.addToQueue(track)
 				}
 				return m, nil
-			default:
-				// 通常の文字を追加
-				if len(key) == 1 {
-					m.searchQuery += key
+			case actSearchAddToUpNext:
+				if track, ok := m.selectedQueueTrack(); ok {
+					return m.addToUpNext(track, false)
+				}
+				return m, nil
+			case actSearchAddToPlaylist:
+				if m.searchIndex < len(m.searchResults) && m.searchResults[m.searchIndex].kind == searchTrack {
+					return m.openPlaylistPicker(m.searchResults[m.searchIndex].uri)
 				}
+				return m, nil
+			}
+			// その他のキー（文字、カーソル移動、単語削除、貼り付けなど）は入力欄で処理する
+			return m.updateSearchInput(msg)
+		}
+
+		// chord（"g l" など）の途中なら続きのキーを待つ
+		seq := append(m.pendingKeys[:len(m.pendingKeys):len(m.pendingKeys)], k)
+		a, prefix := m.keys.match(scopeMain, seq)
+		if a == "" && !prefix && len(m.pendingKeys) > 0 {
+			// chord にならなかった場合は、最後のキーだけで改めて照合する
+			m.pendingKeys = nil
+			if k == "esc" {
+				return m, nil
 			}
+			seq = single
+			a, prefix = m.keys.match(scopeMain, seq)
+		}
+		if a == "" && prefix {
+			m.pendingKeys = seq
 			return m, nil
 		}
+		m.pendingKeys = nil
 
 		// グローバルキーを先に処理（listに渡さない）
 		var cmd tea.Cmd
-		switch key {
-		case "q", "ctrl+c":
+		switch a {
+		case actQuit:
 			return m, tea.Quit
 
-		case " ":
-			cmd = m.togglePlayPause()
+		case actHelp:
+			m.showHelp = true
+
+		case actAccounts:
+			if m.accounts != nil {
+				m.showAccounts = true
+				cmd = m.fetchAccounts()
+			}
 
-		case "n":
+		case actRelogin:
+			if m.reloginRequired && m.reauth != nil && m.loginCancel == nil {
+				ctx, cancel := context.WithCancel(m.ctx)
+				m.loginCancel = cancel
+				cmd = m.startLogin(ctx)
+			}
+
+		case actTheme:
+			m = m.nextTheme()
+			logger.Info("Switched theme", "theme", m.theme.Name)
+
+		case actPlayPause:
+			if m.activeDevice == nil && len(m.devices) > 0 {
+				// アクティブなデバイスがない場合は再生先を選ばせる
+				m.showDevices = true
+				m.deviceIndex = 0
+				cmd = m.fetchDevices()
+			} else {
+				cmd = m.togglePlayPause()
+			}
+
+		case actDevices:
+			m.showDevices = true
+			m.deviceIndex = 0
+			for i, d := range m.devices {
+				if d.Active {
+					m.deviceIndex = i
+				}
+			}
+			cmd = m.fetchDevices()
+
+		case actNext:
 			cmd = m.nextTrack()
 
-		case "p":
+		case actPrevious:
 			cmd = m.previousTrack()
 
-		case "s":
+		case actShuffle:
 			m.shuffle = !m.shuffle
 			cmd = func() tea.Msg {
 				if err := m.client.ToggleShuffle(m.ctx, m.shuffle); err != nil {
-					return errorMsg(err.Error())
+					return apiError(err)
 				}
 				return nil
 			}
 
-		case "r":
+		case actRepeat:
 			states := []string{"off", "context", "track"}
 			for i, s := range states {
 				if s == m.repeatState {
@@ -93,49 +326,154 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			cmd = func() tea.Msg {
 				if err := m.client.SetRepeat(m.ctx, m.repeatState); err != nil {
-					return errorMsg(err.Error())
+					return apiError(err)
 				}
 				return nil
 			}
 
-		case "tab":
-			// フォーカス切り替え（Sidebar -> Main -> Queue -> Sidebar）
-			switch m.focus {
-			case FocusSidebar:
-				m.focus = FocusMain
-			case FocusMain:
-				m.focus = FocusQueue
-			case FocusQueue:
-				m.focus = FocusSidebar
+		case actSeekBackward:
+			m, cmd = m.seekTo(m.progress - 5*time.Second)
+
+		case actSeekForward:
+			m, cmd = m.seekTo(m.progress + 5*time.Second)
+
+		case actSeekBackwardLong:
+			m, cmd = m.seekTo(m.progress - 30*time.Second)
+
+		case actSeekForwardLong:
+			m, cmd = m.seekTo(m.progress + 30*time.Second)
+
+		case actSeekPercent:
+			// n 番目のキーで n×10% の位置にジャンプ（11番目以降のキーは使わない）
+			if i := m.keys.keyIndex(a, seq); i >= 0 && i < 10 {
+				m, cmd = m.seekTo(m.duration * time.Duration(i*10) / 100)
 			}
-			return m, nil
 
-		case "shift+tab":
+		case actVolumeUp:
+			m, cmd = m.setVolume(m.volume + m.volumeStep)
+
+		case actVolumeDown:
+			m, cmd = m.setVolume(m.volume - m.volumeStep)
+
+		case actMute:
+			m, cmd = m.toggleMute()
+
+		case actFocusNext:
+			// フォーカス切り替え（Sidebar -> Main -> Queue -> Sidebar、隠したパネルは飛ばす）
+			return m.cycleFocus(1), nil
+
+		case actFocusPrev:
 			// 逆方向のフォーカス切り替え
+			return m.cycleFocus(-1), nil
+
+		case actLayoutMode:
+			// Auto -> Columns -> Stacked -> Mini -> Auto
+			m.layoutMode = (m.layoutMode + 1) % (LayoutMini + 1)
+			m = m.resizeLists()
+
+		case actToggleSidebar:
+			m = m.togglePanel(FocusSidebar)
+
+		case actToggleQueue:
+			m = m.togglePanel(FocusQueue)
+
+		case actGrowPanel:
+			m = m.resizePanel(panelResizeStep)
+
+		case actShrinkPanel:
+			m = m.resizePanel(-panelResizeStep)
+
+		case actSearch:
+			return m.openSearch(), nil
+
+		case actArtist:
+			m, cmd = m.openArtist(m.selectedTrackArtist())
+
+		case actPlayingArtist:
+			m, cmd = m.openArtist(m.playingArtist())
+
+		case actAlbum:
+			m, cmd = m.openAlbum(m.selectedTrackAlbum())
+
+		case actPlayingAlbum:
+			m, cmd = m.openAlbum(m.playingAlbum())
+
+		case actBack:
+			m, cmd = m.goBack()
+
+		case actLike:
+			m, cmd = m.toggleLike(m.selectedLibraryURI())
+
+		case actLikeAlbum:
+			m, cmd = m.toggleLike(albumURI(m.selectedTrackAlbum()))
+
+		case actLikePlaying:
+			m, cmd = m.toggleLike(spotifysdk.URI(m.playingTrackURI))
+
+		case actCreatePlaylist:
+			m, cmd = m.openNamePrompt(promptCreate)
+
+		case actRenamePlaylist:
+			m, cmd = m.openNamePrompt(promptRename)
+
+		case actRemove:
+			// サイドバーではプレイリストを、メインパネルでは選択中の曲を、キューでは Up Next の曲を削除する
 			switch m.focus {
 			case FocusSidebar:
-				m.focus = FocusQueue
-			case FocusMain:
-				m.focus = FocusSidebar
+				m, cmd = m.deleteSelectedPlaylist()
 			case FocusQueue:
-				m.focus = FocusMain
+				m, cmd = m.removeUpNext()
+			default:
+				m, cmd = m.removeSelectedTrack()
 			}
-			return m, nil
 
-		case "/":
-			m.searchMode = true
-			return m, nil
+		case actAddToQueue:
+			if track, ok := m.selectedQueueTrack(); ok {
+				m, cmd = m.addToQueue(track)
+			}
 
-		case "enter":
-			if m.focus == FocusSidebar {
+		case actAddToUpNext:
+			if track, ok := m.selectedQueueTrack(); ok {
+				m, cmd = m.addToUpNext(track, false)
+			}
+
+		case actPlayNext:
+			if track, ok := m.selectedQueueTrack(); ok {
+				m, cmd = m.addToUpNext(track, true)
+			}
+
+		case actClearUpNext:
+			m, cmd = m.clearUpNext()
+
+		case actAddToPlaylist:
+			m, cmd = m.openPlaylistPicker(m.selectedTrackURI())
+
+		case actAddPlayingToPlaylist:
+			m, cmd = m.openPlaylistPicker(spotifysdk.URI(m.playingTrackURI))
+
+		case actMoveUp:
+			if m.focus == FocusQueue {
+				m, cmd = m.moveUpNext(-1)
+			} else {
+				m, cmd = m.moveSelectedTrack(-1)
+			}
+
+		case actMoveDown:
+			if m.focus == FocusQueue {
+				m, cmd = m.moveUpNext(1)
+			} else {
+				m, cmd = m.moveSelectedTrack(1)
+			}
+
+		case actUndo:
+			m, cmd = m.undoLast()
+
+		case actSelect:
+			if m.focus == FocusMain && m.view != viewTracks {
+				m, cmd = m.openPageEntry()
+			} else if m.focus == FocusSidebar {
 				if item, ok := m.playlists.SelectedItem().(playlistItem); ok {
-					m.loadingTracks = true
-					m.currentPlaylistName = item.name
-					if item.id == "liked" {
-						cmd = m.fetchSavedTracks()
-					} else {
-						cmd = m.fetchPlaylistTracks(spotifysdk.ID(item.id))
-					}
+					m, cmd = m.openPlaylist(item)
 				}
 			} else if m.focus == FocusMain && len(m.tracks) > 0 {
 				// プレイリストのコンテキストで再生
@@ -148,20 +486,66 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmd = m.skipToQueueIndex(selectedIdx)
 			}
 
-		case "up", "k", "down", "j":
-			if m.focus == FocusSidebar {
-				m.playlists, cmd = m.playlists.Update(msg)
+		case actUp:
+			// list のキー割り当てに依存しないよう、カーソルを直接動かす
+			if m.focus == FocusMain && m.view != viewTracks {
+				index, _ := m.pageSelection()
+				m, cmd = m.movePageSelection(index - 1)
+			} else if m.focus == FocusSidebar {
+				m.playlists.CursorUp()
 			} else if m.focus == FocusMain {
-				m.trackList, cmd = m.trackList.Update(msg)
+				m.trackList.CursorUp()
 			} else if m.focus == FocusQueue {
-				m.queueList, cmd = m.queueList.Update(msg)
+				m.queueList.CursorUp()
 			}
 
+		case actDown:
+			if m.focus == FocusMain && m.view != viewTracks {
+				index, _ := m.pageSelection()
+				m, cmd = m.movePageSelection(index + 1)
+			} else if m.focus == FocusSidebar {
+				m.playlists.CursorDown()
+			} else if m.focus == FocusMain {
+				m.trackList.CursorDown()
+			} else if m.focus == FocusQueue {
+				m.queueList.CursorDown()
+			}
+
+		case actGoTop:
+			if m.focus == FocusMain && m.view != viewTracks {
+				m, cmd = m.movePageSelection(0)
+				break
+			}
+			m.focusedList().Select(0)
+
+		case actGoBottom:
+			if m.focus == FocusMain && m.view != viewTracks {
+				_, count := m.pageSelection()
+				m, cmd = m.movePageSelection(count - 1)
+				break
+			}
+			l := m.focusedList()
+			l.Select(len(l.Items()) - 1)
+
+		case actGoLiked:
+			// サイドバーの先頭は常に Liked Songs
+			if len(m.playlists.Items()) > 0 {
+				m.playlists.Select(0)
+				m, cmd = m.openPlaylist(m.playlists.Items()[0].(playlistItem))
+				m.focus = FocusMain
+			}
+
+		case actGoPlaylists:
+			m = m.showPanel(FocusSidebar)
+
+		case actGoQueue:
+			m = m.showPanel(FocusQueue)
+
 		default:
-			// その他のキーはlistに渡す
+			// その他のキーはlistに渡す（アーティストやアルバムのページはlistを使わない）
 			if m.focus == FocusSidebar {
 				m.playlists, cmd = m.playlists.Update(msg)
-			} else if m.focus == FocusMain {
+			} else if m.focus == FocusMain && m.view == viewTracks {
 				m.trackList, cmd = m.trackList.Update(msg)
 			} else if m.focus == FocusQueue {
 				m.queueList, cmd = m.queueList.Update(msg)
@@ -172,13 +556,65 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, cmd)
 		}
 
+	case tea.MouseMsg:
+		if msg.Action != tea.MouseActionPress || msg.Button != tea.MouseButtonLeft {
+			break
+		}
+		// シークバーのクリック位置へシーク
+		x, y, width := m.layout().ProgressBarBounds()
+		if msg.Y == y && msg.X >= x && msg.X < x+width && width > 0 {
+			var cmd tea.Cmd
+			m, cmd = m.seekTo(m.duration * time.Duration(msg.X-x) / time.Duration(width))
+			cmds = append(cmds, cmd)
+		}
+
+	case seekFireMsg:
+		// 最後のシーク操作のみ送信する
+		if int(msg) == m.seekSeq {
+			cmds = append(cmds, m.sendSeek(m.seekSeq, m.progress))
+		}
+
+	case seekDoneMsg:
+		// 送信中に次のシーク操作があった場合は、その送信が終わるまで楽観的に更新した位置を使う
+		if msg.seq == m.seekSeq {
+			m.seekPending = false
+		}
+		if msg.err != nil {
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+		}
+
+	case volumeFireMsg:
+		if int(msg) == m.volumeSeq {
+			cmds = append(cmds, m.sendVolume(m.volumeSeq, m.volume))
+		}
+
+	case volumeDoneMsg:
+		// 送信中に次の音量変更があった場合は、その送信が終わるまで楽観的に更新した音量を使う
+		if msg.seq == m.volumeSeq {
+			m.volumePending = false
+		}
+		if msg.err != nil {
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+		}
+
+	case noActiveDeviceMsg:
+		m.showDevices = true
+		m.deviceIndex = 0
+		m.deviceRetry = msg.retry
+		cmds = append(cmds, m.fetchDevices())
+
+	case deviceTransferredMsg:
+		m.showDevices = false
+		m.deviceRetry = nil
+		cmds = append(cmds, m.fetchDevices(), m.fetchCurrentPlayback())
+		if msg.retry != nil {
+			cmds = append(cmds, msg.retry)
+		}
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		layout := CalculateLayout(msg.Width, msg.Height)
-		m.playlists.SetSize(layout.LeftContentWidth, layout.ListHeight)
-		m.trackList.SetSize(layout.MainContentWidth, layout.ListHeight)
-		m.queueList.SetSize(layout.RightContentWidth, layout.ListHeight)
+		m = m.resizeLists()
 
 	case tickMsg:
 		// シークバーをスムーズに更新
@@ -191,17 +627,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.lastUpdate = time.Now()
 
-		// 再生状態は毎秒取得
-		cmds = append(cmds, tickCmd(), m.fetchCurrentPlayback())
+		cmds = append(cmds, m.tickCmd())
+
+		// 再ログインが必要な間はAPIを呼ばない
+		if m.reloginRequired {
+			break
+		}
 
-		// キューは5秒ごと
-		if time.Since(m.lastQueueFetch) >= 5*time.Second {
+		// 再生状態、キュー、デバイスはそれぞれ設定の間隔で取得（デフォルトは1秒、5秒、10秒）
+		if m.due(m.lastPlaybackFetch, m.polling.Playback) {
+			cmds = append(cmds, m.fetchCurrentPlayback())
+			m.lastPlaybackFetch = time.Now()
+		}
+
+		if m.due(m.lastQueueFetch, m.polling.Queue) {
 			cmds = append(cmds, m.fetchQueue())
 			m.lastQueueFetch = time.Now()
 		}
 
-		// デバイスは10秒ごと
-		if time.Since(m.lastDeviceFetch) >= 10*time.Second {
+		if m.due(m.lastDeviceFetch, m.polling.Devices) {
 			cmds = append(cmds, m.fetchDevices())
 			m.lastDeviceFetch = time.Now()
 		}
@@ -218,76 +662,317 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.trackList.SetItems(m.updateTrackListItems(newPlayingURI))
 					m.trackList.Select(selectedIdx)
 				}
+				var cmd tea.Cmd
+				m, cmd = m.checkLiked(msg.Item.URI)
+				cmds = append(cmds, cmd)
 			}
 			m.isPlaying = msg.Playing
-			m.progress = time.Duration(msg.Progress) * time.Millisecond
+			// シーク送信前は楽観的に更新した位置を優先する
+			if !m.seekPending {
+				m.progress = time.Duration(msg.Progress) * time.Millisecond
+				m.lastUpdate = time.Now()
+			}
 			m.duration = time.Duration(msg.Item.Duration) * time.Millisecond
-			m.lastUpdate = time.Now()
 			m.shuffle = msg.ShuffleState
 			m.repeatState = msg.RepeatState
 		}
+		if msg != nil {
+			// 曲が終わっていれば Up Next の次の曲を再生する
+			cmds = append(cmds, m.observePlayback(msg))
+		}
 
 	case playlistsMsg:
-		// Liked Songsを先頭に追加
-		items := make([]list.Item, 0, len(msg)+1)
-		items = append(items, playlistItem{
-			id:   "liked", // 特別なID
-			name: "💚 Liked Songs",
-		})
-		for _, pl := range msg {
-			items = append(items, playlistItem{
-				id:   string(pl.ID),
-				name: pl.Name,
-			})
+		// 読み込み直した後に届いた前の読み込みのページは捨てる
+		if msg.loadID != m.playlistsLoadID {
+			break
+		}
+		if msg.err != nil {
+			// 残りのページは取得しないので、読み込み中の表示をやめる
+			m.playlistsTotal = m.playlistsCount
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+			break
+		}
+		items := m.playlists.Items()
+		if msg.offset == 0 {
+			// Liked Songsと保存したアルバムを先頭に追加
+			items = []list.Item{
+				playlistItem{
+					id:   "liked", // 特別なID
+					name: "💚 Liked Songs",
+				},
+				playlistItem{
+					id:   "albums", // 特別なID
+					name: "💿 Saved Albums",
+				},
+			}
+			m.playlistsCount = 0
+		}
+		for _, pl := range msg.playlists {
+			items = append(items, newPlaylistItem(pl))
 		}
+		m.playlistsCount += len(msg.playlists)
+		m.playlistsTotal = msg.total
 		m.playlists.SetItems(items)
+		// 起動時に Liked Songs を開く設定の場合
+		if msg.offset == 0 && m.startupView == config.StartupLikedSongs && m.tracksLoadID == 0 {
+			var cmd tea.Cmd
+			m, cmd = m.openPlaylist(items[0].(playlistItem))
+			m.focus = FocusMain
+			cmds = append(cmds, cmd)
+		}
+		// 残りのページを順に取得する
+		if len(msg.playlists) > 0 && m.playlistsCount < msg.total {
+			cmds = append(cmds, m.fetchPlaylistsPage(m.playlistsCount))
+		}
 
 	case tracksMsg:
-		m.tracks = msg.tracks
-		m.currentPlaylistURI = msg.playlistURI
-		m.isLikedSongs = false
-		m.loadingTracks = false
-		// trackListを更新
-		items := make([]list.Item, len(msg.tracks))
+		// 別のプレイリストに切り替えた後に届いた古いページは捨てる
+		if msg.loadID != m.tracksLoadID {
+			break
+		}
+		if msg.err != nil {
+			var cmd tea.Cmd
+			m, cmd = m.tracksLoadFailed(msg.offset, msg.err)
+			cmds = append(cmds, cmd)
+			break
+		}
+		selectedIdx := m.trackList.Index()
+		if msg.offset == 0 {
+			m.tracks = nil
+			m.currentPlaylistURI = spotifysdk.URI("spotify:playlist:" + string(msg.playlistID))
+			m.isLikedSongs = false
+			m.loadingTracks = false
+			selectedIdx = 0
+		}
+		m.tracks = append(m.tracks, msg.tracks...)
+		m.tracksTotal = msg.total
+		// trackListを更新（読み込み済みのページに追加）
+		m.trackList.SetItems(m.buildTrackItems())
+		m.trackList.Select(selectedIdx)
+		if len(msg.tracks) > 0 && len(m.tracks) < msg.total {
+			cmds = append(cmds, m.fetchPlaylistTracks(msg.playlistID, len(m.tracks)))
+		}
+		// 届いたページの曲をまとめて保存済みか問い合わせる
+		uris := make([]spotifysdk.URI, len(msg.tracks))
 		for i, t := range msg.tracks {
-			items[i] = trackItem{
-				index:  i,
-				name:   t.Track.Name,
-				artist: t.Track.Artists[0].Name,
-				uri:    string(t.Track.URI),
-			}
+			uris[i] = t.Track.URI
 		}
-		m.trackList.SetItems(items)
-		m.trackList.Select(0)
+		var cmd tea.Cmd
+		m, cmd = m.checkLiked(uris...)
+		cmds = append(cmds, cmd)
 
 	case savedTracksMsg:
+		if msg.loadID != m.tracksLoadID {
+			break
+		}
+		if msg.err != nil {
+			var cmd tea.Cmd
+			m, cmd = m.tracksLoadFailed(0, msg.err)
+			cmds = append(cmds, cmd)
+			break
+		}
 		// SavedTrackをPlaylistTrack形式に変換
-		tracks := make([]spotifysdk.PlaylistTrack, len(msg))
-		for i, st := range msg {
+		tracks := make([]spotifysdk.PlaylistTrack, len(msg.tracks))
+		for i, st := range msg.tracks {
 			tracks[i] = spotifysdk.PlaylistTrack{
 				Track: st.FullTrack,
 			}
+			// Liked Songs の曲は問い合わせるまでもなく保存済み
+			m.liked[st.URI] = true
+			delete(m.likedPending, st.URI)
 		}
 		m.tracks = tracks
+		m.tracksTotal = len(tracks)
 		m.isLikedSongs = true
 		m.currentPlaylistURI = "" // URIは使用しない
 		m.loadingTracks = false
 		// trackListを更新
-		items := make([]list.Item, len(tracks))
-		for i, t := range tracks {
-			items[i] = trackItem{
-				index:  i,
-				name:   t.Track.Name,
-				artist: t.Track.Artists[0].Name,
-				uri:    string(t.Track.URI),
-			}
-		}
-		m.trackList.SetItems(items)
+		m.trackList.SetItems(m.buildTrackItems())
 		m.trackList.Select(0)
 
+	case searchFireMsg:
+		// 最後の入力から searchDebounce 経過した場合だけ検索する
+		if int(msg) == m.searchSeq && m.searchMode {
+			var cmd tea.Cmd
+			m, cmd = m.startSearch()
+			cmds = append(cmds, cmd)
+		}
+
 	case searchResultsMsg:
-		m.searchResults = msg
+		// 検索語を変えた後に届いた古い検索の結果は捨てる
+		if msg.seq != m.searchSeq {
+			break
+		}
+		m = m.cancelSearch()
+		m.searchedQuery = msg.query
+		m.searchResults = searchEntries(msg.results)
 		m.searchIndex = 0
+		m = m.setSearchPages(msg.results)
+		var more, check tea.Cmd
+		m, more = m.loadMoreSearch()
+		m, check = m.checkLiked(libraryEntryURIs(m.searchResults)...)
+		cmds = append(cmds, more, check)
+
+	case searchMoreMsg:
+		if msg.seq != m.searchSeq {
+			break
+		}
+		if msg.err != nil {
+			m.searchPages[msg.kind].loading = false
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+			break
+		}
+		m = m.appendSearchResults(msg.kind, msg.results)
+		// 削除済みの項目を除いて結果が増えなかった場合などは、さらに続きを読み込む
+		var more, check tea.Cmd
+		m, more = m.loadMoreSearch()
+		m, check = m.checkLiked(libraryEntryURIs(m.searchResults)...)
+		cmds = append(cmds, more, check)
+
+	case searchHistoryMsg:
+		// 読み込みが終わる前に検索した検索語は新しい方に残す
+		history := []string(msg)
+		for _, q := range slices.Backward(m.searchHistory) {
+			history = addSearchHistory(history, q)
+		}
+		m.searchHistory = history
+
+	case artistPageMsg:
+		// 別のページを開いた後に届いた古いページは捨てる
+		if msg.loadID != m.pageLoadID {
+			break
+		}
+		if msg.err != nil {
+			m.artist.loading = false
+			m.artist.failed = true
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+			break
+		}
+		m.artist = msg.page
+		var uris []spotifysdk.URI
+		for _, section := range m.artist.sections {
+			uris = append(uris, libraryEntryURIs(section.entries)...)
+		}
+		var cmd tea.Cmd
+		m, cmd = m.checkLiked(uris...)
+		cmds = append(cmds, cmd)
+
+	case artistAlbumsMsg:
+		if msg.loadID != m.pageLoadID {
+			break
+		}
+		if msg.err != nil {
+			m.artist.sections[msg.section].page.loading = false
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+			break
+		}
+		m.artist = m.artist.appendArtistAlbums(msg.section, msg.albums, msg.offset, msg.total)
+		var cmd tea.Cmd
+		m, cmd = m.checkLiked(libraryEntryURIs(m.artist.sections[msg.section].entries)...)
+		cmds = append(cmds, cmd)
+
+	case albumPageMsg:
+		if msg.loadID != m.pageLoadID {
+			break
+		}
+		if msg.err != nil {
+			m.album.loading = false
+			m.album.failed = true
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+			break
+		}
+		m.album = msg.page
+		uris := []spotifysdk.URI{m.album.uri}
+		for _, t := range m.album.tracks {
+			uris = append(uris, t.URI)
+		}
+		var cmd tea.Cmd
+		m, cmd = m.checkLiked(uris...)
+		cmds = append(cmds, cmd)
+
+	case savedAlbumsMsg:
+		if msg.loadID != m.pageLoadID {
+			break
+		}
+		if msg.err != nil {
+			m.savedAlbums.loading = false
+			m.savedAlbums.albums.page.loading = false
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+			break
+		}
+		m.savedAlbums = m.savedAlbums.appendSavedAlbums(msg.albums, msg.offset, msg.total)
+		// 保存したアルバムは問い合わせるまでもなく保存済み
+		for _, a := range msg.albums {
+			m.liked[a.URI] = true
+			delete(m.likedPending, a.URI)
+		}
+
+	case likedMsg:
+		var cmd tea.Cmd
+		m, cmd = m.setLiked(msg)
+		cmds = append(cmds, cmd)
+		if msg.err != nil {
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+		}
+
+	case likeSavedMsg:
+		if msg.err != nil {
+			// 失敗したので表示を元に戻す
+			m.liked[msg.uri] = !msg.liked
+			m = m.refreshLikedItems()
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+		}
+
+	case playlistEditedMsg:
+		var cmd tea.Cmd
+		m, cmd = m.applyPlaylistEdit(msg)
+		cmds = append(cmds, cmd)
+
+	case playlistCreatedMsg:
+		if msg.err != nil {
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+			break
+		}
+		var cmd tea.Cmd
+		m, cmd = m.addSidebarPlaylist(msg.playlist.SimplePlaylist)
+		cmds = append(cmds, cmd)
+
+	case playlistRenamedMsg:
+		if msg.err != nil {
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+			break
+		}
+		var cmd tea.Cmd
+		m, cmd = m.renameSidebarPlaylist(msg.id, msg.name)
+		cmds = append(cmds, cmd)
+
+	case playlistDeletedMsg:
+		m.editPending = false
+		if msg.err != nil {
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+			break
+		}
+		var cmd tea.Cmd
+		m, cmd = m.removeSidebarPlaylist(msg.item)
+		cmds = append(cmds, cmd)
+
+	case playlistRestoredMsg:
+		m.editPending = false
+		if msg.err != nil {
+			cmds = append(cmds, func() tea.Msg { return apiError(msg.err) })
+			break
+		}
+		// 戻したプレイリストの位置は API の一覧に合わせるので、サイドバーを読み込み直す
+		var notice, reload tea.Cmd
+		m, notice = m.setNotice("Restored playlist " + msg.item.name)
+		m, reload = m.reloadPlaylists()
+		cmds = append(cmds, notice, reload)
+
+	case noticeClearMsg:
+		// 後から表示した結果は消さない
+		if int(msg) == m.noticeSeq {
+			m.notice = ""
+		}
 
 	case userMsg:
 		m.user = msg
@@ -295,61 +980,170 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case playStartedMsg:
 		m.playingPlaylistName = string(msg)
 
+	case contextLostMsg:
+		// キューの残りを曲のリストとして再生しているので、コンテキストの名前は表示しない
+		m.playingPlaylistName = "Queue"
+		context := msg.context
+		if context == "" {
+			context = "the previous context"
+		}
+		var cmd tea.Cmd
+		m, cmd = m.setNotice("Playing " + msg.track + " from the queue; " + context + " won't continue after it")
+		cmds = append(cmds, cmd)
+
 	case queueMsg:
-		if msg != nil {
-			m.queue = msg.Items
-			// queueListを更新（選択位置を保持）
-			selectedIdx := m.queueList.Index()
-			items := make([]list.Item, len(msg.Items))
-			for i, t := range msg.Items {
-				items[i] = queueItem{
-					name:   t.Name,
-					artist: t.Artists[0].Name,
-					uri:    string(t.URI),
-				}
-			}
-			m.queueList.SetItems(items)
-			// 選択位置を復元（アイテム数が変わった場合は範囲内に収める）
-			if selectedIdx >= len(items) {
-				selectedIdx = len(items) - 1
-			}
-			if selectedIdx >= 0 {
-				m.queueList.Select(selectedIdx)
+		if msg.queue != nil {
+			m = m.reconcileQueue(msg.queue.Items, msg.requestedAt)
+			uris := make([]spotifysdk.URI, len(m.queue))
+			for i, t := range m.queue {
+				uris[i] = t.URI
 			}
+			var cmd tea.Cmd
+			m, cmd = m.checkLiked(uris...)
+			cmds = append(cmds, cmd)
+		}
+
+	case upNextLoadedMsg:
+		m.upNextEntries = msg
+		m = m.setQueue(m.queueFetched)
+		uris := make([]spotifysdk.URI, len(msg))
+		for i, e := range msg {
+			uris[i] = e.URI
 		}
+		var cmd tea.Cmd
+		m, cmd = m.checkLiked(uris...)
+		cmds = append(cmds, cmd)
+
+	case upNextStartedMsg:
+		var cmd tea.Cmd
+		m, cmd = m.upNextStarted(spotify.QueueEntry(msg))
+		cmds = append(cmds, cmd)
+
+	case queueAddMsg:
+		var cmd tea.Cmd
+		m, cmd = m.addToQueue(spotifysdk.FullTrack(msg))
+		cmds = append(cmds, cmd)
+
+	case queueAddedMsg:
+		var cmd tea.Cmd
+		m, cmd = m.queueAdded(msg)
+		cmds = append(cmds, cmd)
 
 	case devicesMsg:
 		m.devices = msg
+		if m.deviceIndex >= len(msg) {
+			m.deviceIndex = len(msg) - 1
+		}
+		if m.deviceIndex < 0 {
+			m.deviceIndex = 0
+		}
 		// アクティブなデバイスを見つける
 		m.activeDevice = nil
 		for i := range msg {
 			if msg[i].Active {
 				m.activeDevice = &msg[i]
-				m.volume = int(msg[i].Volume)
+				// 送信前の音量変更がある場合は楽観的な値を保持する
+				if !m.volumePending {
+					m.volume = int(msg[i].Volume)
+					// 他のクライアントで音量が変更された場合はミュートを解除する
+					if m.volume > 0 {
+						m.muted = false
+					}
+				}
 				break
 			}
 		}
 
+	case reloginRequiredMsg:
+		if !m.reloginRequired {
+			logger.Error("UI error", "message", "re-login required")
+		}
+		m.reloginRequired = true
+		m.missingScope = msg.missingScope
+		m.seekPending = false
+		m.volumePending = false
+
+	case accountsMsg:
+		m.accountNames = msg
+		m.accountIndex = 0
+		for i, name := range msg {
+			if name == m.profile {
+				m.accountIndex = i
+			}
+		}
+
+	case accountSwitchedMsg:
+		return m.switchAccount(Account(msg))
+
+	case loginStartedMsg:
+		m.loginURL = msg.url
+		cmds = append(cmds, waitLogin(msg.wait))
+
+	case loginDoneMsg:
+		m.loginURL = ""
+		if m.loginCancel != nil {
+			m.loginCancel()
+			m.loginCancel = nil
+		}
+		if msg.err != nil {
+			cmds = append(cmds, func() tea.Msg {
+				return errorMsg("Login failed: " + msg.err.Error())
+			})
+			break
+		}
+		logger.Info("Re-login completed")
+		m.reloginRequired = false
+		m.missingScope = false
+		var reload tea.Cmd
+		m, reload = m.reloadPlaylists()
+		cmds = append(cmds,
+			reload,
+			m.fetchUser(),
+			m.fetchCurrentPlayback(),
+			m.fetchQueue(),
+			m.fetchDevices(),
+		)
+
 	case errorMsg:
 		m.err = string(msg)
 		if string(msg) != "" {
+			m.seekPending = false
+			m.volumePending = false
 			logger.Error("UI error", "message", string(msg))
 		}
 		cmds = append(cmds, clearErrorAfter(3*time.Second))
+
+	default:
+		// 貼り付けなど、入力欄が自分で発行したメッセージ
+		if m.namePrompt != promptNone {
+			var cmd tea.Cmd
+			m.nameInput, cmd = m.nameInput.Update(msg)
+			cmds = append(cmds, cmd)
+		} else if m.searchMode {
+			var cmd tea.Cmd
+			m, cmd = m.updateSearchInput(msg)
+			cmds = append(cmds, cmd)
+		}
 	}
 
 	return m, tea.Batch(cmds...)
 }
 
 func clearErrorAfter(d time.Duration) tea.Cmd {
-	return tea.Tick(d, func(t time.Time) tea.Msg {
+	return tick(d, func(t time.Time) tea.Msg {
 		return errorMsg("")
 	})
 }
 
+// playlistItem はサイドバーの項目
+// Liked Songs と保存したアルバムは ownerID が空で、編集できない
 type playlistItem struct {
-	id   string
-	name string
+	id            string
+	name          string
+	ownerID       string
+	collaborative bool
+	public        bool
+	snapshotID    string // 曲の削除と並べ替えで、どの版のプレイリストに対する変更かを示す
 }
 
 func (i playlistItem) FilterValue() string { return i.name }
@@ -362,12 +1156,38 @@ type trackItem struct {
 	artist    string
 	uri       string
 	isPlaying bool
+	liked     bool
 }
 
 func (i trackItem) FilterValue() string { return i.name }
 func (i trackItem) Title() string       { return i.name }
 func (i trackItem) Description() string { return i.artist }
 
+// buildTrackItems は m.tracks から trackList のアイテムを作る
+func (m Model) buildTrackItems() []list.Item {
+	items := make([]list.Item, len(m.tracks))
+	for i, t := range m.tracks {
+		items[i] = trackItem{
+			index:     i,
+			name:      t.Track.Name,
+			artist:    artistName(t.Track.Artists),
+			uri:       string(t.Track.URI),
+			isPlaying: t.Track.URI != "" && string(t.Track.URI) == m.playingTrackURI,
+			liked:     m.liked[t.Track.URI],
+		}
+	}
+	return items
+}
+
+// artistName は先頭のアーティスト名を返す
+// 再生できないトラックやローカルファイルはアーティストを持たないことがある
+func artistName(artists []spotifysdk.SimpleArtist) string {
+	if len(artists) == 0 {
+		return ""
+	}
+	return artists[0].Name
+}
+
 func (m Model) updateTrackListItems(playingURI string) []list.Item {
 	items := m.trackList.Items()
 	newItems := make([]list.Item, len(items))
@@ -381,13 +1201,3 @@ func (m Model) updateTrackListItems(playingURI string) []list.Item {
 	}
 	return newItems
 }
-
-type bindingMap struct{}
-
-func (b bindingMap) ShortHelp() []key.Binding {
-	return []key.Binding{}
-}
-
-func (b bindingMap) FullHelp() [][]key.Binding {
-	return [][]key.Binding{}
-}
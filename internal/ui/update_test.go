@@ -0,0 +1,1451 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"spotify-tui/internal/config"
+	"spotify-tui/internal/spotify"
+
+	tea "github.com/charmbracelet/bubbletea"
+	spotifysdk "github.com/zmb3/spotify/v2"
+)
+
+// cmdTimeout はコマンドの結果を待つ時間
+// これを過ぎても結果が返らないコマンドはテストの失敗とする
+const cmdTimeout = 5 * time.Second
+
+// TestMain は tick で遅らせるコマンド（通知を消す、ポーリングなど）を実行しないようにする
+// 遅らせたメッセージはテストから直接 Update に渡す
+func TestMain(m *testing.M) {
+	tick = func(time.Duration, func(time.Time) tea.Msg) tea.Cmd {
+		return func() tea.Msg { return nil }
+	}
+	os.Exit(m.Run())
+}
+
+// testPlaylist はテスト用のプレイリストの曲
+var testPlaylist = func() []spotifysdk.FullTrack {
+	tracks := make([]spotifysdk.FullTrack, 5)
+	for i := range tracks {
+		tracks[i] = spotify.FakeTrack(fmt.Sprintf("track%d", i), fmt.Sprintf("Track %d", i), "Artist", "Album", 3*time.Minute)
+	}
+	return tracks
+}()
+
+// newTestModel は FakePlayer でプレイリストの先頭の曲を再生中の Model を作成する
+func newTestModel(t *testing.T) (Model, *spotify.FakePlayer) {
+	t.Helper()
+	fake := spotify.NewFakePlayer(nil)
+	fake.SetUser(spotifysdk.PrivateUser{User: spotifysdk.User{ID: "user"}})
+	fake.SetDevices([]spotify.Device{{PlayerDevice: spotifysdk.PlayerDevice{ID: "device", Name: "Laptop", Active: true}}})
+	fake.AddPlaylist(spotifysdk.SimplePlaylist{ID: "playlist", Name: "Playlist"}, testPlaylist)
+	if err := fake.PlayTrackInContext(context.Background(), "spotify:playlist:playlist", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewModel(context.Background(), fake, &config.Config{})
+	m = update(t, m, tea.WindowSizeMsg{Width: 120, Height: 40})
+	m = run(t, m, tea.Batch(m.fetchPlaylistsPage(0), m.fetchUser(), m.fetchCurrentPlayback(), m.fetchQueue(), m.fetchDevices()))
+	return m, fake
+}
+
+// update は msg を Model に渡し、返されたコマンドを実行し終えた Model を返す
+func update(t *testing.T, m Model, msg tea.Msg) Model {
+	t.Helper()
+	updated, cmd := m.Update(msg)
+	return run(t, updated.(Model), cmd)
+}
+
+// run はコマンドを実行して、結果のメッセージを順に Model に渡す
+func run(t *testing.T, m Model, cmd tea.Cmd) Model {
+	t.Helper()
+	if cmd == nil {
+		return m
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	var msg tea.Msg
+	select {
+	case msg = <-done:
+	case <-time.After(cmdTimeout):
+		t.Fatalf("command didn't finish within %v", cmdTimeout)
+	}
+	switch msg := msg.(type) {
+	case nil:
+		return m
+	case tea.BatchMsg:
+		for _, c := range msg {
+			m = run(t, m, c)
+		}
+		return m
+	case errorMsg:
+		t.Fatalf("command failed: %s", msg)
+	}
+	return update(t, m, msg)
+}
+
+// press はキーを順に押す
+func press(t *testing.T, m Model, keys ...string) Model {
+	t.Helper()
+	for _, k := range keys {
+		var msg tea.KeyMsg
+		switch k {
+		case "space":
+			msg = tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "tab":
+			msg = tea.KeyMsg{Type: tea.KeyTab}
+		case "esc":
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		case "up":
+			msg = tea.KeyMsg{Type: tea.KeyUp}
+		case "down":
+			msg = tea.KeyMsg{Type: tea.KeyDown}
+		case "backspace":
+			msg = tea.KeyMsg{Type: tea.KeyBackspace}
+		default:
+			msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		}
+		m = update(t, m, msg)
+	}
+	return m
+}
+
+// poll は再生状態とキューを取得し直す
+func poll(t *testing.T, m Model) Model {
+	t.Helper()
+	return run(t, m, tea.Batch(m.fetchCurrentPlayback(), m.fetchQueue()))
+}
+
+func playerState(t *testing.T, fake *spotify.FakePlayer) *spotifysdk.PlayerState {
+	t.Helper()
+	state, err := fake.PlayerState(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestUpdatePlayPause(t *testing.T) {
+	m, fake := newTestModel(t)
+	if !m.isPlaying || m.playingTrackURI != string(testPlaylist[0].URI) {
+		t.Fatalf("isPlaying = %v, playing %q; want the first track playing", m.isPlaying, m.playingTrackURI)
+	}
+
+	m = poll(t, press(t, m, "space"))
+	if playerState(t, fake).Playing {
+		t.Error("player still playing after pause")
+	}
+	if m.isPlaying {
+		t.Error("model still playing after pause")
+	}
+
+	m = poll(t, press(t, m, "space"))
+	if !playerState(t, fake).Playing {
+		t.Error("player not playing after resume")
+	}
+	if !m.isPlaying {
+		t.Error("model not playing after resume")
+	}
+}
+
+func TestUpdateNext(t *testing.T) {
+	m, fake := newTestModel(t)
+
+	m = poll(t, press(t, m, "n"))
+	if got := playerState(t, fake).Item.URI; got != testPlaylist[1].URI {
+		t.Errorf("playing %s after next, want %s", got, testPlaylist[1].URI)
+	}
+	if m.playingTrackURI != string(testPlaylist[1].URI) {
+		t.Errorf("model shows %s after next, want %s", m.playingTrackURI, testPlaylist[1].URI)
+	}
+	if len(m.queue) != len(testPlaylist)-2 || m.queue[0].URI != testPlaylist[2].URI {
+		t.Errorf("queue after next = %v, want the rest of the playlist", queueURIs(m))
+	}
+}
+
+func TestUpdateAddToQueue(t *testing.T) {
+	m, fake := newTestModel(t)
+
+	// サイドバーで Liked Songs と Saved Albums の次のプレイリストを開き、4曲目をキューに加える
+	m = press(t, m, "j", "j", "enter", "tab")
+	if m.focus != FocusMain || len(m.tracks) != len(testPlaylist) {
+		t.Fatalf("focus = %v, %d tracks; want the playlist open", m.focus, len(m.tracks))
+	}
+	m = press(t, m, "j", "j", "j", "z")
+	if len(m.queue) == 0 || m.queue[0].URI != testPlaylist[3].URI {
+		t.Fatalf("queue after adding = %v, want %s first", queueURIs(m), testPlaylist[3].URI)
+	}
+
+	// 取得したキューに反映されても、表示は変わらない
+	m = poll(t, m)
+	want := []spotifysdk.URI{testPlaylist[3].URI, testPlaylist[1].URI, testPlaylist[2].URI, testPlaylist[3].URI, testPlaylist[4].URI}
+	if got := queueURIs(m); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("queue = %v, want %v", got, want)
+	}
+
+	// 次の曲は加えた曲
+	m = poll(t, press(t, m, "n"))
+	if got := playerState(t, fake).Item.URI; got != testPlaylist[3].URI {
+		t.Errorf("playing %s after next, want the queued %s", got, testPlaylist[3].URI)
+	}
+	if len(m.queue) == 0 || m.queue[0].URI != testPlaylist[1].URI {
+		t.Errorf("queue after playing the queued track = %v", queueURIs(m))
+	}
+}
+
+func TestUpdateSkipToQueueItem(t *testing.T) {
+	m, fake := newTestModel(t)
+
+	// キューで3番目の曲を選んで再生する
+	m = press(t, m, "tab", "tab")
+	if m.focus != FocusQueue {
+		t.Fatalf("focus = %v, want the queue", m.focus)
+	}
+	m = poll(t, press(t, m, "j", "j", "enter"))
+	if got := playerState(t, fake).Item.URI; got != testPlaylist[3].URI {
+		t.Errorf("playing %s, want %s", got, testPlaylist[3].URI)
+	}
+	if len(m.queue) != 1 || m.queue[0].URI != testPlaylist[4].URI {
+		t.Errorf("queue = %v, want only %s", queueURIs(m), testPlaylist[4].URI)
+	}
+}
+
+func queueURIs(m Model) []spotifysdk.URI {
+	uris := make([]spotifysdk.URI, len(m.queue))
+	for i, t := range m.queue {
+		uris[i] = t.URI
+	}
+	return uris
+}
+
+func TestUpdatePlaylistPages(t *testing.T) {
+	m, _ := newTestModel(t)
+	page := func(loadID, offset int, ids ...string) playlistsMsg {
+		msg := playlistsMsg{loadID: loadID, offset: offset, total: 4}
+		for _, id := range ids {
+			msg.playlists = append(msg.playlists, spotifysdk.SimplePlaylist{ID: spotifysdk.ID(id), Name: id})
+		}
+		return msg
+	}
+
+	// 読み込み直した後に届いた前の読み込みのページは捨てる
+	m, _ = m.reloadPlaylists()
+	old := m.playlistsLoadID - 1
+	m, _ = updateOnly(m, page(m.playlistsLoadID, 0, "a", "b"))
+	m, _ = updateOnly(m, page(old, 2, "stale"))
+	m, _ = updateOnly(m, page(m.playlistsLoadID, 2, "c", "d"))
+	var names []string
+	for _, item := range m.playlists.Items()[2:] {
+		names = append(names, item.(playlistItem).name)
+	}
+	if fmt.Sprint(names) != "[a b c d]" {
+		t.Errorf("sidebar = %v, want [a b c d]", names)
+	}
+
+	// ページの取得に失敗したら、読み込み中の表示をやめてエラーを表示する
+	m, _ = m.reloadPlaylists()
+	m, _ = updateOnly(m, page(m.playlistsLoadID, 0, "a", "b"))
+	m, cmd := updateOnly(m, playlistsMsg{loadID: m.playlistsLoadID, offset: 2, err: fmt.Errorf("boom")})
+	if m.playlistsCount != m.playlistsTotal {
+		t.Errorf("loading %d/%d after a failed page", m.playlistsCount, m.playlistsTotal)
+	}
+	if cmd == nil {
+		t.Fatal("no error for the failed page")
+	}
+	if msg := cmd(); msg != errorMsg("boom") {
+		t.Errorf("error = %v, want boom", msg)
+	}
+}
+
+func TestUpdateTrackPageError(t *testing.T) {
+	m, _ := newTestModel(t)
+	m = press(t, m, "j", "j", "enter")
+	loadID := m.tracksLoadID
+
+	m, _ = updateOnly(m, tracksMsg{loadID: loadID, playlistID: "playlist", offset: 0, total: 10, tracks: toPlaylistTracks(testPlaylist)})
+	m, _ = updateOnly(m, tracksMsg{loadID: loadID, playlistID: "playlist", offset: 5, err: fmt.Errorf("boom")})
+	if m.loadingTracks || m.tracksTotal != len(testPlaylist) || len(m.tracks) != len(testPlaylist) {
+		t.Errorf("loading = %v, %d/%d tracks; want the loaded page kept and loading stopped", m.loadingTracks, len(m.tracks), m.tracksTotal)
+	}
+
+	// 最初のページから失敗したら、前に開いていたプレイリストの曲を表示しない
+	m = m.startTracksLoad("Other")
+	m, _ = updateOnly(m, tracksMsg{loadID: m.tracksLoadID, playlistID: "other", offset: 0, err: fmt.Errorf("boom")})
+	if m.loadingTracks || len(m.tracks) != 0 || m.tracksTotal != 0 {
+		t.Errorf("loading = %v, %d/%d tracks; want nothing loaded", m.loadingTracks, len(m.tracks), m.tracksTotal)
+	}
+}
+
+// updateOnly は msg を Model に渡し、返されたコマンドは実行しない
+func updateOnly(m Model, msg tea.Msg) (Model, tea.Cmd) {
+	updated, cmd := m.Update(msg)
+	return updated.(Model), cmd
+}
+
+func toPlaylistTracks(tracks []spotifysdk.FullTrack) []spotifysdk.PlaylistTrack {
+	items := make([]spotifysdk.PlaylistTrack, len(tracks))
+	for i, t := range tracks {
+		items[i] = spotifysdk.PlaylistTrack{Track: t}
+	}
+	return items
+}
+
+func TestUpdateSeekPercent(t *testing.T) {
+	tests := []struct {
+		name string
+		keys map[string][]string
+		key  string
+		want time.Duration // 曲の長さ（3分）に対する位置、-1 は移動しない
+	}{
+		{"default 0", nil, "0", 0},
+		{"default 5", nil, "5", 90 * time.Second},
+		{"default 9", nil, "9", 162 * time.Second},
+		{"remapped", map[string][]string{"seek_percent": {"a", "b", "c"}}, "c", 36 * time.Second},
+		{"unbound digit", map[string][]string{"seek_percent": {"a", "b", "c"}}, "5", -1},
+		{"eleventh key", map[string][]string{"seek_percent": {"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "x"}}, "x", -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := newTestModel(t)
+			m.keys = newKeyMap((&config.Config{Keys: tt.keys}).KeyBindings())
+			m.progress = time.Minute
+			m, _ = updateOnly(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(tt.key)})
+			want := tt.want
+			if want < 0 {
+				want = time.Minute
+			}
+			if m.progress != want {
+				t.Errorf("progress = %v, want %v", m.progress, want)
+			}
+		})
+	}
+}
+
+func TestValidateKeysSeekPercent(t *testing.T) {
+	bindings := (&config.Config{Keys: map[string][]string{"next": {"5"}}}).KeyBindings()
+	err := ValidateKeys(bindings)
+	if err == nil || !strings.Contains(err.Error(), `next and seek_percent are both bound to "5"`) {
+		t.Errorf("ValidateKeys = %v, want the conflict with seek_percent", err)
+	}
+	if help := newKeyMap(config.DefaultKeys).binding(actSeekPercent).Help(); help.Key != "0/1/2/3/4/5/6/7/8/9" {
+		t.Errorf("help keys = %q", help.Key)
+	}
+}
+
+func TestUpdateSkipToQueuedTrackLosesContext(t *testing.T) {
+	m, fake := newTestModel(t)
+	extra := spotify.FakeTrack("extra", "Extra", "Other", "Other Album", 3*time.Minute)
+	fake.SetSavedTracks([]spotifysdk.FullTrack{extra})
+	if err := fake.AddToQueue(context.Background(), extra.URI); err != nil {
+		t.Fatal(err)
+	}
+	m = poll(t, m)
+
+	// キューに追加した曲へ飛ぶと、プレイリストから外れたことを知らせる
+	m = poll(t, press(t, m, "tab", "tab", "enter"))
+	if got := playerState(t, fake).Item.URI; got != extra.URI {
+		t.Fatalf("playing %s, want %s", got, extra.URI)
+	}
+	if !strings.Contains(m.notice, "Playlist won't continue") {
+		t.Errorf("notice = %q, want a note that the playlist won't continue", m.notice)
+	}
+	if got := m.playingContextName(); got != "Queue" {
+		t.Errorf("context shown as %q, want Queue", got)
+	}
+}
+
+func TestUpdateAlbumPage(t *testing.T) {
+	m, _ := newTestModel(t)
+
+	// 読み込みに失敗したら、読み込み中の表示をやめてエラーを表示する
+	m, cmd := m.openAlbum("missing")
+	m, cmd = updateOnly(m, cmd())
+	if m.album.loading || !m.album.failed {
+		t.Errorf("loading = %v, failed = %v after a failed album", m.album.loading, m.album.failed)
+	}
+	if cmd == nil {
+		t.Fatal("no error for the failed album")
+	}
+	if msg, ok := cmd().(errorMsg); !ok || !strings.Contains(string(msg), "Non existing id") {
+		t.Errorf("error = %v, want the lookup error", msg)
+	}
+
+	// 別のアルバムを開いた後に届いた、前のアルバムの結果は捨てる
+	m, stale := m.openAlbum("album")
+	m, cmd = m.openAlbum("missing")
+	m, _ = updateOnly(m, stale())
+	if !m.album.loading || m.album.name != "" {
+		t.Errorf("loading = %v, album = %q; want the stale album dropped", m.album.loading, m.album.name)
+	}
+	m, _ = updateOnly(m, cmd())
+	if m.album.loading || !m.album.failed {
+		t.Errorf("loading = %v, failed = %v after a failed album", m.album.loading, m.album.failed)
+	}
+
+	// 戻ってきたら失敗したアルバムを読み込み直す
+	m, _ = m.openAlbum("album")
+	m, _ = updateOnly(m, m.fetchAlbum("album")())
+	m, cmd = m.goBack()
+	if !m.album.loading || m.album.failed || cmd == nil {
+		t.Errorf("loading = %v, failed = %v; want the failed album reloaded", m.album.loading, m.album.failed)
+	}
+
+	// 開いたアルバムの曲を表示する
+	m, cmd = m.openAlbum("album")
+	m, _ = updateOnly(m, cmd())
+	if m.album.loading || m.album.failed || m.album.name != "Album" || len(m.album.tracks) != len(testPlaylist) {
+		t.Errorf("album = %q with %d tracks, want Album with %d", m.album.name, len(m.album.tracks), len(testPlaylist))
+	}
+}
+
+func TestUpdateArtistPage(t *testing.T) {
+	m, _ := newTestModel(t)
+
+	// 読み込みに失敗したら、読み込み中の表示をやめてエラーを表示する
+	m, cmd := m.openArtist("missing")
+	m, cmd = updateOnly(m, cmd())
+	if m.artist.loading || !m.artist.failed {
+		t.Errorf("loading = %v, failed = %v after a failed artist", m.artist.loading, m.artist.failed)
+	}
+	if cmd == nil {
+		t.Fatal("no error for the failed artist")
+	}
+	if msg, ok := cmd().(errorMsg); !ok || !strings.Contains(string(msg), "Non existing id") {
+		t.Errorf("error = %v, want the lookup error", msg)
+	}
+
+	// 別のアーティストを開いた後に届いた、前のアーティストの結果は捨てる
+	m, stale := m.openArtist("artist")
+	m, cmd = m.openArtist("missing")
+	m, _ = updateOnly(m, stale())
+	if !m.artist.loading || m.artist.name != "" {
+		t.Errorf("loading = %v, artist = %q; want the stale artist dropped", m.artist.loading, m.artist.name)
+	}
+	m, _ = updateOnly(m, cmd())
+	if m.artist.loading || !m.artist.failed {
+		t.Errorf("loading = %v, failed = %v after a failed artist", m.artist.loading, m.artist.failed)
+	}
+
+	// 戻ってきたら失敗したアーティストを読み込み直す
+	m, _ = m.openArtist("artist")
+	m, cmd = m.goBack()
+	if !m.artist.loading || m.artist.failed || cmd == nil {
+		t.Errorf("loading = %v, failed = %v; want the failed artist reloaded", m.artist.loading, m.artist.failed)
+	}
+}
+
+func TestUpdatePlayArtistTopTracks(t *testing.T) {
+	tests := []struct {
+		name        string
+		row         int
+		wantContext string // 再生するコンテキストの種類（URIのリストで再生する場合は空）
+	}{
+		{"first top track", 0, "artist"},
+		{"third top track", 2, ""},
+		{"last top track", 3, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, fake := newTestModel(t)
+			singer := make([]spotifysdk.FullTrack, 4)
+			for i := range singer {
+				singer[i] = spotify.FakeTrack(fmt.Sprintf("song%d", i), fmt.Sprintf("Song %d", i), "Singer", "Songs", 3*time.Minute)
+			}
+			fake.AddPlaylist(spotifysdk.SimplePlaylist{ID: "singer", Name: "Singer"}, singer)
+
+			m, cmd := m.openArtist("singer")
+			m, _ = updateOnly(m, cmd())
+			m = poll(t, press(t, m, append(slices.Repeat([]string{"j"}, tt.row), "enter")...))
+			state := playerState(t, fake)
+			if state.Item.URI != singer[tt.row].URI {
+				t.Errorf("playing %s, want %s", state.Item.URI, singer[tt.row].URI)
+			}
+			if state.PlaybackContext.Type != tt.wantContext {
+				t.Errorf("context = %q, want %q", state.PlaybackContext.Type, tt.wantContext)
+			}
+			// どちらの場合もプレイヤーバーにはアーティスト名を表示する
+			if got := m.playingContextName(); got != "Singer" {
+				t.Errorf("player bar shows %q, want Singer", got)
+			}
+		})
+	}
+}
+
+func TestUpdateToggleLikeWaitsForCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		saved     bool
+		checking  bool // 押す前から問い合わせ中
+		presses   int
+		wantSaved bool
+	}{
+		// 保存済みの曲を、問い合わせ中に切り替えたら保存をやめる（もう一度保存しない）
+		{"saved track", true, true, 1, false},
+		{"unsaved track", false, true, 1, true},
+		// 問い合わせていない曲は、押したときに問い合わせる
+		{"not checked yet", true, false, 1, false},
+		// 結果が届く前にもう一度押すと取り消す
+		{"pressed twice", true, true, 2, true},
+		{"not checked, pressed twice", true, false, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, fake := newTestModel(t)
+			// キューなどに表示していない、保存済みか問い合わせていない曲
+			track := spotify.FakeTrack("other", "Other", "Other", "Other", 3*time.Minute)
+			fake.AddPlaylist(spotifysdk.SimplePlaylist{ID: "other", Name: "Other"}, []spotifysdk.FullTrack{track})
+			if tt.saved {
+				fake.SetSavedTracks([]spotifysdk.FullTrack{track})
+			}
+
+			var check tea.Cmd
+			if tt.checking {
+				m, check = m.checkLiked(track.URI)
+			}
+			for range tt.presses {
+				var cmd tea.Cmd
+				m, cmd = m.toggleLike(track.URI)
+				if check == nil {
+					check = cmd
+				} else if cmd != nil {
+					t.Fatal("toggled before the check returned")
+				}
+			}
+			if got := m.liked[track.URI]; got != false {
+				t.Errorf("shown liked = %v before the check returned", got)
+			}
+
+			m = run(t, m, check)
+			saved, err := fake.TracksSaved(context.Background(), []spotifysdk.ID{track.ID})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved[0] != tt.wantSaved || m.liked[track.URI] != tt.wantSaved {
+				t.Errorf("saved = %v, shown liked = %v; want %v", saved[0], m.liked[track.URI], tt.wantSaved)
+			}
+		})
+	}
+}
+
+func TestUpdateLikeAlbum(t *testing.T) {
+	m, fake := newTestModel(t)
+	album := albumURI(testPlaylist[0].Album.ID)
+	albumSaved := func() bool {
+		t.Helper()
+		saved, err := fake.AlbumsSaved(context.Background(), []spotifysdk.ID{testPlaylist[0].Album.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return saved[0]
+	}
+
+	// アルバムのページでアルバムを保存する
+	m, cmd := m.openAlbum(testPlaylist[0].Album.ID)
+	m = run(t, m, cmd)
+	if liked, ok := m.liked[album]; !ok || liked {
+		t.Fatalf("album liked = %v, checked = %v; want checked and not saved", liked, ok)
+	}
+	m = press(t, m, "g", "L")
+	if !albumSaved() || !m.liked[album] {
+		t.Errorf("saved = %v, shown liked = %v after liking the album", albumSaved(), m.liked[album])
+	}
+	if !strings.Contains(m.renderAlbumPage(80, 20), "♥") {
+		t.Error("album page doesn't show the album as liked")
+	}
+
+	// 保存したアルバムの一覧で選んだアルバムの保存をやめる
+	m, cmd = m.openSavedAlbums()
+	m = run(t, m, cmd)
+	m = press(t, m, "l")
+	if albumSaved() || m.liked[album] {
+		t.Errorf("saved = %v, shown liked = %v after unliking in Saved Albums", albumSaved(), m.liked[album])
+	}
+}
+
+func TestUpdateSeekWhileSending(t *testing.T) {
+	m, fake := newTestModel(t)
+
+	// 1回目のシークを送信している間に、次のシーク操作をする
+	m, _ = m.seekTo(30 * time.Second)
+	m, send := updateOnly(m, seekFireMsg(m.seekSeq))
+	m, _ = m.seekTo(90 * time.Second)
+	m = run(t, m, send)
+	if !m.seekPending {
+		t.Error("the first seek finishing cleared the pending second seek")
+	}
+
+	// 2回目を送信するまでは、取得した再生位置で表示を戻さない
+	m = poll(t, m)
+	if m.progress != 90*time.Second {
+		t.Errorf("progress = %v after polling, want the pending 1m30s", m.progress)
+	}
+
+	m, send = updateOnly(m, seekFireMsg(m.seekSeq))
+	m = run(t, m, send)
+	if m.seekPending {
+		t.Error("still pending after the last seek finished")
+	}
+	if got := playerState(t, fake).Progress; got < 90000 || got > 91000 {
+		t.Errorf("player at %dms, want 90000ms", got)
+	}
+}
+
+// useVolumeDevice は音量を変更できるデバイスを音量 volume で再生中にする
+func useVolumeDevice(t *testing.T, m Model, fake *spotify.FakePlayer, volume int) Model {
+	t.Helper()
+	fake.SetDevices([]spotify.Device{{PlayerDevice: spotifysdk.PlayerDevice{ID: "device", Name: "Laptop", Active: true, Volume: spotifysdk.Numeric(volume)}, SupportsVolume: true}})
+	return run(t, m, m.fetchDevices())
+}
+
+// fireVolume は遅延させた音量変更を送信する
+func fireVolume(t *testing.T, m Model) Model {
+	t.Helper()
+	return update(t, m, volumeFireMsg(m.volumeSeq))
+}
+
+func TestUpdateVolume(t *testing.T) {
+	tests := []struct {
+		name  string
+		start int
+		keys  []string
+		want  int
+	}{
+		{"volume up", 50, []string{"+"}, 55},
+		{"volume down", 50, []string{"-", "-"}, 40},
+		{"up to the maximum", 98, []string{"+"}, 100},
+		{"down to the minimum", 3, []string{"-"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, fake := newTestModel(t)
+			m = useVolumeDevice(t, m, fake, tt.start)
+
+			// 送信前でも表示は楽観的に更新し、取得したデバイスの音量で戻さない
+			m = press(t, m, tt.keys...)
+			m = run(t, m, m.fetchDevices())
+			if m.volume != tt.want || !m.volumePending {
+				t.Errorf("volume = %d, pending = %v before sending; want %d pending", m.volume, m.volumePending, tt.want)
+			}
+
+			m = fireVolume(t, m)
+			if m.volumePending {
+				t.Error("still pending after sending")
+			}
+			if got := int(playerState(t, fake).Device.Volume); got != tt.want {
+				t.Errorf("player volume = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateMute(t *testing.T) {
+	m, fake := newTestModel(t)
+	m = useVolumeDevice(t, m, fake, 40)
+
+	m = fireVolume(t, press(t, m, "m"))
+	if !m.muted || m.volume != 0 {
+		t.Errorf("muted = %v, volume = %d; want muted at 0", m.muted, m.volume)
+	}
+	if got := int(playerState(t, fake).Device.Volume); got != 0 {
+		t.Errorf("player volume = %d after muting, want 0", got)
+	}
+	if !strings.Contains(m.renderDeviceInfo(40), "mute") {
+		t.Error("device info doesn't show mute")
+	}
+
+	// ミュート解除でミュート前の音量に戻す
+	m = fireVolume(t, press(t, m, "m"))
+	if m.muted || m.volume != 40 {
+		t.Errorf("muted = %v, volume = %d after unmuting; want 40", m.muted, m.volume)
+	}
+	if got := int(playerState(t, fake).Device.Volume); got != 40 {
+		t.Errorf("player volume = %d after unmuting, want 40", got)
+	}
+
+	// 他のクライアントで音量が上げられたらミュートを解除する
+	m = fireVolume(t, press(t, m, "m"))
+	if err := fake.SetVolume(context.Background(), 20); err != nil {
+		t.Fatal(err)
+	}
+	m = run(t, m, m.fetchDevices())
+	if m.muted || m.volume != 20 {
+		t.Errorf("muted = %v, volume = %d after another client changed it; want 20", m.muted, m.volume)
+	}
+}
+
+func TestUpdateVolumeUnsupported(t *testing.T) {
+	// newTestModel のデバイスは音量を変更できない
+	m, fake := newTestModel(t)
+	volume := m.volume
+
+	m, cmd := updateOnly(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("+")})
+	if m.volume != volume || m.volumePending {
+		t.Errorf("volume = %d, pending = %v; want the volume unchanged", m.volume, m.volumePending)
+	}
+	if cmd == nil {
+		t.Fatal("no error for a device without volume control")
+	}
+	if msg, ok := cmd().(errorMsg); !ok || !strings.Contains(string(msg), "not supported on Laptop") {
+		t.Errorf("error = %v, want volume control not supported", msg)
+	}
+	if !strings.Contains(m.renderDeviceInfo(40), "n/a") {
+		t.Error("volume bar not shown as disabled")
+	}
+	if got := int(playerState(t, fake).Device.Volume); got != 0 {
+		t.Errorf("player volume = %d, want it untouched", got)
+	}
+}
+
+func TestUpdateVolumeWhileSending(t *testing.T) {
+	m, fake := newTestModel(t)
+	m = useVolumeDevice(t, m, fake, 50)
+
+	// 1回目の音量変更を送信している間に、次の音量変更をする
+	m = press(t, m, "+")
+	m, send := updateOnly(m, volumeFireMsg(m.volumeSeq))
+	m = press(t, m, "+")
+	m = run(t, m, send)
+	if !m.volumePending {
+		t.Error("the first volume change finishing cleared the pending second one")
+	}
+
+	// 2回目を送信するまでは、取得したデバイスの音量で表示を戻さない
+	m = run(t, m, m.fetchDevices())
+	if m.volume != 60 {
+		t.Errorf("volume = %d after fetching devices, want the pending 60", m.volume)
+	}
+
+	m = fireVolume(t, m)
+	if m.volumePending {
+		t.Error("still pending after the last volume change finished")
+	}
+	if got := int(playerState(t, fake).Device.Volume); got != 60 {
+		t.Errorf("player volume = %d, want 60", got)
+	}
+}
+
+// testDevices は Laptop と Phone、操作できない Speaker のデバイス
+// active の名前のデバイスで再生中にする（空ならアクティブなデバイスなし）
+func testDevices(active string) []spotify.Device {
+	devices := []spotify.Device{
+		{PlayerDevice: spotifysdk.PlayerDevice{ID: "laptop", Name: "Laptop", Type: "Computer", Volume: 50}, SupportsVolume: true},
+		{PlayerDevice: spotifysdk.PlayerDevice{ID: "phone", Name: "Phone", Type: "Smartphone", Volume: 80}, SupportsVolume: true, IsPrivateSession: true},
+		{PlayerDevice: spotifysdk.PlayerDevice{ID: "speaker", Name: "Speaker", Type: "Speaker", Restricted: true}},
+	}
+	for i := range devices {
+		devices[i].Active = devices[i].Name == active
+	}
+	return devices
+}
+
+func TestUpdateDevicePicker(t *testing.T) {
+	tests := []struct {
+		name        string
+		paused      bool
+		keys        []string
+		wantDevice  string
+		wantPlaying bool
+	}{
+		{"transfer and play", true, []string{"d", "j", "enter"}, "Phone", true},
+		{"transfer only", true, []string{"d", "j", "t"}, "Phone", false},
+		{"transfer only keeps playing", false, []string{"d", "j", "t"}, "Phone", true},
+		{"close", false, []string{"d", "j", "esc"}, "Laptop", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, fake := newTestModel(t)
+			fake.SetDevices(testDevices("Laptop"))
+			if tt.paused {
+				m = poll(t, press(t, m, "space"))
+			}
+
+			m = run(t, m, m.fetchDevices())
+			m = press(t, m, tt.keys...)
+			m = poll(t, m)
+			if m.showDevices {
+				t.Error("device picker still open")
+			}
+			if m.activeDevice == nil || m.activeDevice.Name != tt.wantDevice {
+				t.Fatalf("active device = %v, want %s", m.activeDevice, tt.wantDevice)
+			}
+			state := playerState(t, fake)
+			if state.Device.Name != tt.wantDevice || state.Playing != tt.wantPlaying {
+				t.Errorf("player on %s, playing = %v; want %s, playing = %v", state.Device.Name, state.Playing, tt.wantDevice, tt.wantPlaying)
+			}
+		})
+	}
+}
+
+func TestUpdateDevicePickerView(t *testing.T) {
+	m, fake := newTestModel(t)
+	fake.SetDevices(testDevices("Laptop"))
+	m = press(t, m, "d")
+	if !m.showDevices {
+		t.Fatal("device picker not open")
+	}
+
+	// 全てのデバイスを種類、音量、状態と一緒に表示する
+	view := m.renderDevicePicker()
+	for _, want := range []string{"Laptop", "Computer", "50%", "[active]", "Phone", "Smartphone", "80%", "[private]", "Speaker", "[restricted]"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("device picker doesn't show %q", want)
+		}
+	}
+
+	// 操作できないデバイスには移さない
+	m = press(t, m, "j", "j")
+	m, cmd := updateOnly(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("no error for a restricted device")
+	}
+	if msg, ok := cmd().(errorMsg); !ok || !strings.Contains(string(msg), "Speaker is restricted") {
+		t.Errorf("error = %v, want the device restricted", msg)
+	}
+	if !m.showDevices {
+		t.Error("device picker closed after choosing a restricted device")
+	}
+	if got := playerState(t, fake).Device.Name; got != "Laptop" {
+		t.Errorf("player moved to %s", got)
+	}
+}
+
+func TestUpdateNoActiveDevice(t *testing.T) {
+	// 再生を押すと、エラーではなく再生先を選ばせる
+	m, fake := newTestModel(t)
+	fake.SetDevices(testDevices(""))
+	m = run(t, m, m.fetchDevices())
+	m = press(t, m, "space")
+	if !m.showDevices || m.err != "" {
+		t.Fatalf("picker open = %v, error = %q; want the device picker", m.showDevices, m.err)
+	}
+	m = poll(t, press(t, m, "enter"))
+	if m.activeDevice == nil || m.activeDevice.Name != "Laptop" || !playerState(t, fake).Playing {
+		t.Errorf("active device = %v, want Laptop playing", m.activeDevice)
+	}
+
+	// 曲の再生に失敗したら、再生先を選んだ後にやり直す
+	m, fake = newTestModel(t)
+	fake.SetDevices(testDevices(""))
+	m = run(t, m, m.fetchDevices())
+	m = press(t, m, "j", "j", "enter", "tab", "j", "j", "enter")
+	if !m.showDevices || m.deviceRetry == nil {
+		t.Fatalf("picker open = %v, retry = %v; want the device picker with the retry", m.showDevices, m.deviceRetry != nil)
+	}
+	m = poll(t, press(t, m, "j", "enter"))
+	state := playerState(t, fake)
+	if state.Device.Name != "Phone" || state.Item.URI != testPlaylist[2].URI {
+		t.Errorf("playing %s on %s, want %s on Phone", state.Item.URI, state.Device.Name, testPlaylist[2].URI)
+	}
+	if m.deviceRetry != nil {
+		t.Error("retry kept after transferring")
+	}
+}
+
+func TestUpdateRelogin(t *testing.T) {
+	m, _ := newTestModel(t)
+	m, _ = updateOnly(m, reloginRequiredMsg{})
+	m, _ = updateOnly(m, loginStartedMsg{url: "https://accounts.example/authorize", wait: func() error { return nil }})
+	if m.loginURL == "" {
+		t.Fatal("login URL not shown")
+	}
+
+	// 失敗したら再ログインが必要なままエラーを表示する
+	m, cmd := updateOnly(m, loginDoneMsg{err: fmt.Errorf("login timed out after 5m0s")})
+	if m.loginURL != "" || !m.reloginRequired {
+		t.Errorf("login URL = %q, relogin required = %v after a failed login", m.loginURL, m.reloginRequired)
+	}
+	if msg, ok := cmd().(errorMsg); !ok || !strings.Contains(string(msg), "Login failed: login timed out") {
+		t.Errorf("error = %v, want the login failure", msg)
+	}
+
+	// 成功したら再ログインの表示を消して、プレイリストなどを読み込み直す
+	m = update(t, m, loginDoneMsg{})
+	if m.reloginRequired || m.loginURL != "" {
+		t.Errorf("relogin required = %v, login URL = %q after logging in", m.reloginRequired, m.loginURL)
+	}
+	// Liked Songs と Saved Albums、プレイリスト
+	if n := len(m.playlists.Items()); n != 3 || m.currentTrack == nil {
+		t.Errorf("%d sidebar items, current track %v; want the playlists and playback reloaded", n, m.currentTrack)
+	}
+}
+
+// testSwitcher はプロファイル名ごとに決まったアカウントを開く AccountSwitcher
+// accounts にないプロファイルは開けない
+type testSwitcher struct {
+	names    []string
+	accounts map[string]Account
+}
+
+func (s testSwitcher) Profiles() ([]string, error) {
+	return s.names, nil
+}
+
+func (s testSwitcher) Open(profile string) (Account, error) {
+	account, ok := s.accounts[profile]
+	if !ok {
+		return Account{}, fmt.Errorf("profile %q keeps its secrets in a locked encrypted file", profile)
+	}
+	return account, nil
+}
+
+func TestUpdateSwitchAccount(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m, _ := newTestModel(t)
+
+	work := spotify.NewFakePlayer(nil)
+	work.SetUser(spotifysdk.PrivateUser{User: spotifysdk.User{ID: "colleague", DisplayName: "Colleague"}})
+	work.SetDevices([]spotify.Device{{PlayerDevice: spotifysdk.PlayerDevice{ID: "office", Name: "Office", Active: true}}})
+	workTracks := []spotifysdk.FullTrack{spotify.FakeTrack("focus", "Focus", "Band", "Work", 3*time.Minute)}
+	work.AddPlaylist(spotifysdk.SimplePlaylist{ID: "work", Name: "Work Mix"}, workTracks)
+	if err := work.PlayTrackInContext(context.Background(), "spotify:playlist:work", 0); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.NewProfile("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = m.WithAccountSwitcher(testSwitcher{
+		names:    []string{config.DefaultProfile, "work", "locked"},
+		accounts: map[string]Account{"work": {Config: cfg, Client: work}},
+	})
+
+	// 今のプロファイルを選んでも何もしない
+	m = press(t, m, "A", "enter")
+	if m.profile != config.DefaultProfile || m.showAccounts {
+		t.Errorf("profile = %q, switcher open = %v after choosing the current profile", m.profile, m.showAccounts)
+	}
+
+	// 切り替えたらユーザー、プレイリスト、再生状態、デバイスを新しいクライアントで読み込み直す
+	m = press(t, m, "A", "j", "enter")
+	if m.profile != "work" || m.showAccounts {
+		t.Fatalf("profile = %q, switcher open = %v; want switched to work", m.profile, m.showAccounts)
+	}
+	var names []string
+	for _, item := range m.playlists.Items() {
+		names = append(names, item.(playlistItem).name)
+	}
+	if !slices.Contains(names, "Work Mix") || slices.Contains(names, "Playlist") {
+		t.Errorf("sidebar = %v, want the work playlists", names)
+	}
+	if m.playingTrackURI != string(workTracks[0].URI) || m.activeDevice == nil || m.activeDevice.Name != "Office" {
+		t.Errorf("playing %s on %v, want %s on Office", m.playingTrackURI, m.activeDevice, workTracks[0].URI)
+	}
+	if m.user == nil || m.user.ID != "colleague" {
+		t.Errorf("user = %v, want colleague", m.user)
+	}
+
+	// 開けないプロファイルはエラーを表示して、今のアカウントのままにする
+	m = press(t, m, "A", "j", "j")
+	m, cmd := updateOnly(m, tea.KeyMsg{Type: tea.KeyEnter})
+	m, _ = updateOnly(m, cmd())
+	if m.profile != "work" || !strings.Contains(m.err, "locked encrypted file") {
+		t.Errorf("profile = %q, error = %q; want to stay on work with the error", m.profile, m.err)
+	}
+}
+
+func TestUpdateSwitchTheme(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	ocean := config.ThemeDef{Name: "ocean", Colors: config.DefaultColors.With(config.Colors{Primary: "#0077BE"})}
+	themes := append(slices.Clone(config.BuiltinThemes), ocean)
+
+	// 起動時のテーマにはユーザー定義のテーマも使える
+	fake := spotify.NewFakePlayer(nil)
+	m := NewModel(context.Background(), fake, &config.Config{Theme: config.ThemeSettings{Name: "ocean"}}).WithThemes(themes)
+	if m.theme.Name != "ocean" {
+		t.Fatalf("theme = %q, want ocean", m.theme.Name)
+	}
+
+	// 実行中は themes の順に切り替え、最後の次は最初に戻る
+	m = press(t, m, "T")
+	if m.theme.Name != config.ThemeSpotify {
+		t.Errorf("theme = %q after switching from the last theme, want %s", m.theme.Name, config.ThemeSpotify)
+	}
+	m = press(t, m, "T")
+	if m.theme.Name != config.ThemeLight {
+		t.Errorf("theme = %q, want %s", m.theme.Name, config.ThemeLight)
+	}
+
+	// 名前が見つからない場合は最初のテーマを使う
+	m = NewModel(context.Background(), fake, &config.Config{Theme: config.ThemeSettings{Name: "neon"}}).WithThemes(themes)
+	if m.theme.Name != config.ThemeSpotify {
+		t.Errorf("theme = %q for an unknown name, want %s", m.theme.Name, config.ThemeSpotify)
+	}
+}
+
+// typeText は文字を1つずつ入力する
+func typeText(t *testing.T, m Model, text string) Model {
+	t.Helper()
+	for _, r := range text {
+		m = update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return m
+}
+
+// fireSearch は入力が止まってから searchDebounce 経過したことにして検索する
+func fireSearch(t *testing.T, m Model) Model {
+	t.Helper()
+	return update(t, m, searchFireMsg(m.searchSeq))
+}
+
+func TestUpdateSearchInput(t *testing.T) {
+	m, _ := newTestModel(t)
+	m = press(t, m, "/")
+	if !m.searchMode {
+		t.Fatal("search not open")
+	}
+
+	// 複数バイトの文字も1文字ずつ消す
+	m = typeText(t, m, "日本語")
+	m = press(t, m, "backspace")
+	if got := m.searchInput.Value(); got != "日本" {
+		t.Errorf("query = %q after backspace, want 日本", got)
+	}
+
+	// 単語の削除と貼り付け
+	m = update(t, m, tea.KeyMsg{Type: tea.KeyCtrlW})
+	m = update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("Track 1"), Paste: true})
+	if got := m.searchInput.Value(); got != "Track 1" {
+		t.Errorf("query = %q after deleting a word and pasting, want Track 1", got)
+	}
+
+	// カーソルを動かして途中に入力する
+	m = update(t, m, tea.KeyMsg{Type: tea.KeyLeft})
+	m = update(t, m, tea.KeyMsg{Type: tea.KeyLeft})
+	m = typeText(t, m, "s")
+	if got := m.searchInput.Value(); got != "Tracks 1" {
+		t.Errorf("query = %q after typing in the middle, want Tracks 1", got)
+	}
+}
+
+func TestUpdateSearchAsYouType(t *testing.T) {
+	m, _ := newTestModel(t)
+	m = press(t, m, "/")
+
+	// 入力の途中の予約は、後の入力で取り消される
+	m = typeText(t, m, "Tr")
+	old := m.searchSeq
+	m = typeText(t, m, "ack 3")
+	m = update(t, m, searchFireMsg(old))
+	if m.searchedQuery != "" || len(m.searchResults) != 0 {
+		t.Errorf("searched %q with %d results before the input stopped", m.searchedQuery, len(m.searchResults))
+	}
+
+	// 入力が止まったら検索する
+	m = fireSearch(t, m)
+	if m.searchedQuery != "Track 3" || len(m.searchResults) != 1 || m.searchResults[0].uri != testPlaylist[3].URI {
+		t.Fatalf("searched %q with %d results, want Track 3 only", m.searchedQuery, len(m.searchResults))
+	}
+
+	// 検索中に入力を続けたら、前の検索の結果は捨てる
+	m = press(t, m, "backspace")
+	m, search := updateOnly(m, searchFireMsg(m.searchSeq))
+	m = typeText(t, m, "4")
+	m = run(t, m, search)
+	if m.searchedQuery != "Track 3" {
+		t.Errorf("searched %q, want the stale results for \"Track \" dropped", m.searchedQuery)
+	}
+
+	// 検索語を消したら結果も消す
+	m = press(t, m, slices.Repeat([]string{"backspace"}, len("Track 4"))...)
+	if m.searchedQuery != "" || len(m.searchResults) != 0 {
+		t.Errorf("searched %q with %d results after clearing the query", m.searchedQuery, len(m.searchResults))
+	}
+}
+
+func TestUpdateSearchFilters(t *testing.T) {
+	m, _ := newTestModel(t)
+	m = typeText(t, press(t, m, "/"), "ar")
+	if hint := m.searchHint(); !strings.Contains(hint, "artist:") {
+		t.Errorf("hint = %q, want the artist: completion", hint)
+	}
+
+	// 補完したフィルタは値を入力するまで検索しない
+	m, cmd := updateOnly(m, tea.KeyMsg{Type: tea.KeyTab})
+	if got := m.searchInput.Value(); got != "artist:" {
+		t.Fatalf("query = %q after completing, want artist:", got)
+	}
+	if cmd != nil {
+		t.Error("scheduled a search before the filter had a value")
+	}
+	if hint := m.searchHint(); hint != "e.g. artist:Radiohead" {
+		t.Errorf("hint = %q, want an example", hint)
+	}
+
+	m = fireSearch(t, typeText(t, m, "Artist"))
+	if m.searchedQuery != "artist:Artist" {
+		t.Errorf("searched %q, want artist:Artist", m.searchedQuery)
+	}
+}
+
+func TestUpdateSearchHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m, _ := newTestModel(t)
+	m = press(t, m, "/")
+
+	// 検索した検索語を新しい順に保存する
+	for _, q := range []string{"Track 1", "Album", "Track 1"} {
+		m = typeText(t, m, q)
+		m = press(t, m, "enter")
+		m = update(t, m, tea.KeyMsg{Type: tea.KeyCtrlU})
+	}
+	want := []string{"Track 1", "Album"}
+	if !slices.Equal(m.searchHistory, want) {
+		t.Errorf("history = %v, want %v", m.searchHistory, want)
+	}
+	saved, err := config.LoadSearchHistory(m.profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(saved, want) {
+		t.Errorf("saved history = %v, want %v", saved, want)
+	}
+
+	// ↑で古い方へ、↓で新しい方へ進み、最新より先は入力前の検索語に戻る
+	m = press(t, m, "up")
+	if got := m.searchInput.Value(); got != "Track 1" {
+		t.Errorf("query = %q after up, want Track 1", got)
+	}
+	m = press(t, m, "up", "up")
+	if got := m.searchInput.Value(); got != "Album" {
+		t.Errorf("query = %q after going past the oldest, want Album", got)
+	}
+	m = press(t, m, "down", "down")
+	if got := m.searchInput.Value(); got != "" {
+		t.Errorf("query = %q after coming back, want empty", got)
+	}
+
+	// 次に開いたときは保存した履歴を読み込む
+	m, _ = newTestModel(t)
+	m = run(t, m, m.loadSearchHistory())
+	if !slices.Equal(m.searchHistory, want) {
+		t.Errorf("loaded history = %v, want %v", m.searchHistory, want)
+	}
+}
+
+func TestUpdateSearchPages(t *testing.T) {
+	m, fake := newTestModel(t)
+	songs := make([]spotifysdk.FullTrack, 45)
+	for i := range songs {
+		songs[i] = spotify.FakeTrack(fmt.Sprintf("song%02d", i), fmt.Sprintf("Song %02d", i), "Singer", "Songs", 3*time.Minute)
+	}
+	fake.AddPlaylist(spotifysdk.SimplePlaylist{ID: "mix", Name: "Mix"}, songs)
+
+	// 最初のページと、API が返した総数を表示する
+	m = fireSearch(t, typeText(t, press(t, m, "/"), "Song"))
+	view := m.renderSearchView(100, 40)
+	if !strings.Contains(view, "Tracks (20 of 45)") || !strings.Contains(view, "↓ more") {
+		t.Errorf("search view doesn't show the first page of 45 tracks:\n%s", view)
+	}
+
+	// 選択がセクションの最後に近づいたら続きを読み込み、選択はそのままにする
+	m = press(t, m, slices.Repeat([]string{"down"}, 14)...)
+	if n := len(m.searchResults); n != 21 {
+		t.Fatalf("%d results before reaching the end of the page, want 21", n)
+	}
+	m = press(t, m, "down")
+	if got := m.searchResults[m.searchIndex].name; got != "Song 15" || len(m.searchResults) != 41 {
+		t.Errorf("selected %q with %d results after loading more, want Song 15 with 41", got, len(m.searchResults))
+	}
+
+	// 読み込み中に後ろのセクションを選んでも、届いた結果で選択がずれない
+	m = press(t, m, slices.Repeat([]string{"down"}, 19)...)
+	m, more := updateOnly(m, tea.KeyMsg{Type: tea.KeyDown})
+	if more == nil || !m.searchPages[searchTrack].loading {
+		t.Fatal("didn't load the last page")
+	}
+	if !strings.Contains(m.renderSearchView(100, 40), "Loading more...") {
+		t.Error("search view doesn't show the page loading")
+	}
+	m, _ = updateOnly(m, tea.KeyMsg{Type: tea.KeyTab})
+	m = run(t, m, more)
+	if e := m.searchResults[m.searchIndex]; e.kind != searchAlbum || e.name != "Songs" {
+		t.Errorf("selected %q after the last page arrived, want the album Songs", e.name)
+	}
+	if page := m.searchPages[searchTrack]; page.next != 0 || len(m.searchResults) != 46 {
+		t.Errorf("next = %d with %d results, want all 45 tracks and the album", page.next, len(m.searchResults))
+	}
+	if view := m.renderSearchView(100, 40); strings.Contains(view, "↓ more") {
+		t.Error("search view still offers more after the last page")
+	}
+}
+
+func TestUpdateSearchPageErrors(t *testing.T) {
+	m, _ := newTestModel(t)
+	m = fireSearch(t, typeText(t, press(t, m, "/"), "Track"))
+	m.searchPages[searchTrack] = searchPage{total: 30, next: 20, loading: true}
+
+	// 検索語を変えた後に届いた続きのページは捨てる
+	m, _ = updateOnly(m, searchMoreMsg{seq: m.searchSeq - 1, kind: searchTrack, results: &spotify.SearchResults{}})
+	if !m.searchPages[searchTrack].loading {
+		t.Error("a stale page finished the loading")
+	}
+
+	// 失敗したら読み込み中の表示をやめて、エラーを表示する
+	m, cmd := updateOnly(m, searchMoreMsg{seq: m.searchSeq, kind: searchTrack, err: fmt.Errorf("boom")})
+	if page := m.searchPages[searchTrack]; page.loading || page.next != 20 {
+		t.Errorf("page = %+v after a failed page, want the next page kept for retrying", page)
+	}
+	if cmd == nil {
+		t.Fatal("no error for the failed page")
+	}
+	if msg, ok := cmd().(errorMsg); !ok || !strings.Contains(string(msg), "boom") {
+		t.Errorf("error = %v, want boom", msg)
+	}
+	if len(m.searchResults) != len(testPlaylist) {
+		t.Errorf("%d results after a failed page, want %d", len(m.searchResults), len(testPlaylist))
+	}
+}
+
+// fakePlaylist は FakePlayer のプレイリストの曲とスナップショットIDを返す
+func fakePlaylist(t *testing.T, fake *spotify.FakePlayer, id spotifysdk.ID) ([]spotifysdk.URI, string) {
+	t.Helper()
+	tracks, err := fake.PlaylistTracks(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uris := make([]spotifysdk.URI, len(tracks))
+	for i, track := range tracks {
+		uris[i] = track.Track.URI
+	}
+	playlists, err := fake.UserPlaylists(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pl := range playlists {
+		if pl.ID == id {
+			return uris, pl.SnapshotID
+		}
+	}
+	t.Fatalf("playlist %s not found", id)
+	return nil, ""
+}
+
+// openTestPlaylist はサイドバーでプレイリストを開いてメインパネルにフォーカスし、row 番目の曲を選ぶ
+func openTestPlaylist(t *testing.T, m Model, row int) Model {
+	t.Helper()
+	m = press(t, m, "j", "j", "enter", "tab")
+	return press(t, m, slices.Repeat([]string{"j"}, row)...)
+}
+
+// trackURIs は開いているプレイリストの曲のURI
+func trackURIs(m Model) []spotifysdk.URI {
+	uris := make([]spotifysdk.URI, len(m.tracks))
+	for i, t := range m.tracks {
+		uris[i] = t.Track.URI
+	}
+	return uris
+}
+
+func TestUpdateRemoveAndUndo(t *testing.T) {
+	m, fake := newTestModel(t)
+	m = openTestPlaylist(t, m, 2)
+	original, _ := fakePlaylist(t, fake, "playlist")
+
+	m = press(t, m, "x")
+	removed, snapshot := fakePlaylist(t, fake, "playlist")
+	if want := slices.Delete(slices.Clone(original), 2, 3); !slices.Equal(removed, want) {
+		t.Errorf("playlist = %v after removing, want %v", removed, want)
+	}
+	if !slices.Equal(trackURIs(m), removed) {
+		t.Errorf("main panel = %v, want %v", trackURIs(m), removed)
+	}
+	// 次の変更に使うスナップショットIDをサイドバーに保存する
+	if item, _, _ := m.sidebarPlaylist("playlist"); item.snapshotID != snapshot {
+		t.Errorf("sidebar snapshot = %q, want %q", item.snapshotID, snapshot)
+	}
+	if !strings.Contains(m.notice, "Removed Track 2 from Playlist") || !strings.Contains(m.notice, "[u] to undo") {
+		t.Errorf("notice = %q, want the removal with undo", m.notice)
+	}
+
+	// 削除した曲を同じ位置に戻す
+	m = press(t, m, "u")
+	restored, restoredSnapshot := fakePlaylist(t, fake, "playlist")
+	if !slices.Equal(restored, original) {
+		t.Errorf("playlist = %v after undo, want %v", restored, original)
+	}
+	if !slices.Equal(trackURIs(m), original) {
+		t.Errorf("main panel = %v after undo, want %v", trackURIs(m), original)
+	}
+	if selected, ok := m.trackList.SelectedItem().(trackItem); !ok || selected.index != 2 {
+		t.Errorf("selected %v after undo, want the restored track", m.trackList.SelectedItem())
+	}
+	if item, _, _ := m.sidebarPlaylist("playlist"); item.snapshotID != restoredSnapshot || restoredSnapshot == snapshot {
+		t.Errorf("sidebar snapshot = %q after undo, want the new %q", item.snapshotID, restoredSnapshot)
+	}
+
+	// 元に戻せるのは最後の削除の1回だけ
+	m = press(t, m, "u")
+	if again, _ := fakePlaylist(t, fake, "playlist"); !slices.Equal(again, original) {
+		t.Errorf("playlist = %v after undoing twice, want %v", again, original)
+	}
+}
+
+func TestUpdateMoveTrack(t *testing.T) {
+	m, fake := newTestModel(t)
+	m = openTestPlaylist(t, m, 1)
+
+	m = press(t, m, "J", "J")
+	want := []spotifysdk.URI{testPlaylist[0].URI, testPlaylist[2].URI, testPlaylist[3].URI, testPlaylist[1].URI, testPlaylist[4].URI}
+	moved, snapshot := fakePlaylist(t, fake, "playlist")
+	if !slices.Equal(moved, want) || !slices.Equal(trackURIs(m), want) {
+		t.Errorf("playlist = %v, main panel = %v; want %v", moved, trackURIs(m), want)
+	}
+	if selected, ok := m.trackList.SelectedItem().(trackItem); !ok || selected.index != 3 {
+		t.Errorf("selected %v, want the moved track", m.trackList.SelectedItem())
+	}
+	if item, _, _ := m.sidebarPlaylist("playlist"); item.snapshotID != snapshot {
+		t.Errorf("sidebar snapshot = %q, want %q", item.snapshotID, snapshot)
+	}
+
+	// 先頭より上には移さない
+	m = press(t, m, "K", "K", "K", "K")
+	want = []spotifysdk.URI{testPlaylist[1].URI, testPlaylist[0].URI, testPlaylist[2].URI, testPlaylist[3].URI, testPlaylist[4].URI}
+	if moved, _ := fakePlaylist(t, fake, "playlist"); !slices.Equal(moved, want) {
+		t.Errorf("playlist = %v after moving to the top, want %v", moved, want)
+	}
+}
+
+func TestUpdatePlaylistEditErrors(t *testing.T) {
+	m, fake := newTestModel(t)
+	m = openTestPlaylist(t, m, 2)
+
+	// 応答を待っている間の変更は送らない
+	m, send := updateOnly(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	m, again := updateOnly(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("J")})
+	if again != nil {
+		t.Error("sent a second edit before the first finished")
+	}
+
+	// 失敗したら表示は変えずにエラーを表示する（曲を先に消しておき、削除を失敗させる）
+	if _, err := fake.RemovePlaylistItem(context.Background(), "playlist", testPlaylist[2].URI, 2, ""); err != nil {
+		t.Fatal(err)
+	}
+	m, cmd := updateOnly(m, send())
+	if m.editPending || m.undo != nil {
+		t.Errorf("pending = %v, undo = %v after a failed edit", m.editPending, m.undo)
+	}
+	if len(m.tracks) != len(testPlaylist) || m.tracks[2].Track.URI != testPlaylist[2].URI {
+		t.Errorf("main panel = %v after a failed edit, want it unchanged", trackURIs(m))
+	}
+	if msg, ok := cmd().(errorMsg); !ok || !strings.Contains(string(msg), "Could not remove tracks") {
+		t.Errorf("error = %v, want the failed removal", msg)
+	}
+
+	// ほかのユーザーのプレイリストの曲は変更できない
+	m, fake = newTestModel(t)
+	fake.AddPlaylist(spotifysdk.SimplePlaylist{ID: "shared", Name: "Shared", Owner: spotifysdk.User{ID: "friend"}}, testPlaylist)
+	m = run(t, m, m.fetchPlaylistsPage(0))
+	m = press(t, m, "j", "j", "j", "enter", "tab")
+	m, cmd = updateOnly(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if msg, ok := cmd().(errorMsg); !ok || !strings.Contains(string(msg), "Only tracks in your own or collaborative playlists") {
+		t.Errorf("error = %v, want the playlist not editable", msg)
+	}
+}
+
+func TestUpdateDeleteAndUndoPlaylist(t *testing.T) {
+	m, fake := newTestModel(t)
+	m = press(t, m, "j", "j", "x")
+	if _, _, ok := m.sidebarPlaylist("playlist"); ok {
+		t.Fatal("playlist still in the sidebar after deleting")
+	}
+	if playlists, _ := fake.UserPlaylists(context.Background()); len(playlists) != 0 {
+		t.Errorf("still following %d playlists", len(playlists))
+	}
+
+	m = press(t, m, "u")
+	if _, _, ok := m.sidebarPlaylist("playlist"); !ok {
+		t.Error("playlist not back in the sidebar after undo")
+	}
+	if playlists, _ := fake.UserPlaylists(context.Background()); len(playlists) != 1 {
+		t.Errorf("following %d playlists after undo, want 1", len(playlists))
+	}
+}
+
+// countURI は uris に uri がいくつあるかを返す
+func countURI(uris []spotifysdk.URI, uri spotifysdk.URI) int {
+	n := 0
+	for _, u := range uris {
+		if u == uri {
+			n++
+		}
+	}
+	return n
+}
+
+func TestUpdateAddToQueueReconcile(t *testing.T) {
+	extra := spotify.FakeTrack("extra", "Extra", "Other", "Other", 3*time.Minute)
+	m, fake := newTestModel(t)
+	fake.AddPlaylist(spotifysdk.SimplePlaylist{ID: "other", Name: "Other"}, []spotifysdk.FullTrack{extra})
+	m = run(t, m, m.fetchPlaylistsPage(0))
+	m = press(t, m, "j", "j", "j", "enter", "tab")
+
+	// 追加のリクエストを送る前から、キューの先頭に表示する
+	m, add := updateOnly(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("z")})
+	if len(m.queue) == 0 || m.queue[0].URI != extra.URI {
+		t.Fatalf("queue = %v before adding, want %s first", queueURIs(m), extra.URI)
+	}
+
+	// 追加が済む前に取得したキューには含まれていないので、表示したまま反映を待つ
+	m = poll(t, m)
+	if got := queueURIs(m); len(got) == 0 || got[0] != extra.URI || len(m.queuePending) != 1 {
+		t.Errorf("queue = %v with %d pending after an earlier fetch, want %s still first", got, len(m.queuePending), extra.URI)
+	}
+
+	// 追加が済んだ後に取得したキューで置き換え、同じ曲を2回表示しない
+	m = run(t, m, add)
+	m = poll(t, poll(t, m))
+	if got := queueURIs(m); len(got) == 0 || got[0] != extra.URI || countURI(got, extra.URI) != 1 {
+		t.Errorf("queue = %v after fetching, want %s once at the top", got, extra.URI)
+	}
+	if len(m.queuePending) != 0 {
+		t.Errorf("%d tracks still pending after the fetched queue included them", len(m.queuePending))
+	}
+}
+
+func TestUpdateAddToQueueFails(t *testing.T) {
+	m, _ := newTestModel(t)
+	m = openTestPlaylist(t, m, 3)
+	before := queueURIs(m)
+
+	// 失敗したら楽観的に加えた曲を除き、エラーを表示する
+	m, add := updateOnly(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("z")})
+	msg := add().(queueAddedMsg)
+	m, cmd := updateOnly(m, queueAddedMsg{id: msg.id, track: msg.track, err: fmt.Errorf("boom")})
+	if got := queueURIs(m); !slices.Equal(got, before) || len(m.queuePending) != 0 {
+		t.Errorf("queue = %v with %d pending after a failed add, want %v", got, len(m.queuePending), before)
+	}
+	if msg, ok := cmd().(errorMsg); !ok || !strings.Contains(string(msg), "boom") {
+		t.Errorf("error = %v, want boom", msg)
+	}
+}
+
+func TestUpdateAddToQueueNoActiveDevice(t *testing.T) {
+	m, fake := newTestModel(t)
+	m = openTestPlaylist(t, m, 3)
+	fake.SetDevices(testDevices(""))
+	m = run(t, m, m.fetchDevices())
+	before := queueURIs(m)
+
+	// アクティブなデバイスがなければ表示から除き、デバイスを選ばせる
+	m = press(t, m, "z")
+	if got := queueURIs(m); !slices.Equal(got, before) {
+		t.Errorf("queue = %v after failing without a device, want %v", got, before)
+	}
+	if !m.showDevices || m.deviceRetry == nil {
+		t.Fatalf("picker open = %v, retry = %v; want the device picker with the retry", m.showDevices, m.deviceRetry != nil)
+	}
+
+	// デバイスを選んだらもう一度加える
+	m = poll(t, press(t, m, "enter"))
+	if got := queueURIs(m); len(got) == 0 || got[0] != testPlaylist[3].URI {
+		t.Errorf("queue = %v after picking a device, want %s first", got, testPlaylist[3].URI)
+	}
+}
@@ -5,148 +5,163 @@ import (
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/mattn/go-runewidth"
 )
 
-var (
-	// Colors
-	primaryColor   = lipgloss.Color("#1DB954") // Spotify Green
-	secondaryColor = lipgloss.Color("#FFFFFF")
-	accentColor    = lipgloss.Color("#B3B3B3")
-	bgColor        = lipgloss.Color("#121212")
-	highlightColor = lipgloss.Color("#282828")
-
-	// Styles
-	titleStyle = lipgloss.NewStyle().
-			Foreground(primaryColor).
-			Bold(true)
-
-	sidebarStyle = lipgloss.NewStyle().
-			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(accentColor).
-			Padding(0)
-
-	mainPanelStyle = lipgloss.NewStyle().
-			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(accentColor).
-			Padding(0)
-
-	playerBarStyle = lipgloss.NewStyle().
-			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(primaryColor).
-			Padding(0, 1)
-
-	focusedStyle = lipgloss.NewStyle().
-			BorderForeground(primaryColor)
-
-	trackStyle = lipgloss.NewStyle().
-			Foreground(secondaryColor)
-
-	selectedTrackStyle = lipgloss.NewStyle().
-				Foreground(primaryColor).
-				Bold(true).
-				Background(highlightColor)
-
-	playingTrackStyle = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#1DB954")).
-				Bold(true)
-
-	errorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF0000")).
-			Bold(true)
-)
-
 func (m Model) View() string {
 	if m.width == 0 {
 		return "Initializing..."
 	}
 
-	// Minimum size check
-	if m.height < 15 || m.width < 100 {
+	// Minimum size check（どのレイアウトも収まらない）
+	layout := m.layout()
+	if layout.Mode == LayoutAuto {
+		size := minLayoutSize[LayoutMini]
 		return lipgloss.Place(
 			m.width, m.height,
 			lipgloss.Center, lipgloss.Center,
-			"Terminal too small\n(min: 100x15)",
+			fmt.Sprintf("Terminal too small\n(min: %dx%d)", size.width, size.height),
 		)
 	}
 
-	layout := CalculateLayout(m.width, m.height)
+	if m.loginURL != "" {
+		return lipgloss.Place(
+			m.width, m.height,
+			lipgloss.Center, lipgloss.Center,
+			m.renderLoginPrompt(),
+		)
+	}
 
-	// Render top row content
-	sidebarContent := m.renderSidebar(layout.LeftContentWidth, layout.TopContentHeight)
-	mainContent := m.renderMainPanel(layout.MainContentWidth, layout.TopContentHeight)
-	queueContent := m.renderQueue(layout.RightContentWidth, layout.TopContentHeight)
+	if m.showHelp {
+		return lipgloss.Place(
+			m.width, m.height,
+			lipgloss.Center, lipgloss.Center,
+			m.renderHelp(),
+		)
+	}
 
-	// Apply borders and styling
-	// lipgloss Height() is for content inside border, border is added on top
-	// So we need to account for this by subtracting border from total height
-	panelHeight := layout.TopPanelHeight - borderSize
-	if panelHeight < 1 {
-		panelHeight = 1
+	if m.showAccounts {
+		return lipgloss.Place(
+			m.width, m.height,
+			lipgloss.Center, lipgloss.Center,
+			m.renderAccountPicker(),
+		)
 	}
 
-	sidebarStyleFinal := sidebarStyle.
-		Width(layout.LeftWidth - borderSize).
-		Height(panelHeight)
+	if m.showDevices {
+		return lipgloss.Place(
+			m.width, m.height,
+			lipgloss.Center, lipgloss.Center,
+			m.renderDevicePicker(),
+		)
+	}
 
-	mainPanelStyleFinal := mainPanelStyle.
-		Width(layout.MainWidth - borderSize).
-		Height(panelHeight)
+	if m.namePrompt != promptNone {
+		return lipgloss.Place(
+			m.width, m.height,
+			lipgloss.Center, lipgloss.Center,
+			m.renderNamePrompt(),
+		)
+	}
 
-	rightPanelStyleFinal := mainPanelStyle.Copy().
-		Width(layout.RightWidth - borderSize).
-		Height(panelHeight)
+	if m.showPlaylistPicker {
+		return lipgloss.Place(
+			m.width, m.height,
+			lipgloss.Center, lipgloss.Center,
+			m.renderPlaylistPicker(),
+		)
+	}
 
-	switch m.focus {
-	case FocusSidebar:
-		sidebarStyleFinal = sidebarStyleFinal.Copy().BorderForeground(primaryColor)
-	case FocusMain:
-		mainPanelStyleFinal = mainPanelStyleFinal.Copy().BorderForeground(primaryColor)
-	case FocusQueue:
-		rightPanelStyleFinal = rightPanelStyleFinal.Copy().BorderForeground(primaryColor)
+	switch layout.Mode {
+	case LayoutMini:
+		return m.renderPlayerBarPanel(layout)
+	case LayoutStacked:
+		return lipgloss.JoinVertical(lipgloss.Left, m.renderStackedPanel(layout), m.renderPlayerBarPanel(layout))
 	}
 
-	// Top row: sidebar + main + queue
-	topRow := lipgloss.JoinHorizontal(
-		lipgloss.Top,
-		sidebarStyleFinal.Render(sidebarContent),
-		mainPanelStyleFinal.Render(mainContent),
-		rightPanelStyleFinal.Render(queueContent),
-	)
+	// Apply borders and styling
+	// lipgloss Height() is for content inside border, border is added on top
+	// So we need to account for this by subtracting border from total height
+	panelHeight := max(layout.TopPanelHeight-borderSize, 1)
+
+	panelStyle := func(panel FocusPanel) lipgloss.Style {
+		if m.focus == panel {
+			return m.theme.FocusedPanel
+		}
+		return m.theme.Panel
+	}
 
+	// Top row: sidebar + main + queue（幅0のパネルは隠している）
 	// Bottom bar: user info (left) + player bar (center) + device info (right)
-	userInfoContent := m.renderUserInfo(layout.LeftContentWidth)
-	playerBarContent := m.renderPlayerBar(layout.MainContentWidth)
-	deviceInfoContent := m.renderDeviceInfo(layout.RightContentWidth)
+	var topPanels, bottomPanels []string
+	if layout.LeftWidth > 0 {
+		topPanels = append(topPanels, panelStyle(FocusSidebar).
+			Width(layout.LeftWidth-borderSize).
+			Height(panelHeight).
+			Render(m.renderSidebar(layout.LeftContentWidth, layout.TopContentHeight)))
+		bottomPanels = append(bottomPanels, m.theme.PlayerBar.
+			Width(layout.LeftWidth-borderSize).
+			Height(layout.BottomContentHeight).
+			Render(m.renderUserInfo(layout.LeftContentWidth)))
+	}
 
-	userInfoFinal := playerBarStyle.
-		Width(layout.LeftWidth - borderSize).
-		Height(layout.BottomContentHeight).
-		Render(userInfoContent)
+	topPanels = append(topPanels, panelStyle(FocusMain).
+		Width(layout.MainWidth-borderSize).
+		Height(panelHeight).
+		Render(m.renderMainPanel(layout.MainContentWidth, layout.TopContentHeight)))
+	bottomPanels = append(bottomPanels, m.renderPlayerBarPanel(layout))
+
+	if layout.RightWidth > 0 {
+		topPanels = append(topPanels, panelStyle(FocusQueue).
+			Width(layout.RightWidth-borderSize).
+			Height(panelHeight).
+			Render(m.renderQueue(layout.RightContentWidth, layout.TopContentHeight)))
+		bottomPanels = append(bottomPanels, m.theme.PlayerBar.
+			Width(layout.RightWidth-borderSize).
+			Height(layout.BottomContentHeight).
+			Render(m.renderDeviceInfo(layout.RightContentWidth)))
+	}
 
-	playerBarFinal := playerBarStyle.
-		Width(layout.MainWidth - borderSize).
-		Height(layout.BottomContentHeight).
-		Render(playerBarContent)
+	topRow := lipgloss.JoinHorizontal(lipgloss.Top, topPanels...)
+	bottomRow := lipgloss.JoinHorizontal(lipgloss.Top, bottomPanels...)
 
-	deviceInfoFinal := playerBarStyle.
-		Width(layout.RightWidth - borderSize).
-		Height(layout.BottomContentHeight).
-		Render(deviceInfoContent)
+	return lipgloss.JoinVertical(lipgloss.Left, topRow, bottomRow)
+}
 
-	bottomRow := lipgloss.JoinHorizontal(
-		lipgloss.Top,
-		userInfoFinal,
-		playerBarFinal,
-		deviceInfoFinal,
-	)
+// renderStackedPanel はフォーカスのあるパネルを全幅で描画する（LayoutStacked）
+// 検索中は常にメインパネルを表示する
+func (m Model) renderStackedPanel(layout Layout) string {
+	var content string
+	switch {
+	case m.searchMode || m.focus == FocusMain:
+		content = m.renderMainPanel(layout.MainContentWidth, layout.TopContentHeight)
+	case m.focus == FocusSidebar:
+		content = m.renderSidebar(layout.LeftContentWidth, layout.TopContentHeight)
+	case m.focus == FocusQueue:
+		content = m.renderQueue(layout.RightContentWidth, layout.TopContentHeight)
+	}
+	return m.theme.FocusedPanel.
+		Width(layout.MainWidth - borderSize).
+		Height(max(layout.TopPanelHeight-borderSize, 1)).
+		Render(content)
+}
 
-	return lipgloss.JoinVertical(lipgloss.Left, topRow, bottomRow)
+// renderPlayerBarPanel は枠を付けたプレイヤーバーを描画する
+func (m Model) renderPlayerBarPanel(layout Layout) string {
+	return m.theme.PlayerBar.
+		Width(layout.PlayerWidth - borderSize).
+		Height(layout.BottomContentHeight).
+		Render(m.renderPlayerBar(layout.PlayerWidth - 4))
 }
 
 func (m Model) renderSidebar(width, height int) string {
-	title := titleStyle.Render(truncate(" 🎵 My Library", width))
+	titleText := " 🎵 My Library"
+	if m.playlistsCount < m.playlistsTotal {
+		titleText += fmt.Sprintf(" (loading %d/%d)", m.playlistsCount, m.playlistsTotal)
+	}
+	title := m.theme.Title.Render(truncate(titleText, width))
 
 	if len(m.playlists.Items()) == 0 {
 		return lipgloss.Place(
@@ -167,6 +182,15 @@ func (m Model) renderMainPanel(width, height int) string {
 		return m.renderSearchView(width, height)
 	}
 
+	switch m.view {
+	case viewArtist:
+		return m.renderArtistPage(width, height)
+	case viewAlbum:
+		return m.renderAlbumPage(width, height)
+	case viewSavedAlbums:
+		return m.renderSavedAlbums(width, height)
+	}
+
 	if m.loadingTracks {
 		return lipgloss.Place(
 			width, height,
@@ -183,7 +207,11 @@ func (m Model) renderMainPanel(width, height int) string {
 		)
 	}
 
-	title := titleStyle.Render(truncate(" 📀 Tracks", width))
+	titleText := " 📀 Tracks"
+	if len(m.tracks) < m.tracksTotal {
+		titleText += fmt.Sprintf(" (loading %d/%d)", len(m.tracks), m.tracksTotal)
+	}
+	title := m.theme.Title.Render(truncate(titleText, width))
 	content := m.trackList.View()
 	inner := lipgloss.JoinVertical(lipgloss.Left, title, "", content)
 
@@ -192,70 +220,100 @@ func (m Model) renderMainPanel(width, height int) string {
 
 func (m Model) renderSearchView(width, height int) string {
 	var lines []string
-	title := titleStyle.Render(truncate(" 🔍 Search", width))
-	query := truncate(fmt.Sprintf(" Query: %s_", m.searchQuery), width)
-	lines = append(lines, title, "", query, "")
+	title := m.theme.Title.Render(truncate(" 🔍 Search", width))
+	// 入力欄の下にはフィルタの補完や入力例を表示する
+	hint := m.theme.MutedText.Render(truncate("  "+m.searchHint(), width))
+	lines = append(lines, title, "", m.searchInput.View(), hint, "")
 
 	if len(m.searchResults) == 0 {
-		if m.searchQuery == "" {
-			hint := lipgloss.NewStyle().
-				Foreground(accentColor).
-				Render(truncate(" Type to search, Enter to execute, Esc to exit", width))
-			lines = append(lines, hint)
-		} else {
+		query := strings.TrimSpace(m.searchInput.Value())
+		switch {
+		case query == "":
+			lines = append(lines, m.theme.MutedText.Render(truncate(" Type to search, Enter to open, Esc to exit", width)))
+		case query == m.searchedQuery:
 			lines = append(lines, " No results found")
+		case searchReady(query):
+			lines = append(lines, m.theme.MutedText.Render(truncate(" Searching...", width)))
 		}
 		inner := strings.Join(lines, "\n")
 		return lipgloss.Place(width, height, lipgloss.Left, lipgloss.Top, inner)
 	}
 
-	// 検索結果を表示
-	lines = append(lines, lipgloss.NewStyle().
-		Foreground(accentColor).
-		Render(truncate(fmt.Sprintf(" Found %d results:", len(m.searchResults)), width)))
-	lines = append(lines, "")
+	// 検索結果をセクションごとに見出しを付けて表示
+	lines = append(lines, m.renderSections(m.searchEntrySections(), m.searchIndex, width, height-len(lines))...)
 
-	// スクロール可能な結果リスト
-	visibleLines := height - 6
-	if visibleLines < 1 {
-		visibleLines = 1
-	}
+	inner := strings.Join(lines, "\n")
+	return lipgloss.Place(width, height, lipgloss.Left, lipgloss.Top, inner)
+}
 
-	start := m.searchIndex
-	if start > len(m.searchResults)-visibleLines {
-		start = len(m.searchResults) - visibleLines
-	}
-	if start < 0 {
-		start = 0
-	}
+// entrySection は見出しを付けて並べる項目のまとまり（検索結果やアーティストページのセクション）
+type entrySection struct {
+	title   string
+	entries []searchEntry
+	page    searchPage // 続きのページ（続きを読み込まないセクションでは空）
+}
 
-	for i := start; i < len(m.searchResults) && i < start+visibleLines; i++ {
-		track := m.searchResults[i]
-		line := fmt.Sprintf(" %2d. %s - %s",
-			i+1,
-			track.Name,
-			track.Artists[0].Name,
-		)
+// renderSections はセクションごとに見出しを付けて項目を並べる
+// selected は全セクションを通した選択位置で、選択中の行が見えるように height 行に収める
+// 項目のないセクションは表示しない
+func (m Model) renderSections(sections []entrySection, selected, width, height int) []string {
+	var rows []string
+	selectedRow, index := 0, 0
+	for _, section := range sections {
+		if len(section.entries) == 0 {
+			continue
+		}
+		if len(rows) > 0 {
+			rows = append(rows, "")
+		}
+		// 読み込んでいない項目がある場合は、API が返した総数も表示する
+		header := fmt.Sprintf(" %s (%d)", section.title, len(section.entries))
+		if section.page.total > len(section.entries) {
+			header = fmt.Sprintf(" %s (%d of %d)", section.title, len(section.entries), section.page.total)
+		}
+		rows = append(rows, m.theme.Title.Render(truncate(header, width)))
 
-		if i == m.searchIndex {
-			// 選択中: " ▶" (3文字分) + line
-			line = selectedTrackStyle.Width(width).Render(truncate(" ▶"+line, width))
-		} else {
-			// 非選択: "  " (2文字分) + line
-			line = trackStyle.Width(width).Render(truncate("  "+line, width))
+		for _, e := range section.entries {
+			line := " " + e.name
+			if e.kind == searchTrack || e.kind == searchAlbum {
+				// 詳細が長くても切れないよう、♥は名前の後に付ける
+				line += m.likedMark(e.uri)
+			}
+			if e.detail != "" {
+				line += " - " + e.detail
+			}
+			if index == selected {
+				// 選択中: " ▶" (3文字分) + line
+				selectedRow = len(rows)
+				line = m.theme.SelectedTrack.Width(width).Render(truncate(" ▶"+line, width))
+			} else {
+				// 非選択: "  " (2文字分) + line
+				line = m.theme.Track.Width(width).Render(truncate("  "+line, width))
+			}
+			rows = append(rows, line)
+			index++
 		}
 
-		lines = append(lines, line)
+		// セクションの最後に続きのページがあることを表示する
+		switch {
+		case section.page.loading:
+			rows = append(rows, m.theme.MutedText.Render(truncate("   Loading more...", width)))
+		case section.page.next > 0:
+			rows = append(rows, m.theme.MutedText.Render(truncate("   ↓ more", width)))
+		}
 	}
 
-	inner := strings.Join(lines, "\n")
-	return lipgloss.Place(width, height, lipgloss.Left, lipgloss.Top, inner)
+	// スクロール可能なリスト（選択中の行とその少し上の見出しが見えるようにする）
+	visibleLines := max(height, 1)
+	start := min(max(selectedRow-visibleLines/3, 0), max(len(rows)-visibleLines, 0))
+	end := min(start+visibleLines, len(rows))
+	return rows[start:end]
 }
 
 func (m Model) renderUserInfo(width int) string {
 	var lines []string
 
-	title := titleStyle.Render(truncate("👤 User", width))
+	title := m.theme.Title.Render(truncate("👤 User", width))
 	lines = append(lines, title)
 
 	if m.user != nil {
@@ -271,6 +329,9 @@ func (m Model) renderUserInfo(width int) string {
 			lines = append(lines, truncate(fmt.Sprintf(" Plan:      %s", m.user.Product), width))
 		}
 		lines = append(lines, truncate(fmt.Sprintf(" Followers: %d", m.user.Followers.Count), width))
+		if m.accounts != nil {
+			lines = append(lines, truncate(fmt.Sprintf(" Profile:   %s", m.profile), width))
+		}
 	} else {
 		lines = append(lines, " Loading...")
 	}
@@ -278,48 +339,53 @@ func (m Model) renderUserInfo(width int) string {
 	return strings.Join(lines, "\n")
 }
 
-func (m Model) renderPlayerBar(width int) string {
-	var lines []string
-
-	// Context info (playlist/album name)
-	contextInfo := ""
+// playingContextName は再生中のコンテキスト（プレイリストやアルバム）の表示名を返す
+func (m Model) playingContextName() string {
 	if m.currentTrack != nil && m.currentTrack.PlaybackContext.Type != "" {
 		switch m.currentTrack.PlaybackContext.Type {
 		case "playlist":
 			// 再生開始時のプレイリスト名を使用
 			if m.playingPlaylistName != "" {
-				contextInfo = m.playingPlaylistName
-			} else {
-				contextInfo = "Playlist"
+				return m.playingPlaylistName
 			}
+			return "Playlist"
 		case "album":
 			if m.currentTrack.Item != nil {
-				contextInfo = m.currentTrack.Item.Album.Name
+				return m.currentTrack.Item.Album.Name
 			}
+			return ""
 		case "artist":
-			contextInfo = "Artist"
+			return m.playingArtistName()
 		case "collection":
-			contextInfo = "Liked Songs"
+			return "Liked Songs"
 		default:
-			contextInfo = string(m.currentTrack.PlaybackContext.Type)
+			return string(m.currentTrack.PlaybackContext.Type)
 		}
-	} else if m.playingPlaylistName != "" {
-		// コンテキストがない場合でも再生中のプレイリスト名を表示
-		contextInfo = m.playingPlaylistName
 	}
+	// コンテキストがない場合でも再生中のプレイリスト名を表示
+	return m.playingPlaylistName
+}
+
+func (m Model) renderPlayerBar(width int) string {
+	var lines []string
+
+	// Context info (playlist/album name)
+	contextInfo := m.playingContextName()
 
 	// Track info
 	trackInfo := "No track playing"
 	if m.currentTrack != nil && m.currentTrack.Item != nil {
 		if contextInfo != "" {
-			trackInfo = fmt.Sprintf("♫ %s - %s | %s",
+			trackInfo = fmt.Sprintf("♫ %s%s - %s | %s",
 				m.currentTrack.Item.Name,
+				m.likedMark(m.currentTrack.Item.URI),
 				m.currentTrack.Item.Artists[0].Name,
 				contextInfo,
 			)
 		} else {
-			trackInfo = fmt.Sprintf("♫ %s - %s",
+			trackInfo = fmt.Sprintf("♫ %s%s - %s",
 				m.currentTrack.Item.Name,
+				m.likedMark(m.currentTrack.Item.URI),
 				m.currentTrack.Item.Artists[0].Name,
 			)
 		}
@@ -353,9 +419,28 @@ func (m Model) renderPlayerBar(width int) string {
 	lines = append(lines, controls)
 
 	// Keybindings
-	keybindings := "[Space] Play/Pause | [n] Next | [p] Prev | [Tab] Switch | [/] Search | [q] Quit"
-	if m.err != "" {
-		keybindings = errorStyle.Render("Error: " + m.err)
+	keybindings := helpLine(m.keys.ShortHelp()...)
+	if len(m.pendingKeys) > 0 {
+		// chord の続きを待っている
+		keybindings = "Keys: " + keySpecName(m.pendingKeys) + " …"
+	}
+	if m.reloginRequired {
+		reason := "Session expired"
+		if m.missingScope {
+			reason = "New permissions needed"
+		}
+		if m.reauth != nil {
+			keybindings = m.theme.ErrorText.Render(reason + ": press [" + m.keys.binding(actRelogin).Help().Key + "] to log in again")
+		} else {
+			keybindings = m.theme.ErrorText.Render(reason + ": re-login required (restart spotify-tui)")
+		}
+		if m.err != "" {
+			keybindings = m.theme.ErrorText.Render("Error: " + m.err)
+		}
+	} else if m.err != "" {
+		keybindings = m.theme.ErrorText.Render("Error: " + m.err)
+	} else if m.notice != "" {
+		keybindings = m.notice
 	}
 	// 幅に収まらない場合はカットして...を追加
 	keybindings = truncate(keybindings, width)
@@ -364,12 +449,24 @@ func (m Model) renderPlayerBar(width int) string {
 	return strings.Join(lines, "\n")
 }
 
+// helpLine はキー割り当てから "[space] play/pause | [n] next track" のような行を作る
+func helpLine(bindings ...key.Binding) string {
+	var parts []string
+	for _, b := range bindings {
+		if !b.Enabled() {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("[%s] %s", b.Help().Key, b.Help().Desc))
+	}
+	return strings.Join(parts, " | ")
+}
+
 func (m Model) renderProgressBar(width int) string {
+	barWidth := progressBarWidth(width)
 	if m.currentTrack == nil || m.duration == 0 {
-		return "[" + strings.Repeat("░", width-20) + "] 0:00 / 0:00"
+		return "[" + strings.Repeat("░", barWidth) + "] 0:00 / 0:00"
 	}
 
-	barWidth := width - 20
 	progress := float64(m.progress) / float64(m.duration)
 	filled := int(progress * float64(barWidth))
 	if filled > barWidth {
@@ -402,10 +499,10 @@ func truncate(text string, width int) string {
 }
 
 func (m Model) renderQueue(width, height int) string {
-	title := titleStyle.Render(truncate(" 📋 Queue", width))
+	title := m.theme.Title.Render(truncate(" 📋 Queue", width))
 
 	if len(m.queue) == 0 {
-		inner := lipgloss.JoinVertical(lipgloss.Left, title, "", " No tracks in queue")
+		inner := lipgloss.JoinVertical(lipgloss.Left, title, "", truncate(" No tracks in queue", width))
 		return lipgloss.Place(width, height, lipgloss.Left, lipgloss.Top, inner)
 	}
 
@@ -418,7 +515,7 @@ func (m Model) renderQueue(width, height int) string {
 func (m Model) renderDeviceInfo(width int) string {
 	var lines []string
 
-	title := titleStyle.Render(truncate("🔊 Device", width))
+	title := m.theme.Title.Render(truncate("🔊 Device", width))
 	lines = append(lines, title)
 
 	if m.activeDevice != nil {
@@ -426,13 +523,23 @@ func (m Model) renderDeviceInfo(width int) string {
 		lines = append(lines, truncate(fmt.Sprintf(" Type: %s", m.activeDevice.Type), width))
 
 		// Volume bar
-		volBarWidth := width - 12
+		volBarWidth := width - 13
 		if volBarWidth < 5 {
 			volBarWidth = 5
 		}
-		volFilled := (m.volume * volBarWidth) / 100
-		volBar := strings.Repeat("█", volFilled) + strings.Repeat("░", volBarWidth-volFilled)
-		lines = append(lines, fmt.Sprintf(" Vol: [%s]", volBar))
+		if !m.activeDevice.SupportsVolume {
+			// 音量を変更できないデバイスは無効表示
+			volBar := strings.Repeat("─", volBarWidth)
+			lines = append(lines, m.theme.MutedText.Render(fmt.Sprintf(" Vol: [%s] n/a", volBar)))
+		} else {
+			volFilled := (m.volume * volBarWidth) / 100
+			volBar := strings.Repeat("█", volFilled) + strings.Repeat("░", volBarWidth-volFilled)
+			volLabel := fmt.Sprintf("%4d", m.volume)
+			if m.muted {
+				volLabel = " mute"
+			}
+			lines = append(lines, fmt.Sprintf(" Vol: [%s]%s", volBar, volLabel))
+		}
 	} else if len(m.devices) > 0 {
 		lines = append(lines, " No active device")
 	} else {
@@ -441,3 +548,168 @@ func (m Model) renderDeviceInfo(width int) string {
 
 	return strings.Join(lines, "\n")
 }
+
+// renderDevicePicker はデバイス選択のオーバーレイを描画する
+func (m Model) renderDevicePicker() string {
+	width := m.width - 10
+	if width > 70 {
+		width = 70
+	}
+
+	var lines []string
+	lines = append(lines, m.theme.Title.Render(truncate("🔊 Select Device", width)), "")
+
+	if m.activeDevice == nil {
+		lines = append(lines, m.theme.MutedText.Render(truncate(" No active device. Choose where to play:", width)), "")
+	}
+
+	if len(m.devices) == 0 {
+		lines = append(lines, truncate(" No devices found. Open Spotify on a device first.", width))
+	}
+
+	for i, d := range m.devices {
+		var flags []string
+		if d.Active {
+			flags = append(flags, "active")
+		}
+		if d.Restricted {
+			flags = append(flags, "restricted")
+		}
+		if d.IsPrivateSession {
+			flags = append(flags, "private")
+		}
+		volume := "  -"
+		if d.SupportsVolume {
+			volume = fmt.Sprintf("%3d%%", d.Volume)
+		}
+		line := fmt.Sprintf(" %-24s %-11s %4s", truncate(d.Name, 24), truncate(d.Type, 11), volume)
+		if len(flags) > 0 {
+			line += " [" + strings.Join(flags, ", ") + "]"
+		}
+
+		switch {
+		case i == m.deviceIndex:
+			line = m.theme.SelectedTrack.Width(width).Render(truncate(" ▶"+line, width))
+		case d.Restricted:
+			line = m.theme.MutedText.Width(width).Render(truncate("  "+line, width))
+		default:
+			line = m.theme.Track.Width(width).Render(truncate("  "+line, width))
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, "", truncate(fmt.Sprintf("[%s] Transfer & play | [%s] Transfer only | [%s] Close",
+		m.keys.binding(actPickerSelect).Help().Key,
+		m.keys.binding(actTransferOnly).Help().Key,
+		m.keys.binding(actPickerClose).Help().Key), width))
+	if m.err != "" {
+		lines = append(lines, m.theme.ErrorText.Render(truncate("Error: "+m.err, width)))
+	}
+
+	return m.theme.PlayerBar.Render(strings.Join(lines, "\n"))
+}
+
+// renderHelp は全てのキー割り当てをパネルごとにまとめて表示する
+// 表示内容は keyMap.FullHelp と同じ key.Binding から作る
+func (m Model) renderHelp() string {
+	keyStyle := m.theme.Title
+	descStyle := m.theme.Track
+
+	var columns []string
+	for i, bindings := range m.keys.FullHelp() {
+		keyWidth := 3 // "0-9"
+		for _, b := range bindings {
+			keyWidth = max(keyWidth, runewidth.StringWidth(b.Help().Key))
+		}
+		keyWidth = min(keyWidth, 20)
+
+		lines := []string{m.theme.Title.Render(keyGroups[i].title)}
+		for _, b := range bindings {
+			if !b.Enabled() {
+				continue
+			}
+			lines = append(lines, keyStyle.Width(keyWidth).Render(truncate(b.Help().Key, keyWidth))+"  "+descStyle.Render(b.Help().Desc))
+		}
+		if keyGroups[i].title == "Player" {
+			lines = append(lines, keyStyle.Width(keyWidth).Render("0-9")+"  "+descStyle.Render("jump to 0%-90%"))
+		}
+		columns = append(columns, lipgloss.NewStyle().PaddingRight(3).Render(strings.Join(lines, "\n")))
+	}
+
+	// 幅に収まる数ずつ列を横に並べる
+	var rows []string
+	var row []string
+	rowWidth := 0
+	for _, c := range columns {
+		w := lipgloss.Width(c)
+		if len(row) > 0 && rowWidth+w > m.width-6 {
+			rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+			row, rowWidth = nil, 0
+		}
+		row = append(row, c)
+		rowWidth += w
+	}
+	if len(row) > 0 {
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+	}
+
+	layoutName := m.layoutMode.String()
+	if m.layoutMode == LayoutAuto {
+		layoutName += " → " + m.layout().Mode.String()
+	}
+	header := m.theme.Title.Render("⌨ Keys") + m.theme.MutedText.Render("  ("+m.keys.binding(actHelp).Help().Key+" or esc to close, theme: "+m.theme.Name+", layout: "+layoutName+")")
+	return m.theme.PlayerBar.Render(header + "\n\n" + strings.Join(rows, "\n\n"))
+}
+
+func (m Model) renderAccountPicker() string {
+	width := m.width - 10
+	if width > 50 {
+		width = 50
+	}
+
+	var lines []string
+	lines = append(lines, m.theme.Title.Render(truncate("👥 Switch Account", width)), "")
+
+	if len(m.accountNames) == 0 {
+		lines = append(lines, truncate(" Loading...", width))
+	}
+	for i, name := range m.accountNames {
+		line := " " + name
+		if name == m.profile {
+			line += " (current)"
+		}
+		if i == m.accountIndex {
+			line = m.theme.SelectedTrack.Width(width).Render(truncate(" ▶"+line, width))
+		} else {
+			line = m.theme.Track.Width(width).Render(truncate("  "+line, width))
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, "", truncate(fmt.Sprintf("[%s] Switch | [%s] Close",
+		m.keys.binding(actPickerSelect).Help().Key,
+		m.keys.binding(actPickerClose).Help().Key), width))
+	if m.err != "" {
+		lines = append(lines, m.theme.ErrorText.Render(truncate("Error: "+m.err, width)))
+	}
+
+	return m.theme.PlayerBar.Render(strings.Join(lines, "\n"))
+}
+
+// renderLoginPrompt は再ログイン中に認可ページのURLを表示する
+// URLはコピーできるよう切り詰めずに折り返す
+func (m Model) renderLoginPrompt() string {
+	width := m.width - 10
+	if width > 90 {
+		width = 90
+	}
+
+	var lines []string
+	lines = append(lines, m.theme.Title.Render("🔑 Log in to Spotify"), "")
+	lines = append(lines, truncate("Open the following page in your browser:", width), "")
+	lines = append(lines, m.theme.Track.Width(width).Render(m.loginURL), "")
+	lines = append(lines, m.theme.MutedText.Render(truncate("Waiting for the browser to redirect back...", width)))
+	lines = append(lines, "", truncate("["+m.keys.binding(actPickerClose).Help().Key+"] Cancel", width))
+
+	return m.theme.PlayerBar.Render(strings.Join(lines, "\n"))
+}